@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/joho/godotenv"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/api"
+	"github.com/LeventeLantos/automatic-messaging/internal/apikey"
+	"github.com/LeventeLantos/automatic-messaging/internal/config"
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+	"github.com/LeventeLantos/automatic-messaging/internal/repo"
+	"github.com/LeventeLantos/automatic-messaging/internal/tenant"
+)
+
+const apiKeyUsage = `usage: messaging apikey <command> [flags]
+
+commands:
+  create -name NAME -scopes SCOPE[,SCOPE...] [-tenant ID | -operator]
+                                               create a key and print it once
+  list                                         list keys
+  revoke -id ID                                revoke a key
+
+scopes: `
+
+// apiKeyMain runs "messaging apikey ..." against POSTGRES_URL and returns the
+// process exit code.
+func apiKeyMain(args []string) int {
+	_ = godotenv.Load()
+
+	dbCfg, err := config.LoadDatabase()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	db, err := sql.Open("pgx", dbCfg.PostgresURL)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := runAPIKeyCommand(ctx, repo.NewPostgresAPIKeyRepo(db), args, os.Stdout); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			err = errUsage
+		}
+		fmt.Fprintln(os.Stderr, err)
+		if errors.Is(err, errUsage) {
+			return 2
+		}
+		return 1
+	}
+	return 0
+}
+
+var errUsage = errors.New(apiKeyUsage + strings.Join(api.Scopes, ", "))
+
+func runAPIKeyCommand(ctx context.Context, keys repo.APIKeyRepository, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	fs := flag.NewFlagSet("apikey "+args[0], flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	switch args[0] {
+	case "create":
+		name := fs.String("name", "", "key name")
+		scopes := fs.String("scopes", "", "comma-separated scopes")
+		tenantID := fs.String("tenant", tenant.Default, "tenant the key acts for; created if missing")
+		operator := fs.Bool("operator", false, "create an operator key, which acts on every tenant")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *operator {
+			tenantSet := false
+			fs.Visit(func(f *flag.Flag) { tenantSet = tenantSet || f.Name == "tenant" })
+			if tenantSet {
+				return errors.New("-operator and -tenant are mutually exclusive")
+			}
+			*tenantID = ""
+		}
+		return createAPIKey(ctx, keys, *name, *scopes, *tenantID, out)
+
+	case "list":
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		return listAPIKeys(ctx, keys, out)
+
+	case "revoke":
+		id := fs.Int64("id", 0, "key ID")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *id <= 0 {
+			return errors.New("-id is required")
+		}
+		if err := keys.RevokeAPIKey(ctx, *id); err != nil {
+			if errors.Is(err, repo.ErrNotFound) {
+				return fmt.Errorf("no active key with id %d", *id)
+			}
+			return err
+		}
+		fmt.Fprintf(out, "revoked key %d\n", *id)
+		return nil
+	}
+	return errUsage
+}
+
+// createAPIKey creates an operator key when tenantID is empty.
+func createAPIKey(ctx context.Context, keys repo.APIKeyRepository, name, scopeList, tenantID string, out io.Writer) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return errors.New("-name is required")
+	}
+	if tenantID != "" && !tenant.Valid(tenantID) {
+		return fmt.Errorf("invalid tenant %q: use lowercase letters, digits and dashes", tenantID)
+	}
+
+	var scopes []string
+	for _, s := range strings.Split(scopeList, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if !slices.Contains(api.Scopes, s) {
+			return fmt.Errorf("unknown scope %q (valid: %s)", s, strings.Join(api.Scopes, ", "))
+		}
+		if !slices.Contains(scopes, s) {
+			scopes = append(scopes, s)
+		}
+	}
+	if len(scopes) == 0 {
+		return errors.New("-scopes is required")
+	}
+
+	raw, prefix, err := apikey.Generate()
+	if err != nil {
+		return err
+	}
+	k, err := keys.CreateAPIKey(ctx, model.APIKey{Name: name, TenantID: tenantID, Prefix: prefix, Scopes: scopes}, apikey.Hash(raw))
+	if err != nil {
+		return err
+	}
+
+	owner := "tenant " + k.TenantID
+	if k.TenantID == "" {
+		owner = "operators"
+	}
+	fmt.Fprintf(out, "created key %d (%s) for %s with scopes %s\n", k.ID, k.Name, owner, strings.Join(k.Scopes, ","))
+	fmt.Fprintf(out, "%s\n", raw)
+	fmt.Fprintln(out, "store it now, it cannot be shown again")
+	return nil
+}
+
+func listAPIKeys(ctx context.Context, keys repo.APIKeyRepository, out io.Writer) error {
+	items, err := keys.ListAPIKeys(ctx)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tTENANT\tPREFIX\tSCOPES\tCREATED\tREVOKED")
+	for _, k := range items {
+		tenantID, revoked := k.TenantID, "-"
+		if tenantID == "" {
+			tenantID = "(operator)"
+		}
+		if k.RevokedAt != nil {
+			revoked = k.RevokedAt.UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			k.ID, k.Name, tenantID, k.Prefix, strings.Join(k.Scopes, ","), k.CreatedAt.UTC().Format(time.RFC3339), revoked)
+	}
+	return tw.Flush()
+}
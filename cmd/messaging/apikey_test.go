@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/apikey"
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+	"github.com/LeventeLantos/automatic-messaging/internal/repo"
+)
+
+type memKeys struct {
+	keys   []model.APIKey
+	hashes []string
+}
+
+func (m *memKeys) CreateAPIKey(ctx context.Context, k model.APIKey, hash string) (*model.APIKey, error) {
+	k.ID = int64(len(m.keys) + 1)
+	k.CreatedAt = time.Now().UTC()
+	m.keys = append(m.keys, k)
+	m.hashes = append(m.hashes, hash)
+	return &k, nil
+}
+
+func (m *memKeys) APIKeyByHash(ctx context.Context, hash string) (*model.APIKey, error) {
+	for i, h := range m.hashes {
+		if h == hash && m.keys[i].RevokedAt == nil {
+			k := m.keys[i]
+			return &k, nil
+		}
+	}
+	return nil, repo.ErrNotFound
+}
+
+func (m *memKeys) ListAPIKeys(ctx context.Context) ([]model.APIKey, error) {
+	return m.keys, nil
+}
+
+func (m *memKeys) RevokeAPIKey(ctx context.Context, id int64) error {
+	for i := range m.keys {
+		if m.keys[i].ID == id && m.keys[i].RevokedAt == nil {
+			now := time.Now().UTC()
+			m.keys[i].RevokedAt = &now
+			return nil
+		}
+	}
+	return repo.ErrNotFound
+}
+
+func TestAPIKeyCommand(t *testing.T) {
+	ctx := context.Background()
+	store := &memKeys{}
+	var out bytes.Buffer
+
+	if err := runAPIKeyCommand(ctx, store, []string{"create", "-name", "ops", "-tenant", "payments", "-scopes", "messages:read, scheduler:admin,messages:read"}, &out); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	var raw string
+	for _, line := range strings.Split(out.String(), "\n") {
+		if strings.HasPrefix(line, "am_") {
+			raw = line
+		}
+	}
+	if raw == "" {
+		t.Fatalf("expected the key to be printed, got %q", out.String())
+	}
+	if store.hashes[0] != apikey.Hash(raw) {
+		t.Fatalf("expected only the hash of the printed key to be stored")
+	}
+	if got := strings.Join(store.keys[0].Scopes, ","); got != "messages:read,scheduler:admin" {
+		t.Fatalf("unexpected scopes %q", got)
+	}
+	if store.keys[0].TenantID != "payments" {
+		t.Fatalf("expected the key to belong to payments, got %q", store.keys[0].TenantID)
+	}
+
+	out.Reset()
+	if err := runAPIKeyCommand(ctx, store, []string{"create", "-name", "root", "-operator", "-scopes", "*"}, &out); err != nil {
+		t.Fatalf("create operator key: %v", err)
+	}
+	if store.keys[1].TenantID != "" {
+		t.Fatalf("expected the operator key to have no tenant, got %q", store.keys[1].TenantID)
+	}
+
+	out.Reset()
+	if err := runAPIKeyCommand(ctx, store, []string{"revoke", "-id", "1"}, &out); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	if _, err := store.APIKeyByHash(ctx, apikey.Hash(raw)); err == nil {
+		t.Fatalf("expected the revoked key to be rejected")
+	}
+
+	out.Reset()
+	if err := runAPIKeyCommand(ctx, store, []string{"list"}, &out); err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if !strings.Contains(out.String(), "ops") || !strings.Contains(out.String(), "(operator)") || strings.Contains(out.String(), raw) {
+		t.Fatalf("unexpected list output %q", out.String())
+	}
+
+	for _, args := range [][]string{
+		nil,
+		{"create", "-name", "x", "-scopes", "messages:delete"},
+		{"create", "-scopes", "messages:read"},
+		{"create", "-name", "x"},
+		{"create", "-name", "x", "-scopes", "messages:read", "-tenant", "Payments"},
+		{"create", "-name", "x", "-scopes", "messages:read", "-tenant", "payments", "-operator"},
+		{"revoke"},
+		{"revoke", "-id", "9"},
+		{"rotate"},
+	} {
+		if err := runAPIKeyCommand(ctx, store, args, &out); err == nil {
+			t.Fatalf("expected %v to fail", args)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
@@ -10,37 +11,105 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/LeventeLantos/automatic-messaging/internal/alert"
 	"github.com/LeventeLantos/automatic-messaging/internal/api"
+	"github.com/LeventeLantos/automatic-messaging/internal/breaker"
 	"github.com/LeventeLantos/automatic-messaging/internal/cache"
 	"github.com/LeventeLantos/automatic-messaging/internal/client"
 	"github.com/LeventeLantos/automatic-messaging/internal/config"
+	"github.com/LeventeLantos/automatic-messaging/internal/dispatch"
+	"github.com/LeventeLantos/automatic-messaging/internal/events"
+	"github.com/LeventeLantos/automatic-messaging/internal/logging"
+	"github.com/LeventeLantos/automatic-messaging/internal/metrics"
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+	"github.com/LeventeLantos/automatic-messaging/internal/reconcile"
+	"github.com/LeventeLantos/automatic-messaging/internal/redact"
 	"github.com/LeventeLantos/automatic-messaging/internal/repo"
+	"github.com/LeventeLantos/automatic-messaging/internal/routing"
 	"github.com/LeventeLantos/automatic-messaging/internal/scheduler"
 	"github.com/LeventeLantos/automatic-messaging/internal/service"
+	"github.com/LeventeLantos/automatic-messaging/internal/tenant"
+	"github.com/LeventeLantos/automatic-messaging/internal/tracing"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/joho/godotenv"
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "apikey" {
+		os.Exit(apiKeyMain(os.Args[2:]))
+	}
+
 	_ = godotenv.Load()
 
 	cfg := mustLoadConfig()
-	setupLogger()
+	setupLogger(cfg)
+
+	shutdownTracing := mustSetupTracing(cfg)
+	defer shutdownTracing()
 
 	db := mustConnectDB(cfg)
 	defer db.Close()
 
 	msgRepo := repo.NewPostgresMessageRepo(db)
-	msgCache := setupRedis(cfg)
+	attemptRepo := repo.NewPostgresAttemptRepo(db)
+	subRepo := repo.NewPostgresSubscriptionRepo(db)
+	routeRepo := repo.NewPostgresRouteRepo(db)
+	statsRepo := repo.NewPostgresStatsRepo(db)
+	apiKeyRepo := repo.NewPostgresAPIKeyRepo(db)
+	auditRepo := repo.NewPostgresAuditRepo(db)
+	tenantRepo := repo.NewPostgresTenantRepo(db)
+	rdb := setupRedis(cfg)
+	msgCache := buildCache(cfg, rdb)
+
+	eventSink := mustBuildEventSink(cfg, rdb)
+	if eventSink != nil {
+		defer eventSink.Close()
+	}
+
+	m := metrics.New()
+	m.RegisterQueue(msgRepo, 2*time.Second)
+
+	providerRouter := mustBuildRouter(cfg, m)
+	prefixRouter := routing.NewPrefixRouter(routeRepo, providerRouter, cfg.Webhook.RoutesRefresh).
+		WithTenants(tenantRepo)
 
-	sender := buildSender(cfg, msgRepo, msgCache)
-	sched := buildScheduler(cfg, msgRepo, sender)
+	var sendClient service.SendClient = prefixRouter
+	brk := mustBuildBreaker(cfg, prefixRouter)
+	if brk != nil {
+		sendClient = brk
+		m.RegisterBreaker(brk)
+	}
+
+	sender := buildSender(cfg, sendClient, msgRepo, attemptRepo, msgCache, eventSink)
+	alerts := mustBuildAlertMonitor(cfg, msgRepo)
+	sched := buildScheduler(cfg, msgRepo, sender, brk, m, alerts)
 	sched.Start()
+	if alerts != nil {
+		alerts.WithScheduler(sched).Start()
+		defer alerts.Stop()
+	}
+
+	reconciler := buildReconciler(cfg, msgRepo, providerRouter, msgCache)
+	reconcileJob := buildReconcileJob(cfg, reconciler)
+	reconcileJob.Start()
+
+	receiptSweeper := buildReceiptSweeper(cfg, msgRepo, msgCache)
+	receiptSweeper.Start()
+
+	eventDispatcher := buildEventDispatcher(cfg, subRepo)
+	eventDispatcher.Start()
 
-	srv := buildHTTPServer(cfg, sched, msgRepo)
-	runWithGracefulShutdown(srv, sched)
+	statsCompactor := buildStatsCompactor(cfg, statsRepo)
+	statsCompactor.Start()
+
+	checks := buildReadinessChecks(cfg, db, rdb, sched)
+	srv := buildHTTPServer(cfg, m, sched, brk, reconciler, providerRouter, prefixRouter, msgRepo, attemptRepo, subRepo, routeRepo, statsRepo, apiKeyRepo, auditRepo, tenantRepo, msgCache, checks)
+	runWithGracefulShutdown(srv, sched, reconcileJob, receiptSweeper, eventDispatcher, statsCompactor)
 }
 
 func mustLoadConfig() *config.Config {
@@ -51,13 +120,41 @@ func mustLoadConfig() *config.Config {
 	return cfg
 }
 
-func setupLogger() {
-	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
-		Level: slog.LevelInfo,
-	}))
+func setupLogger(cfg *config.Config) {
+	logger, err := logging.New(os.Stdout, cfg.Log.Format, cfg.Log.Level)
+	if err != nil {
+		panic(err)
+	}
 	slog.SetDefault(logger)
 }
 
+// mustSetupTracing installs the OTLP tracer provider when tracing is enabled.
+// The returned function flushes buffered spans.
+func mustSetupTracing(cfg *config.Config) func() {
+	if !cfg.Tracing.Enabled {
+		return func() {}
+	}
+
+	shutdown, err := tracing.Setup(context.Background(), tracing.Settings{
+		ServiceName: cfg.Tracing.ServiceName,
+		Endpoint:    cfg.Tracing.Endpoint,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		slog.Error("failed to set up tracing", "err", err)
+		os.Exit(1)
+	}
+	slog.Info("tracing enabled", "service", cfg.Tracing.ServiceName, "sample_ratio", cfg.Tracing.SampleRatio)
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdown(ctx); err != nil {
+			slog.Error("failed to flush spans", "err", err)
+		}
+	}
+}
+
 func mustConnectDB(cfg *config.Config) *sql.DB {
 	db, err := sql.Open("pgx", cfg.Database.PostgresURL)
 	if err != nil {
@@ -76,7 +173,7 @@ func mustConnectDB(cfg *config.Config) *sql.DB {
 	return db
 }
 
-func setupRedis(cfg *config.Config) cache.MessageCache {
+func setupRedis(cfg *config.Config) *redis.Client {
 	if !cfg.Redis.Enabled {
 		return nil
 	}
@@ -91,93 +188,550 @@ func setupRedis(cfg *config.Config) cache.MessageCache {
 	defer cancel()
 
 	if err := rdb.Ping(ctx).Err(); err != nil {
-		slog.Error("redis ping failed (disabling cache)", "err", err)
+		slog.Error("redis ping failed (disabling redis)", "err", err)
 		return nil
 	}
 
-	slog.Info("redis cache enabled", "addr", cfg.Redis.Address, "db", cfg.Redis.DB)
+	slog.Info("redis connected", "addr", cfg.Redis.Address, "db", cfg.Redis.DB)
+	return rdb
+}
+
+func buildCache(cfg *config.Config, rdb *redis.Client) cache.MessageCache {
+	if rdb == nil {
+		return nil
+	}
+	slog.Info("redis cache enabled", "ttl", cfg.Redis.TTL)
 	return cache.NewRedisCache(rdb, cfg.Redis.TTL)
 }
 
+// mustBuildEventSink returns nil when no sinks are configured. A redis sink
+// is skipped if redis turned out to be unreachable at startup.
+func mustBuildEventSink(cfg *config.Config, rdb *redis.Client) events.Sink {
+	var sinks []events.Sink
+	for _, name := range cfg.Events.Sinks {
+		switch name {
+		case config.EventSinkFile:
+			fs, err := events.NewFileSink(cfg.Events.FilePath, cfg.Events.FileMaxBytes, cfg.Events.FileMaxBackups)
+			if err != nil {
+				slog.Error("failed to open event file", "path", cfg.Events.FilePath, "err", err)
+				os.Exit(1)
+			}
+			sinks = append(sinks, fs)
+			slog.Info("event sink enabled", "sink", name, "path", cfg.Events.FilePath)
+		case config.EventSinkRedis:
+			if rdb == nil {
+				slog.Error("redis unavailable, event stream sink disabled")
+				continue
+			}
+			sinks = append(sinks, events.NewRedisStreamSink(rdb, cfg.Events.StreamKey, cfg.Events.StreamMaxLen))
+			slog.Info("event sink enabled", "sink", name, "stream", cfg.Events.StreamKey)
+		}
+	}
+
+	if len(sinks) == 0 {
+		return nil
+	}
+	return events.Multi(sinks...)
+}
+
+// mustBuildRouter wraps every configured provider in a routing.Router, so
+// even a single provider gets a name and health tracking.
+func mustBuildRouter(cfg *config.Config, m *metrics.Metrics) *routing.Router {
+	var providers []routing.Provider
+	for _, p := range cfg.Webhook.ProviderList() {
+		providers = append(providers, routing.Provider{
+			Name: p.Name,
+			Client: client.NewWebhookClient(p.URL).
+				WithHTTPClient(mustBuildHTTPClient(p.Name, p.Transport)).
+				WithAuth(webhookAuth(p.Auth)).
+				WithMapping(mustBuildMapping(p.Name, p.Mapping)).
+				WithStatusLookup(mustBuildStatusLookup(p.Name, p.Mapping)).
+				WithObserver(m.WebhookObserver(p.Name)),
+			Priority: p.Priority,
+			Weight:   p.Weight,
+		})
+	}
+
+	router, err := routing.NewRouter(providers)
+	if err != nil {
+		slog.Error("invalid provider configuration", "err", err)
+		os.Exit(1)
+	}
+	return router.WithHealthPolicy(cfg.Webhook.UnhealthyAfter, cfg.Webhook.UnhealthyCooldown)
+}
+
+// mustBuildBreaker returns nil when the circuit breaker is disabled.
+func mustBuildBreaker(cfg *config.Config, next service.SendClient) *breaker.Breaker {
+	if !cfg.Breaker.Enabled {
+		return nil
+	}
+
+	brk, err := breaker.New(next, breaker.Settings{
+		FailureRatio: cfg.Breaker.FailureRatio,
+		MinRequests:  cfg.Breaker.MinRequests,
+		Window:       cfg.Breaker.Window,
+		OpenFor:      cfg.Breaker.OpenFor,
+	})
+	if err != nil {
+		slog.Error("invalid circuit breaker configuration", "err", err)
+		os.Exit(1)
+	}
+	return brk
+}
+
 func buildSender(
 	cfg *config.Config,
+	sendClient service.SendClient,
 	msgRepo repo.MessageRepository,
+	attemptRepo repo.AttemptRepository,
 	msgCache cache.MessageCache,
+	eventSink events.Sink,
 ) *service.Sender {
-	webhookClient := client.NewWebhookClient(cfg.Webhook.URL)
+	publish := func(ctx context.Context, ev model.Event) {
+		if eventSink == nil {
+			return
+		}
+		if err := eventSink.Publish(ctx, ev); err != nil {
+			slog.WarnContext(ctx, "failed to publish event", "id", ev.MessageID, "type", ev.Type, "err", err)
+		}
+	}
 
-	return service.NewSender(webhookClient, cfg.Webhook.ContentMax).
+	return service.NewSender(sendClient, cfg.Webhook.ContentMax).
 		WithHooks(
-			func(ctx context.Context, internalID int64, remoteMessageID string) error {
-				if err := msgRepo.MarkSent(ctx, internalID, remoteMessageID); err != nil {
-					slog.Error("failed to mark sent", "id", internalID, "err", err)
+			func(ctx context.Context, internalID int64, res model.SendResult) error {
+				if err := msgRepo.MarkSent(ctx, internalID, res.RemoteMessageID, res.Provider); err != nil {
+					slog.ErrorContext(ctx, "failed to mark sent", "id", internalID, "err", err)
 					return err
 				}
 
-				slog.Info("message sent", "id", internalID, "remote_message_id", remoteMessageID)
+				slog.InfoContext(ctx, "message sent", "id", internalID, "remote_message_id", res.RemoteMessageID, "provider", res.Provider)
 
+				now := time.Now().UTC()
 				if msgCache != nil {
-					if err := msgCache.StoreSent(ctx, internalID, remoteMessageID, time.Now().UTC()); err != nil {
-						slog.Warn("failed to store redis cache", "id", internalID, "err", err)
+					if err := msgCache.StoreSent(ctx, internalID, res.RemoteMessageID, now); err != nil {
+						slog.WarnContext(ctx, "failed to store redis cache", "id", internalID, "err", err)
 					}
 				}
 
+				publish(ctx, model.Event{
+					Type:            model.EventMessageSent,
+					OccurredAt:      now,
+					MessageID:       internalID,
+					TenantID:        tenant.From(ctx),
+					Status:          model.Sent,
+					RemoteMessageID: res.RemoteMessageID,
+					Provider:        res.Provider,
+				})
 				return nil
 			},
 			func(ctx context.Context, internalID int64, reason string) error {
 				if err := msgRepo.MarkFailed(ctx, internalID, reason); err != nil {
-					slog.Error("failed to mark failed", "id", internalID, "err", err)
+					slog.ErrorContext(ctx, "failed to mark failed", "id", internalID, "err", err)
 					return err
 				}
-				slog.Warn("message failed", "id", internalID, "reason", reason)
+				slog.WarnContext(ctx, "message failed", "id", internalID, "reason", reason)
+
+				publish(ctx, model.Event{
+					Type:       model.EventMessageFailed,
+					OccurredAt: time.Now().UTC(),
+					MessageID:  internalID,
+					TenantID:   tenant.From(ctx),
+					Status:     model.Failed,
+					Reason:     reason,
+				})
 				return nil
 			},
-		)
+		).
+		WithSendingHook(func(ctx context.Context, internalID int64) error {
+			if err := msgRepo.MarkSending(ctx, internalID); err != nil {
+				slog.ErrorContext(ctx, "failed to mark sending", "id", internalID, "err", err)
+				return err
+			}
+			return nil
+		}).
+		WithAttemptHook(func(ctx context.Context, attempt model.Attempt) error {
+			attempt.InstanceID = cfg.Server.InstanceID
+			if err := attemptRepo.RecordAttempt(ctx, attempt); err != nil {
+				slog.ErrorContext(ctx, "failed to record attempt", "id", attempt.MessageID, "err", err)
+				return err
+			}
+			return nil
+		}).
+		WithDeferHook(func(ctx context.Context, ids []int64) error {
+			if err := msgRepo.ReleaseClaimed(ctx, ids); err != nil {
+				slog.ErrorContext(ctx, "failed to release claimed messages", "ids", ids, "err", err)
+				return err
+			}
+			slog.WarnContext(ctx, "circuit breaker open, messages left pending", "count", len(ids))
+			return nil
+		})
+}
+
+func mustBuildMapping(provider string, m config.WebhookMappingConfig) client.Mapping {
+	mapping, err := client.NewMapping(m.BodyTemplate, m.ContentType, m.AcceptedStatuses, m.RemoteIDPath)
+	if err != nil {
+		slog.Error("invalid webhook mapping", "provider", provider, "err", err)
+		os.Exit(1)
+	}
+	return mapping
+}
+
+func mustBuildStatusLookup(provider string, m config.WebhookMappingConfig) client.StatusLookup {
+	lookup, err := client.NewStatusLookup(m.StatusURL, m.StatusPath, m.StatusValues)
+	if err != nil {
+		slog.Error("invalid status lookup", "provider", provider, "err", err)
+		os.Exit(1)
+	}
+	return lookup
+}
+
+func mustBuildHTTPClient(provider string, t config.WebhookTransportConfig) *http.Client {
+	hc, err := client.NewHTTPClient(client.Transport{
+		CertFile:            t.CertFile,
+		KeyFile:             t.KeyFile,
+		CAFile:              t.CAFile,
+		ProxyURL:            t.ProxyURL,
+		Timeout:             t.Timeout,
+		DialTimeout:         t.DialTimeout,
+		TLSHandshakeTimeout: t.TLSHandshakeTimeout,
+		MaxIdleConns:        t.MaxIdleConns,
+		MaxIdleConnsPerHost: t.MaxIdleConnsPerHost,
+		HTTP2:               t.HTTP2,
+	})
+	if err != nil {
+		slog.Error("invalid webhook transport", "provider", provider, "err", err)
+		os.Exit(1)
+	}
+	return hc
+}
+
+func webhookAuth(a config.WebhookAuthConfig) client.Auth {
+	return client.Auth{
+		Mode:     client.AuthMode(a.Mode),
+		Headers:  a.Headers,
+		Token:    a.BearerToken,
+		Username: a.BasicUser,
+		Password: a.BasicPassword,
+		HMAC: client.HMACAuth{
+			Secret:          []byte(a.HMACSecret),
+			Header:          a.HMACHeader,
+			TimestampHeader: a.HMACTimestampHeader,
+			Tolerance:       a.HMACTolerance,
+		},
+	}
 }
 
 func buildScheduler(
 	cfg *config.Config,
 	msgRepo repo.MessageRepository,
 	sender *service.Sender,
+	brk *breaker.Breaker,
+	m *metrics.Metrics,
+	alerts *alert.Monitor,
 ) *scheduler.Scheduler {
-	sched, err := scheduler.New(cfg.Scheduler.Interval, func(ctx context.Context) {
+	sched, err := scheduler.New(cfg.Scheduler.Interval, newTick(cfg, msgRepo, sender, brk, m, alerts))
+	if err != nil {
+		slog.Error("failed to create scheduler", "err", err)
+		panic(err)
+	}
+	return sched
+}
+
+// newTick returns the scheduler tick: claim a batch and send it. Each tick is
+// a trace root, so its spans cover the claim, every send and the follow-up
+// writes. When alerts is set, every tick ends by handing its outcome to the
+// alert monitor, which evaluates the rules in the background.
+func newTick(
+	cfg *config.Config,
+	msgRepo repo.MessageRepository,
+	sender *service.Sender,
+	brk *breaker.Breaker,
+	m *metrics.Metrics,
+	alerts *alert.Monitor,
+) func(ctx context.Context) {
+	return func(ctx context.Context) {
+		start := time.Now()
+		ctx, span := tracing.Start(ctx, "scheduler.tick", trace.WithNewRoot())
+		defer span.End()
+
+		observe := func(outcome string, claimed, sent, failed int) {
+			span.SetAttributes(
+				attribute.String("tick.outcome", outcome),
+				attribute.Int("messages.claimed", claimed),
+				attribute.Int("messages.sent", sent),
+				attribute.Int("messages.failed", failed),
+			)
+			m.ObserveTick(outcome, time.Since(start), claimed, sent, failed)
+			if alerts != nil {
+				alerts.TickDone(sent, failed)
+			}
+		}
+
+		if brk != nil && !brk.Allow() {
+			slog.WarnContext(ctx, "circuit breaker open, skipping tick", "breaker", brk.Status().State)
+			observe(metrics.TickSkipped, 0, 0, 0)
+			return
+		}
+
 		msgs, err := msgRepo.ClaimPending(ctx, cfg.Scheduler.BatchSize)
 		if err != nil {
-			slog.Error("claim pending failed", "err", err)
+			slog.ErrorContext(ctx, "claim pending failed", "err", err)
+			span.SetStatus(codes.Error, err.Error())
+			observe(metrics.TickError, 0, 0, 0)
 			return
 		}
 		if len(msgs) == 0 {
-			slog.Info("no pending messages")
+			slog.InfoContext(ctx, "no pending messages")
+			observe(metrics.TickEmpty, 0, 0, 0)
 			return
 		}
 
-		slog.Info("claimed messages", "count", len(msgs))
+		slog.InfoContext(ctx, "claimed messages", "count", len(msgs))
 		sent, failed := sender.ProcessBatch(ctx, msgs)
-		slog.Info("batch processed", "sent", sent, "failed", failed)
+		slog.InfoContext(ctx, "batch processed", "sent", sent, "failed", failed)
+		observe(metrics.TickProcessed, len(msgs), sent, failed)
+	}
+}
+
+// mustBuildAlertMonitor returns nil unless ALERT_WEBHOOK_URL is set.
+func mustBuildAlertMonitor(cfg *config.Config, msgRepo repo.MessageRepository) *alert.Monitor {
+	if cfg.Alerts.WebhookURL == "" {
+		return nil
+	}
+
+	mon, err := alert.New(alert.Settings{
+		FailureRatio:     cfg.Alerts.FailureRatio,
+		FailureTicks:     cfg.Alerts.FailureTicks,
+		OldestPending:    cfg.Alerts.OldestPending,
+		SchedulerStopped: cfg.Alerts.SchedulerStopped,
+		Interval:         cfg.Alerts.Interval,
+		InstanceID:       cfg.Server.InstanceID,
+	}, msgRepo, alert.NewWebhookNotifier(cfg.Alerts.WebhookURL, []byte(cfg.Alerts.Secret)))
+	if err != nil {
+		slog.Error("failed to create alert monitor", "err", err)
+		panic(err)
+	}
+	slog.Info("alerting enabled")
+	return mon
+}
+
+// buildReconciler asks providers about ambiguous sends and late receipts when
+// any provider has a status lookup, and returns messages left in processing
+// or sending by a crashed or interrupted tick to pending. Those are resent
+// with the same idempotency key.
+func buildReconciler(
+	cfg *config.Config,
+	msgRepo repo.MessageRepository,
+	providerRouter *routing.Router,
+	msgCache cache.MessageCache,
+) *reconcile.Reconciler {
+	r := reconcile.New(msgRepo, reconcile.Settings{
+		StuckAfter:   cfg.Scheduler.StuckAfter,
+		ReceiptAfter: cfg.Reconcile.ReceiptAfter,
+		RecheckAfter: cfg.Reconcile.RecheckAfter,
+		BatchSize:    cfg.Reconcile.BatchSize,
+	})
+	if cfg.Webhook.HasStatusLookup() {
+		r.WithStatusClient(providerRouter)
+		slog.Info("provider status lookups enabled")
+	}
+	if msgCache != nil {
+		r.WithChangeHook(func(ctx context.Context, id int64) {
+			if err := msgCache.Invalidate(ctx, id); err != nil {
+				slog.WarnContext(ctx, "failed to invalidate redis cache", "id", id, "err", err)
+			}
+		})
+	}
+	return r
+}
+
+func buildReconcileJob(cfg *config.Config, r *reconcile.Reconciler) *scheduler.Scheduler {
+	job, err := scheduler.New(cfg.Scheduler.ReconcileInterval, func(ctx context.Context) {
+		rep, err := r.Run(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "reconciliation failed", "err", err)
+			return
+		}
+		if rep.Checked > 0 || len(rep.Released) > 0 {
+			slog.InfoContext(ctx, "reconciliation finished",
+				"checked", rep.Checked,
+				"marked_sent", rep.MarkedSent,
+				"receipts_applied", rep.ReceiptsApplied,
+				"not_found", rep.NotFound,
+				"errors", rep.Errors,
+				"released", len(rep.Released),
+			)
+		}
 	})
 	if err != nil {
-		slog.Error("failed to create scheduler", "err", err)
+		slog.Error("failed to create reconciler", "err", err)
+		panic(err)
+	}
+	return job
+}
+
+// buildStatsCompactor folds the status count deltas into the counters.
+func buildStatsCompactor(cfg *config.Config, statsRepo repo.StatusCountCompactor) *scheduler.Scheduler {
+	compactor, err := scheduler.New(cfg.Stats.CompactInterval, func(ctx context.Context) {
+		if err := statsRepo.CompactStatusCounts(ctx); err != nil {
+			slog.ErrorContext(ctx, "status count compaction failed", "err", err)
+		}
+	})
+	if err != nil {
+		slog.Error("failed to create stats compactor", "err", err)
+		panic(err)
+	}
+	return compactor
+}
+
+// buildReceiptSweeper marks messages as unknown when no delivery receipt
+// arrived within the configured timeout.
+func buildReceiptSweeper(
+	cfg *config.Config,
+	msgRepo repo.MessageRepository,
+	msgCache cache.MessageCache,
+) *scheduler.Scheduler {
+	sweeper, err := scheduler.New(cfg.Receipts.SweepInterval, func(ctx context.Context) {
+		ids, err := msgRepo.MarkUnknownWithoutReceipt(ctx, time.Now().Add(-cfg.Receipts.Timeout))
+		if err != nil {
+			slog.ErrorContext(ctx, "receipt sweep failed", "err", err)
+			return
+		}
+		if len(ids) == 0 {
+			return
+		}
+
+		slog.WarnContext(ctx, "messages without delivery receipt marked unknown", "count", len(ids))
+		if msgCache != nil {
+			for _, id := range ids {
+				if err := msgCache.Invalidate(ctx, id); err != nil {
+					slog.WarnContext(ctx, "failed to invalidate redis cache", "id", id, "err", err)
+				}
+			}
+		}
+	})
+	if err != nil {
+		slog.Error("failed to create receipt sweeper", "err", err)
+		panic(err)
+	}
+	return sweeper
+}
+
+func buildEventDispatcher(cfg *config.Config, queue repo.DeliveryQueue) *scheduler.Scheduler {
+	d := dispatch.NewDispatcher(queue, cfg.Events.DispatchBatchSize).
+		WithRetry(cfg.Events.MaxAttempts, cfg.Events.BackoffBase, cfg.Events.BackoffMax)
+
+	sched, err := scheduler.New(cfg.Events.DispatchInterval, func(ctx context.Context) {
+		delivered, failed, err := d.Dispatch(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "event dispatch failed", "err", err)
+			return
+		}
+		if delivered > 0 || failed > 0 {
+			slog.InfoContext(ctx, "events dispatched", "delivered", delivered, "failed", failed)
+		}
+	})
+	if err != nil {
+		slog.Error("failed to create event dispatcher", "err", err)
 		panic(err)
 	}
 	return sched
 }
 
+// buildReadinessChecks covers Postgres, the applied migration version, Redis
+// when it is connected and the scheduler heartbeat.
+func buildReadinessChecks(cfg *config.Config, db *sql.DB, rdb *redis.Client, sched *scheduler.Scheduler) []api.ReadinessCheck {
+	staleAfter := cfg.Health.SchedulerStale
+	if staleAfter == 0 {
+		staleAfter = 3 * cfg.Scheduler.Interval
+	}
+
+	checks := []api.ReadinessCheck{
+		{Name: "database", Check: db.PingContext},
+		{Name: "migrations", Check: func(ctx context.Context) error {
+			v, err := repo.CurrentSchemaVersion(ctx, db)
+			if err != nil {
+				return err
+			}
+			if v < repo.SchemaVersion {
+				return fmt.Errorf("schema version %d, want %d", v, repo.SchemaVersion)
+			}
+			return nil
+		}},
+		api.HeartbeatCheck(sched, staleAfter),
+	}
+	if rdb != nil {
+		checks = append(checks, api.ReadinessCheck{Name: "redis", Check: func(ctx context.Context) error {
+			return rdb.Ping(ctx).Err()
+		}})
+	}
+	return checks
+}
+
 func buildHTTPServer(
 	cfg *config.Config,
+	m *metrics.Metrics,
 	sched *scheduler.Scheduler,
+	brk *breaker.Breaker,
+	reconciler *reconcile.Reconciler,
+	providers api.ProviderHealthSource,
+	prefixRouter *routing.PrefixRouter,
 	msgRepo repo.MessageRepository,
+	attemptRepo repo.AttemptRepository,
+	subRepo repo.SubscriptionRepository,
+	routeRepo repo.RouteRepository,
+	statsRepo repo.StatsRepository,
+	apiKeyRepo repo.APIKeyRepository,
+	auditRepo repo.AuditRepository,
+	tenantRepo repo.TenantRepository,
+	msgCache cache.MessageCache,
+	checks []api.ReadinessCheck,
 ) *http.Server {
-	h := api.NewHandler(sched, msgRepo)
-	router := api.Router(h)
+	h := api.NewHandler(sched, msgRepo).
+		WithCache(msgCache).
+		WithProviders(providers).
+		WithReconciler(reconciler).
+		WithRoutes(routeRepo, prefixRouter.Invalidate).
+		WithTenants(tenantRepo).
+		WithAttempts(attemptRepo).
+		WithSubscriptions(subRepo).
+		WithAudit(auditRepo).
+		WithStats(statsRepo, api.SLOThresholds{
+			OldestPendingAge: cfg.Stats.SLOOldestPending,
+			FailureRatio:     cfg.Stats.SLOFailureRatio,
+			SendLatencyP50:   cfg.Stats.SLOSendLatencyP50,
+			SendLatencyP95:   cfg.Stats.SLOSendLatencyP95,
+		}).
+		WithReceipts([]byte(cfg.Receipts.Secret), cfg.Receipts.Tolerance).
+		WithReadiness(cfg.Health.CheckTimeout, checks...).
+		WithContentMax(cfg.Webhook.ContentMax)
+	if brk != nil {
+		h.WithBreaker(brk)
+	}
+	if cfg.Auth.Enabled {
+		h.WithAuth(apiKeyRepo, cfg.Auth.CacheTTL)
+	} else {
+		slog.Warn("API authentication is disabled")
+	}
+	if cfg.Redact.Phones || cfg.Redact.Content != string(redact.ContentPlain) {
+		h.WithRedaction(redact.Policy{
+			Phones:  cfg.Redact.Phones,
+			Content: redact.ContentMode(cfg.Redact.Content),
+		})
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", m.Handler())
+	mux.Handle("/", api.Router(h))
 
 	return &http.Server{
 		Addr:              cfg.Server.Address,
-		Handler:           loggingMiddleware(router),
+		Handler:           requestIDMiddleware(tracingMiddleware(loggingMiddleware(m, mux))),
 		ReadHeaderTimeout: 5 * time.Second,
 	}
 }
 
-func runWithGracefulShutdown(srv *http.Server, sched *scheduler.Scheduler) {
+func runWithGracefulShutdown(srv *http.Server, scheds ...*scheduler.Scheduler) {
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
@@ -192,7 +746,9 @@ func runWithGracefulShutdown(srv *http.Server, sched *scheduler.Scheduler) {
 	<-ctx.Done()
 	slog.Info("shutdown requested")
 
-	sched.Stop()
+	for _, sched := range scheds {
+		sched.Stop()
+	}
 
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -204,14 +760,17 @@ func runWithGracefulShutdown(srv *http.Server, sched *scheduler.Scheduler) {
 	}
 }
 
-func loggingMiddleware(next http.Handler) http.Handler {
+// loggingMiddleware logs every request and records it in m. The route label
+// is read from r.Pattern, which the ServeMux fills in while routing.
+func loggingMiddleware(m *metrics.Metrics, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 		ww := &wrapWriter{ResponseWriter: w, status: 200}
 
 		next.ServeHTTP(ww, r)
 
-		slog.Info("http request",
+		m.ObserveHTTP(r.Method, r.Pattern, ww.status, time.Since(start))
+		slog.InfoContext(r.Context(), "http request",
 			"method", r.Method,
 			"path", r.URL.Path,
 			"status", ww.status,
@@ -220,6 +779,67 @@ func loggingMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+const requestIDHeader = "X-Request-ID"
+
+// requestIDMiddleware keeps a well-formed incoming X-Request-ID or generates
+// one, echoes it on the response and adds it to every log line written with
+// the request context.
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = logging.NewID()
+		}
+		w.Header().Set(requestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(logging.With(r.Context(), "request_id", id)))
+	})
+}
+
+// validRequestID accepts up to 128 visible ASCII characters, so a client
+// cannot inject line breaks or oversized values into the logs.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// tracingMiddleware starts a server span per request, continuing the trace of
+// an incoming traceparent header. It must wrap loggingMiddleware so both see
+// the request the ServeMux fills in; the span is renamed to the matched
+// pattern once routing is done.
+func tracingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := tracing.Extract(r.Context(), r.Header)
+		ctx, span := tracing.Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("url.path", r.URL.Path),
+			),
+		)
+		defer span.End()
+
+		r = r.WithContext(ctx)
+		ww := &wrapWriter{ResponseWriter: w, status: 200}
+		next.ServeHTTP(ww, r)
+
+		if r.Pattern != "" {
+			span.SetName(r.Pattern)
+			span.SetAttributes(attribute.String("http.route", r.Pattern))
+		}
+		span.SetAttributes(attribute.Int("http.response.status_code", ww.status))
+		if ww.status >= 500 {
+			span.SetStatus(codes.Error, http.StatusText(ww.status))
+		}
+	})
+}
+
 type wrapWriter struct {
 	http.ResponseWriter
 	status int
@@ -1,13 +1,29 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/config"
+	"github.com/LeventeLantos/automatic-messaging/internal/logging"
+	"github.com/LeventeLantos/automatic-messaging/internal/metrics"
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+	"github.com/LeventeLantos/automatic-messaging/internal/repo"
+	"github.com/LeventeLantos/automatic-messaging/internal/service"
+	"github.com/LeventeLantos/automatic-messaging/internal/tracing/tracingtest"
 )
 
 func TestLoggingMiddleware_PassesThroughAndCapturesStatus(t *testing.T) {
-	handler := loggingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	handler := loggingMiddleware(metrics.New(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusCreated)
 		_, _ = w.Write([]byte("ok"))
 	}))
@@ -25,3 +41,155 @@ func TestLoggingMiddleware_PassesThroughAndCapturesStatus(t *testing.T) {
 		t.Fatalf("expected body %q, got %q", "ok", body)
 	}
 }
+
+func TestLoggingMiddleware_RecordsRoutePattern(t *testing.T) {
+	m := metrics.New()
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", m.Handler())
+	mux.HandleFunc("GET /v1/messages/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	handler := loggingMiddleware(m, mux)
+
+	for _, path := range []string{"/v1/messages/1", "/v1/messages/2"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := io.ReadAll(rr.Body)
+
+	want := `messaging_http_requests_total{code="404",method="GET",route="/v1/messages/{id}"} 2`
+	if !strings.Contains(string(body), want) {
+		t.Fatalf("expected %s in metrics output, got:\n%s", want, body)
+	}
+}
+
+func TestTracingMiddleware_ContinuesIncomingTrace(t *testing.T) {
+	spans := tracingtest.Record(t)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/messages/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := tracingMiddleware(loggingMiddleware(metrics.New(), mux))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/messages/42", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	span, ok := tracingtest.Find(spans, "GET /v1/messages/{id}")
+	if !ok {
+		t.Fatalf("expected a span named after the route pattern, got %v", spans.GetSpans())
+	}
+	if span.SpanContext.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" ||
+		span.Parent.SpanID().String() != "00f067aa0ba902b7" {
+		t.Fatalf("expected the span to continue the incoming trace, got trace=%s parent=%s",
+			span.SpanContext.TraceID(), span.Parent.SpanID())
+	}
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, logging.FormatJSON, slog.LevelInfo)
+	if err != nil {
+		t.Fatalf("logger: %v", err)
+	}
+	prev := slog.Default()
+	slog.SetDefault(logger)
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	handler := requestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		slog.InfoContext(r.Context(), "handled")
+	}))
+
+	cases := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{"generated", "", false},
+		{"propagated", "req-123", true},
+		{"replaced when malformed", "bad\nid", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			buf.Reset()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.incoming != "" {
+				req.Header.Set("X-Request-ID", tc.incoming)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			id := rr.Header().Get("X-Request-ID")
+			if id == "" || (id == tc.incoming) != tc.keep {
+				t.Fatalf("unexpected response request ID %q for incoming %q", id, tc.incoming)
+			}
+
+			var rec map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+				t.Fatalf("decode log line %q: %v", buf.String(), err)
+			}
+			if rec["request_id"] != id {
+				t.Fatalf("expected request_id %q in log line, got %v", id, rec)
+			}
+		})
+	}
+}
+
+type tickRepo struct {
+	repo.MessageRepository
+	msgs []model.Message
+}
+
+func (r *tickRepo) ClaimPending(ctx context.Context, limit int) ([]model.Message, error) {
+	return r.msgs, nil
+}
+
+type okClient struct{}
+
+func (okClient) Send(ctx context.Context, phoneNumber, message string) (model.SendResult, error) {
+	return model.SendResult{StatusCode: http.StatusAccepted, RemoteMessageID: "r"}, nil
+}
+
+func TestTick_RecordsSpanPerTick(t *testing.T) {
+	spans := tracingtest.Record(t)
+
+	cfg := &config.Config{Scheduler: config.SchedulerConfig{BatchSize: 10}}
+	msgRepo := &tickRepo{msgs: []model.Message{
+		{ID: 1, RecipientPhone: "+361", Content: "a"},
+		{ID: 2, RecipientPhone: "+362", Content: "b"},
+	}}
+	tick := newTick(cfg, msgRepo, service.NewSender(okClient{}, 160), nil, metrics.New(), nil)
+
+	tick(context.Background())
+
+	root, ok := tracingtest.Find(spans, "scheduler.tick")
+	if !ok {
+		t.Fatalf("expected a tick span, got %v", spans.GetSpans())
+	}
+	for _, want := range []attribute.KeyValue{
+		attribute.String("tick.outcome", metrics.TickProcessed),
+		attribute.Int("messages.claimed", 2),
+		attribute.Int("messages.sent", 2),
+	} {
+		found := false
+		for _, a := range root.Attributes {
+			found = found || a == want
+		}
+		if !found {
+			t.Fatalf("expected tick attribute %v, got %v", want, root.Attributes)
+		}
+	}
+
+	sends := 0
+	for _, s := range spans.GetSpans() {
+		if s.Name == "Send" && s.Parent.SpanID() == root.SpanContext.SpanID() {
+			sends++
+		}
+	}
+	if sends != 2 {
+		t.Fatalf("expected 2 Send spans under the tick, got %d", sends)
+	}
+}
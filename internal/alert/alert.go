@@ -0,0 +1,313 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/repo"
+)
+
+type Rule string
+
+const (
+	RuleFailureRatio     Rule = "failure_ratio"
+	RuleOldestPending    Rule = "oldest_pending"
+	RuleSchedulerStopped Rule = "scheduler_stopped"
+)
+
+type Status string
+
+const (
+	Firing   Status = "firing"
+	Resolved Status = "resolved"
+)
+
+// Alert is the notification sent when a rule starts or stops firing. DedupKey
+// is the same for every notification about one rule, across instances.
+type Alert struct {
+	Status     Status     `json:"status"`
+	Rule       Rule       `json:"rule"`
+	DedupKey   string     `json:"dedupKey"`
+	Summary    string     `json:"summary"`
+	Value      float64    `json:"value"`
+	Threshold  float64    `json:"threshold"`
+	StartedAt  time.Time  `json:"startedAt"`
+	ResolvedAt *time.Time `json:"resolvedAt,omitempty"`
+	InstanceID string     `json:"instanceId,omitempty"`
+}
+
+// Settings define the rules. A rule with a zero threshold is disabled.
+// FailureRatio is checked over the last FailureTicks ticks that sent
+// anything, so idle ticks and ticks skipped by the circuit breaker neither
+// dilute nor resolve it. Interval is how often rules are evaluated when no
+// tick triggers an evaluation, e.g. while the scheduler is stopped.
+type Settings struct {
+	FailureRatio     float64
+	FailureTicks     int
+	OldestPending    time.Duration
+	SchedulerStopped time.Duration
+	Interval         time.Duration
+	InstanceID       string
+}
+
+type Notifier interface {
+	Notify(ctx context.Context, a Alert) error
+}
+
+type QueueSource interface {
+	QueueStats(ctx context.Context) (repo.QueueStats, error)
+}
+
+type SchedulerState interface {
+	IsRunning() bool
+}
+
+type tickResult struct {
+	sent, failed int
+}
+
+// state tracks one rule. notified records whether the current status was
+// delivered; an undelivered notification is retried on the next evaluation.
+type state struct {
+	status    Status
+	startedAt time.Time
+	notified  bool
+	value     float64
+	threshold float64
+	summary   string
+}
+
+// Monitor evaluates the alert rules in the background, after every tick and
+// every Interval, and notifies once when a rule starts firing and once when
+// it resolves.
+type Monitor struct {
+	settings Settings
+	queue    QueueSource
+	sched    SchedulerState
+	notifier Notifier
+
+	mu           sync.Mutex
+	ticks        []tickResult
+	stoppedSince time.Time
+	states       map[Rule]*state
+
+	trigger chan struct{}
+	cancel  context.CancelFunc
+	done    chan struct{}
+
+	now func() time.Time
+}
+
+func New(s Settings, q QueueSource, n Notifier) (*Monitor, error) {
+	switch {
+	case s.FailureRatio < 0 || s.FailureRatio > 1:
+		return nil, errors.New("failure ratio must be in [0, 1]")
+	case s.FailureRatio > 0 && s.FailureTicks <= 0:
+		return nil, errors.New("failure ticks must be > 0")
+	case s.Interval <= 0:
+		return nil, errors.New("interval must be > 0")
+	}
+
+	return &Monitor{
+		settings: s,
+		queue:    q,
+		notifier: n,
+		states:   map[Rule]*state{},
+		trigger:  make(chan struct{}, 1),
+		now:      time.Now,
+	}, nil
+}
+
+// WithScheduler enables the scheduler_stopped rule.
+func (m *Monitor) WithScheduler(s SchedulerState) *Monitor {
+	m.sched = s
+	return m
+}
+
+// TickDone records the outcome of a scheduler tick and schedules an
+// evaluation without waiting for it.
+func (m *Monitor) TickDone(sent, failed int) {
+	if sent+failed > 0 && m.settings.FailureTicks > 0 {
+		m.mu.Lock()
+		m.ticks = append(m.ticks, tickResult{sent: sent, failed: failed})
+		if len(m.ticks) > m.settings.FailureTicks {
+			m.ticks = m.ticks[len(m.ticks)-m.settings.FailureTicks:]
+		}
+		m.mu.Unlock()
+	}
+
+	select {
+	case m.trigger <- struct{}{}:
+	default:
+	}
+}
+
+func (m *Monitor) Start() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.cancel != nil {
+		return false
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+	m.done = make(chan struct{})
+
+	go func() {
+		defer close(m.done)
+
+		ticker := time.NewTicker(m.settings.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-m.trigger:
+			case <-ticker.C:
+			}
+			m.Evaluate(ctx)
+		}
+	}()
+	return true
+}
+
+func (m *Monitor) Stop() bool {
+	m.mu.Lock()
+	cancel, done := m.cancel, m.done
+	m.cancel = nil
+	m.mu.Unlock()
+
+	if cancel == nil {
+		return false
+	}
+	cancel()
+	<-done
+	return true
+}
+
+// Evaluate checks every enabled rule and sends the notifications due. A rule
+// whose input cannot be read keeps its previous state.
+func (m *Monitor) Evaluate(ctx context.Context) {
+	now := m.now()
+
+	if m.settings.FailureRatio > 0 {
+		ratio, ok := m.failureRatio()
+		m.apply(ctx, now, RuleFailureRatio, ok && ratio > m.settings.FailureRatio, ratio, m.settings.FailureRatio,
+			fmt.Sprintf("failure ratio %.2f over the last %d ticks exceeds %.2f", ratio, m.settings.FailureTicks, m.settings.FailureRatio))
+	}
+
+	if m.settings.OldestPending > 0 {
+		st, err := m.queue.QueueStats(ctx)
+		if err != nil {
+			slog.WarnContext(ctx, "alert rule skipped", "rule", RuleOldestPending, "err", err)
+		} else {
+			var age time.Duration
+			if st.OldestPending != nil {
+				age = now.Sub(*st.OldestPending)
+			}
+			m.apply(ctx, now, RuleOldestPending, age > m.settings.OldestPending, age.Seconds(), m.settings.OldestPending.Seconds(),
+				fmt.Sprintf("oldest pending message is %s old, over %s", age.Round(time.Second), m.settings.OldestPending))
+		}
+	}
+
+	if m.settings.SchedulerStopped > 0 && m.sched != nil {
+		stopped := m.stoppedFor(now)
+		m.apply(ctx, now, RuleSchedulerStopped, stopped > m.settings.SchedulerStopped, stopped.Seconds(), m.settings.SchedulerStopped.Seconds(),
+			fmt.Sprintf("scheduler stopped for %s, over %s", stopped.Round(time.Second), m.settings.SchedulerStopped))
+	}
+}
+
+// failureRatio reports false until a full window of ticks was recorded.
+func (m *Monitor) failureRatio() (float64, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.ticks) < m.settings.FailureTicks {
+		return 0, false
+	}
+	var sent, failed int
+	for _, t := range m.ticks {
+		sent += t.sent
+		failed += t.failed
+	}
+	return float64(failed) / float64(sent+failed), true
+}
+
+func (m *Monitor) stoppedFor(now time.Time) time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.sched.IsRunning() {
+		m.stoppedSince = time.Time{}
+		return 0
+	}
+	if m.stoppedSince.IsZero() {
+		m.stoppedSince = now
+	}
+	return now.Sub(m.stoppedSince)
+}
+
+func (m *Monitor) apply(ctx context.Context, now time.Time, rule Rule, firing bool, value, threshold float64, summary string) {
+	m.mu.Lock()
+	st := m.states[rule]
+	switch {
+	case st == nil && !firing:
+		m.mu.Unlock()
+		return
+	case st == nil || (st.status == Resolved && firing):
+		st = &state{status: Firing, startedAt: now}
+		m.states[rule] = st
+	case st.status == Firing && !firing && !st.notified:
+		// Nobody heard about it firing, so there is nothing to resolve.
+		delete(m.states, rule)
+		m.mu.Unlock()
+		return
+	case st.status == Firing && !firing:
+		st.status = Resolved
+		st.notified = false
+	}
+	if firing {
+		st.value, st.threshold, st.summary = value, threshold, summary
+	}
+	if st.notified {
+		m.mu.Unlock()
+		return
+	}
+
+	a := Alert{
+		Status:     st.status,
+		Rule:       rule,
+		DedupKey:   "automatic-messaging/" + string(rule),
+		Summary:    st.summary,
+		Value:      st.value,
+		Threshold:  st.threshold,
+		StartedAt:  st.startedAt.UTC(),
+		InstanceID: m.settings.InstanceID,
+	}
+	if st.status == Resolved {
+		resolvedAt := now.UTC()
+		a.ResolvedAt = &resolvedAt
+		a.Value = value
+	}
+	m.mu.Unlock()
+
+	if err := m.notifier.Notify(ctx, a); err != nil {
+		slog.ErrorContext(ctx, "failed to send alert", "rule", rule, "status", a.Status, "err", err)
+		return
+	}
+	slog.WarnContext(ctx, "alert sent", "rule", rule, "status", a.Status, "summary", a.Summary)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.states[rule] == st && st.status == a.Status {
+		st.notified = true
+		if st.status == Resolved {
+			delete(m.states, rule)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/repo"
+	"github.com/LeventeLantos/automatic-messaging/internal/signature"
+)
+
+type recorder struct {
+	alerts []Alert
+	err    error
+}
+
+func (r *recorder) Notify(ctx context.Context, a Alert) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.alerts = append(r.alerts, a)
+	return nil
+}
+
+type fakeQueue struct {
+	oldest *time.Time
+}
+
+func (f *fakeQueue) QueueStats(ctx context.Context) (repo.QueueStats, error) {
+	return repo.QueueStats{OldestPending: f.oldest}, nil
+}
+
+type fakeSched struct{ running bool }
+
+func (f *fakeSched) IsRunning() bool { return f.running }
+
+func newTestMonitor(t *testing.T, s Settings, q QueueSource) (*Monitor, *recorder, *time.Time) {
+	t.Helper()
+
+	s.Interval = time.Minute
+	n := &recorder{}
+	m, err := New(s, q, n)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+	return m, n, &now
+}
+
+func statuses(alerts []Alert) []Status {
+	var out []Status
+	for _, a := range alerts {
+		out = append(out, a.Status)
+	}
+	return out
+}
+
+func TestMonitor_FailureRatioFiresOnceAndResolves(t *testing.T) {
+	m, n, _ := newTestMonitor(t, Settings{FailureRatio: 0.5, FailureTicks: 3}, &fakeQueue{})
+	ctx := context.Background()
+
+	m.TickDone(0, 2)
+	m.TickDone(0, 0) // idle ticks do not count
+	m.TickDone(1, 1)
+	m.Evaluate(ctx)
+	if len(n.alerts) != 0 {
+		t.Fatalf("expected no alert before a full window, got %+v", n.alerts)
+	}
+
+	m.TickDone(0, 2)
+	m.Evaluate(ctx)
+	m.Evaluate(ctx)
+	if got := statuses(n.alerts); len(got) != 1 || got[0] != Firing {
+		t.Fatalf("expected a single firing alert, got %v", got)
+	}
+	if a := n.alerts[0]; a.Rule != RuleFailureRatio || a.DedupKey != "automatic-messaging/failure_ratio" || a.Value <= 0.5 {
+		t.Fatalf("unexpected alert: %+v", a)
+	}
+
+	for i := 0; i < 3; i++ {
+		m.TickDone(2, 0)
+	}
+	m.Evaluate(ctx)
+	if got := statuses(n.alerts); len(got) != 2 || got[1] != Resolved || n.alerts[1].ResolvedAt == nil {
+		t.Fatalf("expected a resolve notification, got %+v", n.alerts)
+	}
+}
+
+func TestMonitor_OldestPending(t *testing.T) {
+	q := &fakeQueue{}
+	m, n, now := newTestMonitor(t, Settings{OldestPending: 30 * time.Minute}, q)
+	ctx := context.Background()
+
+	oldest := now.Add(-time.Hour)
+	q.oldest = &oldest
+	m.Evaluate(ctx)
+
+	q.oldest = nil
+	m.Evaluate(ctx)
+
+	if got := statuses(n.alerts); len(got) != 2 || got[0] != Firing || got[1] != Resolved {
+		t.Fatalf("expected firing then resolved, got %v", got)
+	}
+	if n.alerts[0].Value != time.Hour.Seconds() || n.alerts[0].Threshold != (30*time.Minute).Seconds() {
+		t.Fatalf("unexpected value or threshold: %+v", n.alerts[0])
+	}
+}
+
+func TestMonitor_SchedulerStopped(t *testing.T) {
+	sched := &fakeSched{}
+	m, n, now := newTestMonitor(t, Settings{SchedulerStopped: 10 * time.Minute}, &fakeQueue{})
+	m.WithScheduler(sched)
+	ctx := context.Background()
+
+	m.Evaluate(ctx)
+	*now = now.Add(5 * time.Minute)
+	m.Evaluate(ctx)
+	if len(n.alerts) != 0 {
+		t.Fatalf("expected no alert within the threshold, got %+v", n.alerts)
+	}
+
+	*now = now.Add(6 * time.Minute)
+	m.Evaluate(ctx)
+	sched.running = true
+	m.Evaluate(ctx)
+
+	if got := statuses(n.alerts); len(got) != 2 || got[0] != Firing || got[1] != Resolved {
+		t.Fatalf("expected firing then resolved, got %v", got)
+	}
+}
+
+func TestMonitor_RetriesFailedNotifications(t *testing.T) {
+	q := &fakeQueue{}
+	m, n, now := newTestMonitor(t, Settings{OldestPending: time.Minute}, q)
+	ctx := context.Background()
+
+	oldest := now.Add(-time.Hour)
+	q.oldest = &oldest
+	n.err = errors.New("receiver down")
+	m.Evaluate(ctx)
+
+	n.err = nil
+	m.Evaluate(ctx)
+	m.Evaluate(ctx)
+	if got := statuses(n.alerts); len(got) != 1 || got[0] != Firing {
+		t.Fatalf("expected the firing alert to be retried once, got %v", got)
+	}
+}
+
+func TestMonitor_DropsUnsentAlertThatCleared(t *testing.T) {
+	q := &fakeQueue{}
+	m, n, now := newTestMonitor(t, Settings{OldestPending: time.Minute}, q)
+	ctx := context.Background()
+
+	oldest := now.Add(-time.Hour)
+	q.oldest = &oldest
+	n.err = errors.New("receiver down")
+	m.Evaluate(ctx)
+
+	n.err = nil
+	q.oldest = nil
+	m.Evaluate(ctx)
+	if len(n.alerts) != 0 {
+		t.Fatalf("expected no resolve for an alert that was never delivered, got %+v", n.alerts)
+	}
+}
+
+func TestNew_RejectsInvalidSettings(t *testing.T) {
+	for _, s := range []Settings{
+		{FailureRatio: 1.5, FailureTicks: 1, Interval: time.Second},
+		{FailureRatio: 0.5, Interval: time.Second},
+		{},
+	} {
+		if _, err := New(s, &fakeQueue{}, &recorder{}); err == nil {
+			t.Fatalf("expected error for %+v", s)
+		}
+	}
+}
+
+func TestWebhookNotifier_PostsSignedJSON(t *testing.T) {
+	secret := []byte("s3cret")
+	var got Alert
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := signature.Verify(secret, r.Header.Get(signature.HeaderTimestamp), r.Header.Get(signature.HeaderSignature), body, time.Minute, time.Now()); err != nil {
+			t.Errorf("signature: %v", err)
+		}
+		_ = json.Unmarshal(body, &got)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	a := Alert{Status: Firing, Rule: RuleOldestPending, DedupKey: "automatic-messaging/oldest_pending"}
+	if err := NewWebhookNotifier(srv.URL, secret).Notify(context.Background(), a); err != nil {
+		t.Fatalf("notify: %v", err)
+	}
+	if got.Rule != RuleOldestPending || got.Status != Firing {
+		t.Fatalf("unexpected payload: %+v", got)
+	}
+
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer down.Close()
+
+	if err := NewWebhookNotifier(down.URL, nil).Notify(context.Background(), a); err == nil {
+		t.Fatalf("expected error for a 502 response")
+	}
+}
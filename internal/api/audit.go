@@ -0,0 +1,110 @@
+package api
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+	"github.com/LeventeLantos/automatic-messaging/internal/repo"
+	"github.com/LeventeLantos/automatic-messaging/internal/tenant"
+)
+
+// maxAuditLimit caps the page size of GET /v1/audit.
+const maxAuditLimit = 500
+
+// WithAudit records administrative actions taken through the API and enables
+// GET /v1/audit.
+func (h *Handler) WithAudit(a repo.AuditRepository) *Handler {
+	h.auditLog = a
+	return h
+}
+
+// audit records a successful action. The action already happened, so a
+// failed write is logged rather than returned to the client.
+func (h *Handler) audit(r *http.Request, action model.AuditAction, target string, params map[string]any) {
+	if h.auditLog == nil {
+		return
+	}
+
+	e := model.AuditEvent{
+		Actor:    actor(r.Context()),
+		TenantID: tenant.From(r.Context()),
+		Action:   action,
+		Target:   target,
+		SourceIP: sourceIP(r),
+	}
+	if len(params) > 0 {
+		b, err := json.Marshal(params)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "audit params encoding failed", "action", action, "err", err)
+			return
+		}
+		e.Params = b
+	}
+
+	if err := h.auditLog.RecordAudit(r.Context(), e); err != nil {
+		slog.ErrorContext(r.Context(), "audit write failed", "action", action, "target", target, "err", err)
+	}
+}
+
+// ListAudit returns the caller's tenant's audit events, newest first. Pass
+// the smallest id of a page as before to get the next one.
+func (h *Handler) ListAudit(w http.ResponseWriter, r *http.Request) {
+	if h.auditLog == nil {
+		http.Error(w, "audit log is not enabled", http.StatusNotFound)
+		return
+	}
+
+	q := r.URL.Query()
+	f := repo.AuditFilter{
+		Actor:  q.Get("actor"),
+		Action: model.AuditAction(q.Get("action")),
+		Target: q.Get("target"),
+		Limit:  min(parseInt(q.Get("limit"), 50), maxAuditLimit),
+	}
+	for name, dst := range map[string]**time.Time{"from": &f.From, "to": &f.To} {
+		raw := q.Get(name)
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			http.Error(w, "invalid "+name+": expected an RFC 3339 timestamp", http.StatusBadRequest)
+			return
+		}
+		*dst = &t
+	}
+	if raw := q.Get("before"); raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || id <= 0 {
+			http.Error(w, "invalid before: expected an audit event id", http.StatusBadRequest)
+			return
+		}
+		f.BeforeID = id
+	}
+
+	items, err := h.auditLog.ListAudit(r.Context(), f)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if items == nil {
+		items = []model.AuditEvent{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"items": items})
+}
+
+// sourceIP is the address of the peer that sent the request. Proxies are not
+// trusted, so X-Forwarded-For is ignored.
+func sourceIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
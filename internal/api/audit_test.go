@@ -0,0 +1,184 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+	"github.com/LeventeLantos/automatic-messaging/internal/repo"
+	"github.com/LeventeLantos/automatic-messaging/internal/tenant"
+)
+
+type fakeAudit struct {
+	events    []model.AuditEvent
+	gotFilter repo.AuditFilter
+}
+
+func (f *fakeAudit) RecordAudit(ctx context.Context, e model.AuditEvent) error {
+	e.ID = int64(len(f.events) + 1)
+	f.events = append(f.events, e)
+	return nil
+}
+
+func (f *fakeAudit) ListAudit(ctx context.Context, filter repo.AuditFilter) ([]model.AuditEvent, error) {
+	f.gotFilter = filter
+	var out []model.AuditEvent
+	for _, e := range f.events {
+		if t := tenant.From(ctx); t == "" || e.TenantID == t {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func TestAudit_RecordsAdminActions(t *testing.T) {
+	msg := model.Message{ID: 3, RecipientPhone: "+36301234111", Content: "hello", Status: model.Pending}
+	fr := &fakeRepo{byID: map[int64]model.Message{3: msg}, requeueIDs: []int64{8, 9}}
+	audit := &fakeAudit{}
+
+	s, h := newTestHandler(t, fr)
+	defer s.Stop()
+	keys := newFakeKeys(map[string][]string{"admin-key": {ScopeAll}})
+	mux := Router(h.WithAuth(keys, time.Minute).WithAudit(audit))
+
+	do := func(method, path, body string) {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-API-Key", "admin-key")
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		if rr.Code >= 300 {
+			t.Fatalf("%s %s: unexpected %d body=%q", method, path, rr.Code, rr.Body.String())
+		}
+	}
+
+	do(http.MethodPost, "/v1/scheduler/stop", "")
+	do(http.MethodPatch, "/v1/messages/3", `{"recipientPhone":"+36309999999"}`)
+	do(http.MethodPost, "/v1/messages/requeue", `{"ids":[8,9]}`)
+	do(http.MethodPost, "/v1/messages/requeue", `{"ids":[8,9],"dryRun":false}`)
+
+	if len(audit.events) != 3 {
+		t.Fatalf("expected 3 audit events (dry runs are not audited), got %+v", audit.events)
+	}
+
+	stop := audit.events[0]
+	if stop.Action != model.AuditSchedulerStop || stop.Actor != "apikey:admi" || stop.TenantID != "" || stop.SourceIP != "192.0.2.1" {
+		t.Fatalf("unexpected scheduler event %+v", stop)
+	}
+
+	update := audit.events[1]
+	if update.Action != model.AuditMessageUpdate || update.Target != "message:3" {
+		t.Fatalf("unexpected update event %+v", update)
+	}
+	if string(update.Params) != `{"fields":["recipientPhone"]}` {
+		t.Fatalf("expected only field names in params, got %s", update.Params)
+	}
+
+	var requeue map[string]any
+	if err := json.Unmarshal(audit.events[2].Params, &requeue); err != nil {
+		t.Fatalf("params: %v", err)
+	}
+	if audit.events[2].Action != model.AuditMessagesRequeue || requeue["count"] != float64(2) {
+		t.Fatalf("unexpected requeue event %+v", audit.events[2])
+	}
+}
+
+func TestAudit_AnonymousWithoutAuth(t *testing.T) {
+	audit := &fakeAudit{}
+	s, h := newTestHandler(t, &fakeRepo{})
+	defer s.Stop()
+	mux := Router(h.WithAudit(audit))
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/v1/scheduler/start", nil))
+	s.Stop()
+
+	if len(audit.events) != 1 || audit.events[0].Actor != "anonymous" {
+		t.Fatalf("expected one anonymous event, got %+v", audit.events)
+	}
+}
+
+func TestListAudit(t *testing.T) {
+	t.Run("not enabled", func(t *testing.T) {
+		s, h := newTestHandler(t, &fakeRepo{})
+		defer s.Stop()
+
+		rr := httptest.NewRecorder()
+		Router(h).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/audit", nil))
+		if rr.Code != http.StatusNotFound {
+			t.Fatalf("expected 404, got %d", rr.Code)
+		}
+	})
+
+	t.Run("filters", func(t *testing.T) {
+		audit := &fakeAudit{events: []model.AuditEvent{{ID: 4, Actor: "apikey:am_x", Action: model.AuditRoutePut}}}
+		s, h := newTestHandler(t, &fakeRepo{})
+		defer s.Stop()
+		mux := Router(h.WithAudit(audit))
+
+		req := httptest.NewRequest(http.MethodGet,
+			"/v1/audit?actor=apikey:am_x&action=route.put&target=route:%2B36&from=2026-01-01T00:00:00Z&before=10&limit=5000", nil)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%q", rr.Code, rr.Body.String())
+		}
+
+		f := audit.gotFilter
+		if f.Actor != "apikey:am_x" || f.Action != model.AuditRoutePut || f.Target != "route:+36" ||
+			f.From == nil || f.To != nil || f.BeforeID != 10 || f.Limit != maxAuditLimit {
+			t.Fatalf("unexpected filter %+v", f)
+		}
+		if items := decodeJSON(t, rr)["items"].([]any); len(items) != 1 {
+			t.Fatalf("expected one item, got %v", items)
+		}
+	})
+
+	t.Run("invalid params", func(t *testing.T) {
+		s, h := newTestHandler(t, &fakeRepo{})
+		defer s.Stop()
+		mux := Router(h.WithAudit(&fakeAudit{}))
+
+		for _, q := range []string{"from=yesterday", "to=1", "before=abc", "before=-1"} {
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/audit?"+q, nil))
+			if rr.Code != http.StatusBadRequest {
+				t.Fatalf("%s: expected 400, got %d", q, rr.Code)
+			}
+		}
+	})
+}
+
+func TestAudit_ScopedToTenant(t *testing.T) {
+	audit := &fakeAudit{}
+	s, h := newTestHandler(t, &fakeRepo{requeueIDs: []int64{8}})
+	defer s.Stop()
+	keys := tenantKeys([]string{ScopeAuditRead, ScopeMessagesWrite}, "payments", tenant.Default)
+	mux := Router(h.WithAuth(keys, time.Minute).WithAudit(audit))
+
+	do := func(key, method, path, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-API-Key", key)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	do("payments", http.MethodPost, "/v1/messages/requeue", `{"ids":[8],"dryRun":false}`)
+	if len(audit.events) != 1 || audit.events[0].TenantID != "payments" {
+		t.Fatalf("expected the event to record the payments tenant, got %+v", audit.events)
+	}
+
+	if items := decodeJSON(t, do(tenant.Default, http.MethodGet, "/v1/audit", ""))["items"].([]any); len(items) != 0 {
+		t.Fatalf("expected another tenant not to see the event, got %v", items)
+	}
+	if items := decodeJSON(t, do("payments", http.MethodGet, "/v1/audit", ""))["items"].([]any); len(items) != 1 {
+		t.Fatalf("expected the payments tenant to see its event, got %v", items)
+	}
+}
@@ -0,0 +1,208 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/apikey"
+	"github.com/LeventeLantos/automatic-messaging/internal/logging"
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+	"github.com/LeventeLantos/automatic-messaging/internal/repo"
+	"github.com/LeventeLantos/automatic-messaging/internal/tenant"
+)
+
+// Scopes granted to API keys. ScopeAll grants every scope.
+const (
+	ScopeAll                 = "*"
+	ScopeMessagesRead        = "messages:read"
+	ScopeMessagesWrite       = "messages:write"
+	ScopePIIRead             = "pii:read"
+	ScopeSchedulerRead       = "scheduler:read"
+	ScopeSchedulerAdmin      = "scheduler:admin"
+	ScopeStatsRead           = "stats:read"
+	ScopeRoutesRead          = "routes:read"
+	ScopeRoutesWrite         = "routes:write"
+	ScopeSubscriptionsRead   = "subscriptions:read"
+	ScopeSubscriptionsWrite  = "subscriptions:write"
+	ScopeReconciliationAdmin = "reconciliation:admin"
+	ScopeAuditRead           = "audit:read"
+	ScopeTenantRead          = "tenant:read"
+	ScopeTenantWrite         = "tenant:write"
+)
+
+// Scopes lists every scope a key can be given.
+var Scopes = []string{
+	ScopeAll,
+	ScopeMessagesRead,
+	ScopeMessagesWrite,
+	ScopePIIRead,
+	ScopeSchedulerRead,
+	ScopeSchedulerAdmin,
+	ScopeStatsRead,
+	ScopeRoutesRead,
+	ScopeRoutesWrite,
+	ScopeSubscriptionsRead,
+	ScopeSubscriptionsWrite,
+	ScopeReconciliationAdmin,
+	ScopeAuditRead,
+	ScopeTenantRead,
+	ScopeTenantWrite,
+}
+
+type scopesKey struct{}
+
+// WithScopes returns a context granting scopes to the request it belongs to.
+func WithScopes(ctx context.Context, scopes ...string) context.Context {
+	return context.WithValue(ctx, scopesKey{}, scopes)
+}
+
+// HasScope reports whether the request context was granted scope.
+func HasScope(ctx context.Context, scope string) bool {
+	scopes, _ := ctx.Value(scopesKey{}).([]string)
+	return slices.Contains(scopes, scope) || slices.Contains(scopes, ScopeAll)
+}
+
+type apiKeyCtxKey struct{}
+
+// actor identifies who made the request in the audit log.
+func actor(ctx context.Context) string {
+	if k, ok := ctx.Value(apiKeyCtxKey{}).(*model.APIKey); ok {
+		return "apikey:" + k.Prefix
+	}
+	return "anonymous"
+}
+
+// APIKeyLookup finds an active API key by the hash of its value.
+type APIKeyLookup interface {
+	APIKeyByHash(ctx context.Context, hash string) (*model.APIKey, error)
+}
+
+// WithAuth requires an API key on every route except the health checks and
+// the signed delivery receipt callback. Requests are scoped to the key's
+// tenant; operator keys have none and see every tenant. Keys are cached for
+// cacheTTL, so a revoked key keeps working for at most that long.
+func (h *Handler) WithAuth(keys APIKeyLookup, cacheTTL time.Duration) *Handler {
+	h.keys = keys
+	h.keyCache = &keyCache{ttl: cacheTTL, entries: map[string]keyCacheEntry{}}
+	return h
+}
+
+// authorize wraps next so it only runs for requests whose API key has scope.
+// Without WithAuth every request is let through.
+func (h *Handler) authorize(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.keys == nil {
+			next(w, r)
+			return
+		}
+
+		raw := requestAPIKey(r)
+		if raw == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="automatic-messaging"`)
+			http.Error(w, "missing API key", http.StatusUnauthorized)
+			return
+		}
+
+		key, err := h.lookupKey(r.Context(), apikey.Hash(raw))
+		if errors.Is(err, repo.ErrNotFound) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="automatic-messaging", error="invalid_token"`)
+			http.Error(w, "invalid API key", http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		ctx := WithScopes(r.Context(), key.Scopes...)
+		ctx = context.WithValue(ctx, apiKeyCtxKey{}, key)
+		ctx = tenant.With(ctx, key.TenantID)
+		ctx = logging.With(ctx, "api_key", key.Prefix, "tenant", key.TenantID)
+		if !HasScope(ctx, scope) {
+			slog.WarnContext(ctx, "API key lacks scope", "scope", scope)
+			http.Error(w, "API key lacks scope "+scope, http.StatusForbidden)
+			return
+		}
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// operatorOnly wraps next so it only runs for operator keys, which belong to
+// no tenant, because it changes state shared by every tenant. It must be
+// wrapped by authorize. Without WithAuth every request is let through.
+func (h *Handler) operatorOnly(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.keys != nil && tenant.From(r.Context()) != "" {
+			http.Error(w, "this endpoint needs an operator key", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// requestAPIKey reads the key from "Authorization: Bearer" or X-API-Key.
+func requestAPIKey(r *http.Request) string {
+	if v, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(v)
+	}
+	return strings.TrimSpace(r.Header.Get("X-API-Key"))
+}
+
+func (h *Handler) lookupKey(ctx context.Context, hash string) (*model.APIKey, error) {
+	if k, ok := h.keyCache.get(hash); ok {
+		return k, nil
+	}
+	k, err := h.keys.APIKeyByHash(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	h.keyCache.put(hash, k)
+	return k, nil
+}
+
+// keyCache holds keys that authenticated recently. Unknown keys are not
+// cached, so random guesses cannot grow it.
+type keyCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]keyCacheEntry
+}
+
+type keyCacheEntry struct {
+	key     *model.APIKey
+	expires time.Time
+}
+
+func (c *keyCache) get(hash string) (*model.APIKey, bool) {
+	if c.ttl <= 0 {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[hash]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(e.expires) {
+		delete(c.entries, hash)
+		return nil, false
+	}
+	return e.key, true
+}
+
+func (c *keyCache) put(hash string, k *model.APIKey) {
+	if c.ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[hash] = keyCacheEntry{key: k, expires: time.Now().Add(c.ttl)}
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/apikey"
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+	"github.com/LeventeLantos/automatic-messaging/internal/redact"
+	"github.com/LeventeLantos/automatic-messaging/internal/repo"
+)
+
+type fakeKeys struct {
+	byHash  map[string]model.APIKey
+	lookups int
+}
+
+func (f *fakeKeys) APIKeyByHash(ctx context.Context, hash string) (*model.APIKey, error) {
+	f.lookups++
+	k, ok := f.byHash[hash]
+	if !ok {
+		return nil, repo.ErrNotFound
+	}
+	return &k, nil
+}
+
+func newFakeKeys(keys map[string][]string) *fakeKeys {
+	f := &fakeKeys{byHash: map[string]model.APIKey{}}
+	for raw, scopes := range keys {
+		f.byHash[apikey.Hash(raw)] = model.APIKey{Name: raw, Prefix: raw[:4], Scopes: scopes}
+	}
+	return f
+}
+
+func TestAuth(t *testing.T) {
+	keys := newFakeKeys(map[string][]string{
+		"reader-key": {ScopeMessagesRead, ScopeSchedulerRead},
+		"admin-key":  {ScopeAll},
+	})
+
+	cases := []struct {
+		name     string
+		method   string
+		path     string
+		header   string
+		value    string
+		wantCode int
+	}{
+		{"health is public", http.MethodGet, "/v1/health", "", "", http.StatusOK},
+		{"readiness is public", http.MethodGet, "/v1/health/ready", "", "", http.StatusOK},
+		{"missing key", http.MethodGet, "/v1/scheduler/status", "", "", http.StatusUnauthorized},
+		{"unknown key", http.MethodGet, "/v1/scheduler/status", "Authorization", "Bearer nope", http.StatusUnauthorized},
+		{"bearer key with scope", http.MethodGet, "/v1/scheduler/status", "Authorization", "Bearer reader-key", http.StatusOK},
+		{"X-API-Key with scope", http.MethodGet, "/v1/messages/sent", "X-API-Key", "reader-key", http.StatusOK},
+		{"key without scope", http.MethodPost, "/v1/scheduler/stop", "X-API-Key", "reader-key", http.StatusForbidden},
+		{"wildcard scope", http.MethodPost, "/v1/scheduler/stop", "X-API-Key", "admin-key", http.StatusOK},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, h := newTestHandler(t, &fakeRepo{})
+			defer s.Stop()
+			mux := Router(h.WithAuth(keys, time.Minute))
+
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			if tc.header != "" {
+				req.Header.Set(tc.header, tc.value)
+			}
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, req)
+
+			if rr.Code != tc.wantCode {
+				t.Fatalf("expected %d, got %d body=%q", tc.wantCode, rr.Code, rr.Body.String())
+			}
+			if rr.Code == http.StatusUnauthorized && rr.Header().Get("WWW-Authenticate") == "" {
+				t.Fatalf("expected a WWW-Authenticate header on 401")
+			}
+		})
+	}
+}
+
+func TestAuth_CachesKeysAndGrantsPII(t *testing.T) {
+	keys := newFakeKeys(map[string][]string{"pii-key": {ScopeMessagesRead, ScopePIIRead}})
+	msg := model.Message{ID: 1, RecipientPhone: "+36301234111"}
+
+	s, h := newTestHandler(t, &fakeRepo{items: []model.Message{msg}})
+	defer s.Stop()
+	h.WithAuth(keys, time.Minute).WithRedaction(redact.Policy{Phones: true})
+	mux := Router(h)
+
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/v1/messages/sent", nil)
+		req.Header.Set("X-API-Key", "pii-key")
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+
+		item := decodeJSON(t, rr)["items"].([]any)[0].(map[string]any)
+		if item["RecipientPhone"] != msg.RecipientPhone {
+			t.Fatalf("expected the pii:read scope to unmask the phone, got %v", item)
+		}
+	}
+	if keys.lookups != 1 {
+		t.Fatalf("expected one key lookup thanks to the cache, got %d", keys.lookups)
+	}
+}
+
+func TestAuth_OperatorOnlyEndpoints(t *testing.T) {
+	s, h := newTestHandler(t, &fakeRepo{})
+	defer s.Stop()
+	s.Start()
+	keys := tenantKeys([]string{ScopeAll}, "payments")
+	mux := Router(h.WithAuth(keys, time.Minute))
+
+	for _, ep := range []struct{ method, path string }{
+		{http.MethodPost, "/v1/scheduler/start"},
+		{http.MethodPost, "/v1/scheduler/stop"},
+		{http.MethodGet, "/v1/reconciliation"},
+		{http.MethodPost, "/v1/reconciliation/run"},
+		{http.MethodGet, "/v1/cache/stats"},
+		{http.MethodGet, "/v1/providers"},
+	} {
+		req := httptest.NewRequest(ep.method, ep.path, nil)
+		req.Header.Set("X-API-Key", "payments")
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusForbidden {
+			t.Fatalf("%s %s: expected a tenant key to be refused, got %d", ep.method, ep.path, rr.Code)
+		}
+	}
+	if !s.IsRunning() {
+		t.Fatalf("expected the scheduler to keep running")
+	}
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+	"github.com/LeventeLantos/automatic-messaging/internal/signature"
+)
+
+// WithReceipts enables signed delivery receipt callbacks.
+func (h *Handler) WithReceipts(secret []byte, tolerance time.Duration) *Handler {
+	h.receiptSecret = secret
+	h.receiptTolerance = tolerance
+	return h
+}
+
+type deliveryReceiptRequest struct {
+	RemoteMessageID string     `json:"remoteMessageId"`
+	Status          string     `json:"status"`
+	Timestamp       *time.Time `json:"timestamp"`
+	ErrorCode       string     `json:"errorCode"`
+}
+
+// DeliveryReceipt applies a provider delivery report. Receipts for messages
+// that already have a final delivery status are acknowledged but not applied,
+// so providers can safely retry.
+func (h *Handler) DeliveryReceipt(w http.ResponseWriter, r *http.Request) {
+	if len(h.receiptSecret) == 0 {
+		http.Error(w, "delivery receipts are not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := signature.Verify(
+		h.receiptSecret,
+		r.Header.Get(signature.HeaderTimestamp),
+		r.Header.Get(signature.HeaderSignature),
+		body,
+		h.receiptTolerance,
+		time.Now(),
+	); err != nil {
+		http.Error(w, "invalid signature: "+err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	// Unknown fields are allowed: providers tend to add their own.
+	var req deliveryReceiptRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rcpt, err := req.toReceipt()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id, err := h.resolveRemote(r.Context(), rcpt.RemoteMessageID)
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+
+	applied, err := h.repo.ApplyReceipt(r.Context(), id, rcpt)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if applied {
+		h.invalidate(r.Context(), id)
+		slog.InfoContext(r.Context(), "delivery receipt applied", "id", id, "status", rcpt.Status, "error_code", rcpt.ErrorCode)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"id": id, "applied": applied})
+}
+
+func (req deliveryReceiptRequest) toReceipt() (model.Receipt, error) {
+	if req.RemoteMessageID == "" {
+		return model.Receipt{}, errors.New("remoteMessageId is required")
+	}
+
+	status := model.Status(req.Status)
+	if status != model.Delivered && status != model.Undelivered {
+		return model.Receipt{}, errors.New("status must be delivered or undelivered")
+	}
+
+	at := time.Now().UTC()
+	if req.Timestamp != nil {
+		at = req.Timestamp.UTC()
+	}
+
+	return model.Receipt{
+		RemoteMessageID: req.RemoteMessageID,
+		Status:          status,
+		At:              at,
+		ErrorCode:       req.ErrorCode,
+	}, nil
+}
+
+// resolveRemote maps a provider message ID to our message ID, trying the
+// cache's reverse lookup before Postgres.
+func (h *Handler) resolveRemote(ctx context.Context, remoteMessageID string) (int64, error) {
+	if h.cache != nil {
+		id, ok, err := h.cache.LookupRemote(ctx, remoteMessageID)
+		if err != nil {
+			slog.WarnContext(ctx, "cache reverse lookup failed", "remote_message_id", remoteMessageID, "err", err)
+		}
+		if ok {
+			return id, nil
+		}
+	}
+
+	m, err := h.repo.GetByRemoteID(ctx, remoteMessageID)
+	if err != nil {
+		return 0, err
+	}
+	return m.ID, nil
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+	"github.com/LeventeLantos/automatic-messaging/internal/signature"
+)
+
+var testReceiptSecret = []byte("dlr-secret")
+
+func signedReceipt(t *testing.T, body string, at time.Time) *http.Request {
+	t.Helper()
+
+	ts := signature.Timestamp(at)
+	req := httptest.NewRequest(http.MethodPost, "/v1/callbacks/delivery", strings.NewReader(body))
+	req.Header.Set(signature.HeaderTimestamp, ts)
+	req.Header.Set(signature.HeaderSignature, signature.Sign(testReceiptSecret, ts, []byte(body)))
+	return req
+}
+
+func newReceiptFixture(t *testing.T) (*fakeRepo, *fakeCache, http.Handler, func()) {
+	t.Helper()
+
+	remoteA := "remote-a"
+	remoteB := "remote-b"
+	fr := &fakeRepo{
+		byID: map[int64]model.Message{
+			1: {ID: 1, Status: model.Sent, RemoteMessageID: &remoteA},
+			2: {ID: 2, Status: model.Sent, RemoteMessageID: &remoteB},
+		},
+	}
+	fc := &fakeCache{
+		records: map[int64]model.Message{1: fr.byID[1]},
+		remote:  map[string]int64{remoteA: 1},
+	}
+
+	s, h := newTestHandler(t, fr)
+	mux := Router(h.WithCache(fc).WithReceipts(testReceiptSecret, time.Minute))
+	return fr, fc, mux, func() { s.Stop() }
+}
+
+func TestDeliveryReceipt_AppliesOnceAndIsIdempotent(t *testing.T) {
+	fr, fc, mux, stop := newReceiptFixture(t)
+	defer stop()
+
+	body := `{"remoteMessageId":"remote-a","status":"delivered","timestamp":"2026-02-02T18:00:00Z","carrier":"x"}`
+
+	for i, wantApplied := range []bool{true, false} {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, signedReceipt(t, body, time.Now()))
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d body=%q", i, rr.Code, rr.Body.String())
+		}
+		if got := decodeJSON(t, rr)["applied"]; got != wantApplied {
+			t.Fatalf("request %d: expected applied=%v, got %v", i, wantApplied, got)
+		}
+	}
+
+	m := fr.byID[1]
+	if m.Status != model.Delivered || m.DeliveryStatusAt == nil {
+		t.Fatalf("expected message delivered with timestamp, got %+v", m)
+	}
+	if fr.getCalls != 0 {
+		t.Fatalf("expected remote id to be resolved from cache, got %d repo lookups", fr.getCalls)
+	}
+	if _, ok := fc.records[1]; ok {
+		t.Fatalf("expected cached record to be invalidated")
+	}
+}
+
+func TestDeliveryReceipt_UndeliveredViaRepoLookup(t *testing.T) {
+	fr, _, mux, stop := newReceiptFixture(t)
+	defer stop()
+
+	body := `{"remoteMessageId":"remote-b","status":"undelivered","errorCode":"EC_ABSENT_SUBSCRIBER"}`
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, signedReceipt(t, body, time.Now()))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%q", rr.Code, rr.Body.String())
+	}
+
+	m := fr.byID[2]
+	if m.Status != model.Undelivered || m.DeliveryErrorCode == nil || *m.DeliveryErrorCode != "EC_ABSENT_SUBSCRIBER" {
+		t.Fatalf("expected undelivered with error code, got %+v", m)
+	}
+}
+
+func TestDeliveryReceipt_Rejections(t *testing.T) {
+	_, _, mux, stop := newReceiptFixture(t)
+	defer stop()
+
+	valid := `{"remoteMessageId":"remote-a","status":"delivered"}`
+
+	unsigned := httptest.NewRequest(http.MethodPost, "/v1/callbacks/delivery", strings.NewReader(valid))
+
+	tampered := signedReceipt(t, valid, time.Now())
+	tampered.Body = http.NoBody
+
+	cases := []struct {
+		name string
+		req  *http.Request
+		want int
+	}{
+		{"unsigned", unsigned, http.StatusUnauthorized},
+		{"tampered body", tampered, http.StatusUnauthorized},
+		{"stale timestamp", signedReceipt(t, valid, time.Now().Add(-time.Hour)), http.StatusUnauthorized},
+		{"bad status", signedReceipt(t, `{"remoteMessageId":"remote-a","status":"sent"}`, time.Now()), http.StatusBadRequest},
+		{"missing remote id", signedReceipt(t, `{"status":"delivered"}`, time.Now()), http.StatusBadRequest},
+		{"unknown remote id", signedReceipt(t, `{"remoteMessageId":"nope","status":"delivered"}`, time.Now()), http.StatusNotFound},
+	}
+
+	for _, tc := range cases {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, tc.req)
+
+		if rr.Code != tc.want {
+			t.Fatalf("%s: expected %d, got %d body=%q", tc.name, tc.want, rr.Code, rr.Body.String())
+		}
+	}
+}
+
+func TestDeliveryReceipt_NotConfigured(t *testing.T) {
+	s, mux := newTestServer(t, &fakeRepo{})
+	defer s.Stop()
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/callbacks/delivery", strings.NewReader(`{}`))
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d body=%q", rr.Code, rr.Body.String())
+	}
+}
@@ -1,39 +1,116 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
+	"github.com/LeventeLantos/automatic-messaging/internal/breaker"
+	"github.com/LeventeLantos/automatic-messaging/internal/cache"
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+	"github.com/LeventeLantos/automatic-messaging/internal/redact"
 	"github.com/LeventeLantos/automatic-messaging/internal/repo"
 	"github.com/LeventeLantos/automatic-messaging/internal/scheduler"
+	"github.com/LeventeLantos/automatic-messaging/internal/tenant"
 )
 
 type Handler struct {
-	sched *scheduler.Scheduler
-	repo  repo.MessageRepository
+	sched      *scheduler.Scheduler
+	repo       repo.MessageRepository
+	cache      cache.MessageCache
+	attempts   repo.AttemptRepository
+	subs       repo.SubscriptionRepository
+	providers  ProviderHealthSource
+	breaker    BreakerStatusSource
+	reconciler Reconciler
+	contentMax int
+
+	stats repo.StatsRepository
+	slo   SLOThresholds
+
+	redaction *redact.Policy
+
+	keys     APIKeyLookup
+	keyCache *keyCache
+	auditLog repo.AuditRepository
+
+	readyChecks  []ReadinessCheck
+	readyTimeout time.Duration
+
+	routes        repo.RouteRepository
+	routesChanged func()
+	tenants       repo.TenantRepository
+
+	receiptSecret    []byte
+	receiptTolerance time.Duration
 }
 
 func NewHandler(s *scheduler.Scheduler, r repo.MessageRepository) *Handler {
 	return &Handler{sched: s, repo: r}
 }
 
+// WithCache enables the cache-aside read path for single message lookups.
+func (h *Handler) WithCache(c cache.MessageCache) *Handler {
+	h.cache = c
+	return h
+}
+
+// WithAttempts enables the per-message attempt history endpoint.
+func (h *Handler) WithAttempts(a repo.AttemptRepository) *Handler {
+	h.attempts = a
+	return h
+}
+
+// WithContentMax limits the content length accepted when editing messages.
+func (h *Handler) WithContentMax(n int) *Handler {
+	h.contentMax = n
+	return h
+}
+
 func (h *Handler) Health(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
 }
 
+// BreakerStatusSource reports the provider circuit breaker state.
+type BreakerStatusSource interface {
+	Status() breaker.Status
+}
+
+// WithBreaker adds the circuit breaker state to scheduler status responses.
+func (h *Handler) WithBreaker(b BreakerStatusSource) *Handler {
+	h.breaker = b
+	return h
+}
+
 func (h *Handler) SchedulerStatus(w http.ResponseWriter, r *http.Request) {
-	writeJSON(w, http.StatusOK, map[string]any{"running": h.sched.IsRunning()})
+	writeJSON(w, http.StatusOK, h.schedulerStatus())
 }
 
 func (h *Handler) SchedulerStart(w http.ResponseWriter, r *http.Request) {
 	h.sched.Start()
-	writeJSON(w, http.StatusOK, map[string]any{"running": h.sched.IsRunning()})
+	h.audit(r, model.AuditSchedulerStart, "scheduler", nil)
+	writeJSON(w, http.StatusOK, h.schedulerStatus())
 }
 
 func (h *Handler) SchedulerStop(w http.ResponseWriter, r *http.Request) {
 	h.sched.Stop()
-	writeJSON(w, http.StatusOK, map[string]any{"running": h.sched.IsRunning()})
+	h.audit(r, model.AuditSchedulerStop, "scheduler", nil)
+	writeJSON(w, http.StatusOK, h.schedulerStatus())
+}
+
+func (h *Handler) schedulerStatus() map[string]any {
+	out := map[string]any{"running": h.sched.IsRunning()}
+	if h.breaker != nil {
+		out["breaker"] = h.breaker.Status()
+	}
+	return out
 }
 
 func (h *Handler) ListSentMessages(w http.ResponseWriter, r *http.Request) {
@@ -45,10 +122,301 @@ func (h *Handler) ListSentMessages(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	out := make([]model.Message, len(items))
+	for i, m := range items {
+		out[i] = h.present(r, m)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"items": out})
+}
+
+func (h *Handler) GetMessage(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r)
+	if !ok {
+		return
+	}
+
+	m, err := h.lookupMessage(r.Context(), id)
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, h.present(r, *m))
+}
+
+func (h *Handler) ListAttempts(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r)
+	if !ok {
+		return
+	}
+	if h.attempts == nil {
+		http.Error(w, "attempt history is not enabled", http.StatusNotFound)
+		return
+	}
+
+	if _, err := h.lookupMessage(r.Context(), id); err != nil {
+		writeRepoError(w, err)
+		return
+	}
+
+	items, err := h.attempts.ListAttempts(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if items == nil {
+		items = []model.Attempt{}
+	}
 
 	writeJSON(w, http.StatusOK, map[string]any{"items": items})
 }
 
+func (h *Handler) CancelMessage(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r)
+	if !ok {
+		return
+	}
+
+	m, err := h.repo.Cancel(r.Context(), id)
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	h.audit(r, model.AuditMessageCancel, messageTarget(id), nil)
+
+	writeJSON(w, http.StatusOK, h.present(r, *m))
+}
+
+type updateMessageRequest struct {
+	RecipientPhone *string `json:"recipientPhone"`
+	Content        *string `json:"content"`
+}
+
+func (h *Handler) UpdateMessage(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r)
+	if !ok {
+		return
+	}
+
+	var req updateMessageRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if err := h.validateUpdate(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	m, err := h.repo.UpdatePending(r.Context(), id, repo.MessageUpdate{
+		RecipientPhone: req.RecipientPhone,
+		Content:        req.Content,
+	})
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	h.audit(r, model.AuditMessageUpdate, messageTarget(id), map[string]any{"fields": req.fields()})
+
+	writeJSON(w, http.StatusOK, h.present(r, *m))
+}
+
+// fields names the fields an update changes. The audit log keeps only the
+// names, since the values are recipient data.
+func (req updateMessageRequest) fields() []string {
+	var out []string
+	if req.RecipientPhone != nil {
+		out = append(out, "recipientPhone")
+	}
+	if req.Content != nil {
+		out = append(out, "content")
+	}
+	return out
+}
+
+func (h *Handler) validateUpdate(req updateMessageRequest) error {
+	if req.RecipientPhone == nil && req.Content == nil {
+		return errors.New("nothing to update: set recipientPhone and/or content")
+	}
+	if req.RecipientPhone != nil && strings.TrimSpace(*req.RecipientPhone) == "" {
+		return errors.New("recipientPhone must not be empty")
+	}
+	if req.Content != nil {
+		if *req.Content == "" {
+			return errors.New("content must not be empty")
+		}
+		if h.contentMax > 0 && utf8.RuneCountInString(*req.Content) > h.contentMax {
+			return fmt.Errorf("content exceeds %d chars", h.contentMax)
+		}
+	}
+	return nil
+}
+
+type requeueRequest struct {
+	FailedFrom    *time.Time `json:"failedFrom"`
+	FailedTo      *time.Time `json:"failedTo"`
+	ErrorContains string     `json:"errorContains"`
+	IDs           []int64    `json:"ids"`
+	Note          string     `json:"note"`
+	DryRun        *bool      `json:"dryRun"`
+}
+
+const defaultRequeueNote = "requeued via API"
+
+// RequeueMessages moves failed messages back to pending. It is a dry run
+// unless the request explicitly sets dryRun to false.
+func (h *Handler) RequeueMessages(w http.ResponseWriter, r *http.Request) {
+	var req requeueRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	filter := repo.RequeueFilter{
+		FailedFrom:    req.FailedFrom,
+		FailedTo:      req.FailedTo,
+		ErrorContains: req.ErrorContains,
+		IDs:           req.IDs,
+	}
+	if filter.IsEmpty() {
+		http.Error(w, "at least one filter is required: failedFrom, failedTo, errorContains or ids", http.StatusBadRequest)
+		return
+	}
+	if filter.FailedFrom != nil && filter.FailedTo != nil && !filter.FailedFrom.Before(*filter.FailedTo) {
+		http.Error(w, "failedFrom must be before failedTo", http.StatusBadRequest)
+		return
+	}
+
+	dryRun := req.DryRun == nil || *req.DryRun
+	note := strings.TrimSpace(req.Note)
+	if note == "" {
+		note = defaultRequeueNote
+	}
+
+	ids, err := h.repo.RequeueFailed(r.Context(), filter, note, dryRun)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if !dryRun {
+		slog.InfoContext(r.Context(), "messages requeued", "count", len(ids), "note", note)
+		h.invalidate(r.Context(), ids...)
+		h.audit(r, model.AuditMessagesRequeue, "messages", map[string]any{
+			"failedFrom":    req.FailedFrom,
+			"failedTo":      req.FailedTo,
+			"errorContains": req.ErrorContains,
+			"ids":           req.IDs,
+			"note":          note,
+			"count":         len(ids),
+		})
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"dryRun": dryRun,
+		"count":  len(ids),
+	})
+}
+
+func (h *Handler) CacheStats(w http.ResponseWriter, r *http.Request) {
+	if h.cache == nil {
+		writeJSON(w, http.StatusOK, map[string]any{"enabled": false})
+		return
+	}
+
+	stats := h.cache.Stats()
+	writeJSON(w, http.StatusOK, map[string]any{
+		"enabled": true,
+		"hits":    stats.Hits,
+		"misses":  stats.Misses,
+	})
+}
+
+// lookupMessage reads through the cache when one is configured. Cache errors
+// are logged and treated as misses so Redis never makes the endpoint fail.
+// The cache is shared by all tenants, so a hit for another tenant's message
+// is treated as a miss and left to the tenant-scoped repository.
+func (h *Handler) lookupMessage(ctx context.Context, id int64) (*model.Message, error) {
+	if h.cache != nil {
+		m, ok, err := h.cache.GetMessage(ctx, id)
+		if err != nil {
+			slog.WarnContext(ctx, "cache read failed", "id", id, "err", err)
+		}
+		if ok && (tenant.From(ctx) == "" || m.TenantID == tenant.From(ctx)) {
+			return m, nil
+		}
+	}
+
+	m, err := h.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if h.cache != nil && cacheable(m.Status) {
+		if err := h.cache.StoreMessage(ctx, *m); err != nil {
+			slog.WarnContext(ctx, "cache fill failed", "id", id, "err", err)
+		}
+	}
+	return m, nil
+}
+
+func (h *Handler) invalidate(ctx context.Context, ids ...int64) {
+	if h.cache == nil {
+		return
+	}
+	for _, id := range ids {
+		if err := h.cache.Invalidate(ctx, id); err != nil {
+			slog.WarnContext(ctx, "cache invalidate failed", "id", id, "err", err)
+		}
+	}
+}
+
+// cacheable reports whether a message in this status can be cached. Rows that
+// the scheduler still moves around are always read from Postgres.
+func cacheable(s model.Status) bool {
+	switch s {
+	case model.Sent, model.Failed, model.Cancelled,
+		model.Delivered, model.Undelivered, model.Unknown:
+		return true
+	default:
+		return false
+	}
+}
+
+func messageTarget(id int64) string {
+	return "message:" + strconv.FormatInt(id, 10)
+}
+
+func parseID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		http.Error(w, "invalid message id", http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+// writeRepoError maps repository sentinel errors to HTTP status codes.
+func writeRepoError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repo.ErrNotFound):
+		http.Error(w, "message not found", http.StatusNotFound)
+	case errors.Is(err, repo.ErrNotPending):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func decodeBody(w http.ResponseWriter, r *http.Request, dst any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
 func parseInt(raw string, def int) int {
 	if raw == "" {
 		return def
@@ -10,9 +10,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/LeventeLantos/automatic-messaging/internal/breaker"
+	"github.com/LeventeLantos/automatic-messaging/internal/cache"
 	"github.com/LeventeLantos/automatic-messaging/internal/model"
 	"github.com/LeventeLantos/automatic-messaging/internal/repo"
 	"github.com/LeventeLantos/automatic-messaging/internal/scheduler"
+	"github.com/LeventeLantos/automatic-messaging/internal/tenant"
 )
 
 type fakeRepo struct {
@@ -23,6 +26,14 @@ type fakeRepo struct {
 	// behavior
 	items []model.Message
 	err   error
+
+	byID     map[int64]model.Message
+	getCalls int
+
+	requeueFilter repo.RequeueFilter
+	requeueNote   string
+	requeueDryRun bool
+	requeueIDs    []int64
 }
 
 var _ repo.MessageRepository = (*fakeRepo)(nil)
@@ -31,7 +42,11 @@ func (f *fakeRepo) ClaimPending(ctx context.Context, limit int) ([]model.Message
 	return nil, errors.New("not implemented")
 }
 
-func (f *fakeRepo) MarkSent(ctx context.Context, id int64, remoteMessageID string) error {
+func (f *fakeRepo) MarkSent(ctx context.Context, id int64, remoteMessageID, provider string) error {
+	return errors.New("not implemented")
+}
+
+func (f *fakeRepo) MarkReconciledSent(ctx context.Context, id int64, remoteMessageID, provider string) error {
 	return errors.New("not implemented")
 }
 
@@ -39,23 +54,200 @@ func (f *fakeRepo) MarkFailed(ctx context.Context, id int64, reason string) erro
 	return errors.New("not implemented")
 }
 
+func (f *fakeRepo) MarkSending(ctx context.Context, id int64) error {
+	return errors.New("not implemented")
+}
+
+func (f *fakeRepo) ReleaseClaimed(ctx context.Context, ids []int64) error {
+	return errors.New("not implemented")
+}
+
+func (f *fakeRepo) ReleaseStuck(ctx context.Context, before time.Time) ([]int64, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeRepo) ListAmbiguous(ctx context.Context, before time.Time, limit int) ([]model.Message, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeRepo) ListAwaitingReceipt(ctx context.Context, sentBefore, checkedBefore time.Time, limit int) ([]model.Message, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeRepo) MarkStatusChecked(ctx context.Context, id int64) error {
+	return errors.New("not implemented")
+}
+
+func (f *fakeRepo) QueueStats(ctx context.Context) (repo.QueueStats, error) {
+	return repo.QueueStats{}, errors.New("not implemented")
+}
+
 func (f *fakeRepo) ListSent(ctx context.Context, limit, offset int) ([]model.Message, error) {
 	f.gotLimit = limit
 	f.gotOffset = offset
 	return f.items, f.err
 }
 
+func (f *fakeRepo) GetByID(ctx context.Context, id int64) (*model.Message, error) {
+	f.getCalls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	m, ok := f.byID[id]
+	if !ok || (tenant.From(ctx) != "" && m.TenantID != tenant.From(ctx)) {
+		return nil, repo.ErrNotFound
+	}
+	return &m, nil
+}
+
+func (f *fakeRepo) Cancel(ctx context.Context, id int64) (*model.Message, error) {
+	return f.updatePending(id, func(m *model.Message) {
+		m.Status = model.Cancelled
+	})
+}
+
+func (f *fakeRepo) UpdatePending(ctx context.Context, id int64, upd repo.MessageUpdate) (*model.Message, error) {
+	return f.updatePending(id, func(m *model.Message) {
+		if upd.RecipientPhone != nil {
+			m.RecipientPhone = *upd.RecipientPhone
+		}
+		if upd.Content != nil {
+			m.Content = *upd.Content
+		}
+	})
+}
+
+func (f *fakeRepo) RequeueFailed(ctx context.Context, filter repo.RequeueFilter, note string, dryRun bool) ([]int64, error) {
+	f.requeueFilter = filter
+	f.requeueNote = note
+	f.requeueDryRun = dryRun
+	return f.requeueIDs, f.err
+}
+
+func (f *fakeRepo) GetByRemoteID(ctx context.Context, remoteMessageID string) (*model.Message, error) {
+	f.getCalls++
+	for _, m := range f.byID {
+		if m.RemoteMessageID != nil && *m.RemoteMessageID == remoteMessageID {
+			return &m, nil
+		}
+	}
+	return nil, repo.ErrNotFound
+}
+
+func (f *fakeRepo) ApplyReceipt(ctx context.Context, id int64, rcpt model.Receipt) (bool, error) {
+	m, ok := f.byID[id]
+	if !ok || (m.Status != model.Sent && m.Status != model.Unknown) {
+		return false, nil
+	}
+	m.Status = rcpt.Status
+	m.DeliveryStatusAt = &rcpt.At
+	if rcpt.ErrorCode != "" {
+		m.DeliveryErrorCode = &rcpt.ErrorCode
+	}
+	f.byID[id] = m
+	return true, nil
+}
+
+func (f *fakeRepo) MarkUnknownWithoutReceipt(ctx context.Context, sentBefore time.Time) ([]int64, error) {
+	return nil, errors.New("not implemented")
+}
+
+// updatePending mimics the repository's status guard: only pending rows change.
+func (f *fakeRepo) updatePending(id int64, apply func(m *model.Message)) (*model.Message, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	m, ok := f.byID[id]
+	if !ok {
+		return nil, repo.ErrNotFound
+	}
+	if m.Status != model.Pending {
+		return nil, repo.ErrNotPending
+	}
+	apply(&m)
+	f.byID[id] = m
+	return &m, nil
+}
+
+type fakeAttempts struct {
+	items map[int64][]model.Attempt
+}
+
+func (f *fakeAttempts) RecordAttempt(ctx context.Context, a model.Attempt) error {
+	return errors.New("not implemented")
+}
+
+func (f *fakeAttempts) ListAttempts(ctx context.Context, messageID int64) ([]model.Attempt, error) {
+	return f.items[messageID], nil
+}
+
+type fakeCache struct {
+	records map[int64]model.Message
+	remote  map[string]int64
+	stores  int
+	stats   cache.Stats
+}
+
+var _ cache.MessageCache = (*fakeCache)(nil)
+
+func (f *fakeCache) StoreSent(ctx context.Context, internalID int64, remoteMessageID string, sentAt time.Time) error {
+	return nil
+}
+
+func (f *fakeCache) GetSent(ctx context.Context, internalID int64) (cache.SentEntry, bool, error) {
+	return cache.SentEntry{}, false, nil
+}
+
+func (f *fakeCache) LookupRemote(ctx context.Context, remoteMessageID string) (int64, bool, error) {
+	id, ok := f.remote[remoteMessageID]
+	return id, ok, nil
+}
+
+func (f *fakeCache) GetMessage(ctx context.Context, id int64) (*model.Message, bool, error) {
+	m, ok := f.records[id]
+	if !ok {
+		f.stats.Misses++
+		return nil, false, nil
+	}
+	f.stats.Hits++
+	return &m, true, nil
+}
+
+func (f *fakeCache) StoreMessage(ctx context.Context, m model.Message) error {
+	if f.records == nil {
+		f.records = map[int64]model.Message{}
+	}
+	f.records[m.ID] = m
+	f.stores++
+	return nil
+}
+
+func (f *fakeCache) Invalidate(ctx context.Context, id int64) error {
+	delete(f.records, id)
+	return nil
+}
+
+func (f *fakeCache) Stats() cache.Stats {
+	return f.stats
+}
+
 func newTestServer(t *testing.T, r repo.MessageRepository) (*scheduler.Scheduler, http.Handler) {
 	t.Helper()
 
+	s, h := newTestHandler(t, r)
+	return s, Router(h)
+}
+
+func newTestHandler(t *testing.T, r repo.MessageRepository) (*scheduler.Scheduler, *Handler) {
+	t.Helper()
+
 	// Long interval so only the immediate tick happens (noop anyway).
 	s, err := scheduler.New(time.Hour, func(context.Context) {})
 	if err != nil {
 		t.Fatalf("failed to create scheduler: %v", err)
 	}
 
-	h := NewHandler(s, r)
-	return s, Router(h)
+	return s, NewHandler(s, r)
 }
 
 func decodeJSON(t *testing.T, rr *httptest.ResponseRecorder) map[string]any {
@@ -140,6 +332,25 @@ func TestSchedulerEndpoints(t *testing.T) {
 	}
 }
 
+type fakeBreaker struct{ st breaker.Status }
+
+func (f fakeBreaker) Status() breaker.Status { return f.st }
+
+func TestSchedulerStatus_IncludesBreaker(t *testing.T) {
+	s, h := newTestHandler(t, &fakeRepo{})
+	defer s.Stop()
+	mux := Router(h.WithBreaker(fakeBreaker{st: breaker.Status{State: breaker.Open, Failures: 3, Requests: 4}}))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/scheduler/status", nil)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	brk, _ := decodeJSON(t, rr)["breaker"].(map[string]any)
+	if rr.Code != http.StatusOK || brk["state"] != "open" || brk["failures"] != float64(3) {
+		t.Fatalf("expected breaker state in status, got %d body=%q", rr.Code, rr.Body.String())
+	}
+}
+
 func TestListSentMessages_DefaultsAndArgs(t *testing.T) {
 	fr := &fakeRepo{
 		items: []model.Message{
@@ -243,3 +454,313 @@ func TestRouterRoot(t *testing.T) {
 		t.Fatalf("expected body %q, got %q", "automatic-messaging", got)
 	}
 }
+
+func TestGetMessage_CacheMissFillsCacheThenHitSkipsRepo(t *testing.T) {
+	fr := &fakeRepo{
+		byID: map[int64]model.Message{
+			7: {ID: 7, RecipientPhone: "+361", Content: "a", Status: model.Sent},
+		},
+	}
+	fc := &fakeCache{}
+
+	s, h := newTestHandler(t, fr)
+	defer s.Stop()
+	mux := Router(h.WithCache(fc))
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/v1/messages/7", nil)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d body=%q", i, rr.Code, rr.Body.String())
+		}
+		body := decodeJSON(t, rr)
+		if id, _ := body["ID"].(float64); id != 7 {
+			t.Fatalf("request %d: expected ID=7, got %v", i, body)
+		}
+	}
+
+	if fr.getCalls != 1 {
+		t.Fatalf("expected repo to be hit once, got %d", fr.getCalls)
+	}
+	if fc.stores != 1 {
+		t.Fatalf("expected cache to be filled once, got %d", fc.stores)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/cache/stats", nil)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	body := decodeJSON(t, rr)
+	if body["hits"] != float64(1) || body["misses"] != float64(1) {
+		t.Fatalf("expected hits=1 misses=1, got %v", body)
+	}
+}
+
+func TestGetMessage_PendingIsNotCached(t *testing.T) {
+	fr := &fakeRepo{
+		byID: map[int64]model.Message{
+			3: {ID: 3, Status: model.Pending},
+		},
+	}
+	fc := &fakeCache{}
+
+	s, h := newTestHandler(t, fr)
+	defer s.Stop()
+	mux := Router(h.WithCache(fc))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/messages/3", nil)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%q", rr.Code, rr.Body.String())
+	}
+	if fc.stores != 0 {
+		t.Fatalf("expected pending message not to be cached, got %d stores", fc.stores)
+	}
+}
+
+func TestGetMessage_NotFoundAndInvalidID(t *testing.T) {
+	s, mux := newTestServer(t, &fakeRepo{})
+	defer s.Stop()
+
+	cases := []struct {
+		path string
+		want int
+	}{
+		{"/v1/messages/42", http.StatusNotFound},
+		{"/v1/messages/abc", http.StatusBadRequest},
+		{"/v1/messages/0", http.StatusBadRequest},
+	}
+
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+
+		if rr.Code != tc.want {
+			t.Fatalf("%s: expected %d, got %d body=%q", tc.path, tc.want, rr.Code, rr.Body.String())
+		}
+	}
+}
+
+func TestCacheStats_Disabled(t *testing.T) {
+	s, mux := newTestServer(t, &fakeRepo{})
+	defer s.Stop()
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/cache/stats", nil)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	body := decodeJSON(t, rr)
+	if enabled, ok := body["enabled"].(bool); !ok || enabled {
+		t.Fatalf("expected enabled=false, got %v", body)
+	}
+}
+
+func TestCancelMessage(t *testing.T) {
+	fr := &fakeRepo{
+		byID: map[int64]model.Message{
+			1: {ID: 1, Status: model.Pending},
+			2: {ID: 2, Status: model.Processing},
+		},
+	}
+	s, mux := newTestServer(t, fr)
+	defer s.Stop()
+
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		want   int
+	}{
+		{"pending via DELETE", http.MethodDelete, "/v1/messages/1", http.StatusOK},
+		{"already cancelled", http.MethodPost, "/v1/messages/1/cancel", http.StatusConflict},
+		{"claimed by scheduler", http.MethodPost, "/v1/messages/2/cancel", http.StatusConflict},
+		{"missing", http.MethodDelete, "/v1/messages/3", http.StatusNotFound},
+	}
+
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+
+		if rr.Code != tc.want {
+			t.Fatalf("%s: expected %d, got %d body=%q", tc.name, tc.want, rr.Code, rr.Body.String())
+		}
+	}
+
+	if got := fr.byID[1].Status; got != model.Cancelled {
+		t.Fatalf("expected message 1 cancelled, got %q", got)
+	}
+}
+
+func TestUpdateMessage(t *testing.T) {
+	fr := &fakeRepo{
+		byID: map[int64]model.Message{
+			1: {ID: 1, RecipientPhone: "+361", Content: "old", Status: model.Pending},
+			2: {ID: 2, RecipientPhone: "+362", Content: "old", Status: model.Sent},
+		},
+	}
+	s, h := newTestHandler(t, fr)
+	defer s.Stop()
+	mux := Router(h.WithContentMax(5))
+
+	cases := []struct {
+		name string
+		path string
+		body string
+		want int
+	}{
+		{"edit content", "/v1/messages/1", `{"content":"new"}`, http.StatusOK},
+		{"edit recipient", "/v1/messages/1", `{"recipientPhone":"+369"}`, http.StatusOK},
+		{"empty body", "/v1/messages/1", `{}`, http.StatusBadRequest},
+		{"content too long", "/v1/messages/1", `{"content":"toolong"}`, http.StatusBadRequest},
+		{"unknown field", "/v1/messages/1", `{"status":"sent"}`, http.StatusBadRequest},
+		{"not pending", "/v1/messages/2", `{"content":"new"}`, http.StatusConflict},
+		{"missing", "/v1/messages/3", `{"content":"new"}`, http.StatusNotFound},
+	}
+
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPatch, tc.path, strings.NewReader(tc.body))
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+
+		if rr.Code != tc.want {
+			t.Fatalf("%s: expected %d, got %d body=%q", tc.name, tc.want, rr.Code, rr.Body.String())
+		}
+	}
+
+	got := fr.byID[1]
+	if got.Content != "new" || got.RecipientPhone != "+369" {
+		t.Fatalf("expected edits to be applied, got %+v", got)
+	}
+}
+
+func TestRequeueMessages_DryRunByDefault(t *testing.T) {
+	fr := &fakeRepo{requeueIDs: []int64{4, 5, 6}}
+	s, mux := newTestServer(t, fr)
+	defer s.Stop()
+
+	body := `{"failedFrom":"2026-02-01T00:00:00Z","failedTo":"2026-02-02T00:00:00Z","errorContains":"timeout"}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages/requeue", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%q", rr.Code, rr.Body.String())
+	}
+	if !fr.requeueDryRun {
+		t.Fatalf("expected dry run when dryRun is omitted")
+	}
+	if fr.requeueFilter.ErrorContains != "timeout" || fr.requeueFilter.FailedFrom == nil || fr.requeueFilter.FailedTo == nil {
+		t.Fatalf("unexpected filter: %+v", fr.requeueFilter)
+	}
+	if fr.requeueNote != defaultRequeueNote {
+		t.Fatalf("expected default note, got %q", fr.requeueNote)
+	}
+
+	got := decodeJSON(t, rr)
+	if got["count"] != float64(3) || got["dryRun"] != true {
+		t.Fatalf("expected count=3 dryRun=true, got %v", got)
+	}
+}
+
+func TestRequeueMessages_ExecuteInvalidatesCache(t *testing.T) {
+	fr := &fakeRepo{requeueIDs: []int64{8}}
+	fc := &fakeCache{records: map[int64]model.Message{8: {ID: 8, Status: model.Failed}}}
+
+	s, h := newTestHandler(t, fr)
+	defer s.Stop()
+	mux := Router(h.WithCache(fc))
+
+	body := `{"ids":[8],"dryRun":false,"note":"provider outage 2026-02-01"}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages/requeue", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%q", rr.Code, rr.Body.String())
+	}
+	if fr.requeueDryRun {
+		t.Fatalf("expected dryRun=false to execute the requeue")
+	}
+	if fr.requeueNote != "provider outage 2026-02-01" {
+		t.Fatalf("unexpected note: %q", fr.requeueNote)
+	}
+	if _, ok := fc.records[8]; ok {
+		t.Fatalf("expected cached record for requeued message to be invalidated")
+	}
+}
+
+func TestRequeueMessages_Validation(t *testing.T) {
+	s, mux := newTestServer(t, &fakeRepo{})
+	defer s.Stop()
+
+	cases := []struct {
+		name string
+		body string
+	}{
+		{"no filters", `{"dryRun":false}`},
+		{"inverted range", `{"failedFrom":"2026-02-02T00:00:00Z","failedTo":"2026-02-01T00:00:00Z"}`},
+		{"bad timestamp", `{"failedFrom":"yesterday"}`},
+	}
+
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/v1/messages/requeue", strings.NewReader(tc.body))
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d body=%q", tc.name, rr.Code, rr.Body.String())
+		}
+	}
+}
+
+func TestListAttempts(t *testing.T) {
+	status := http.StatusBadGateway
+	class := "http_5xx"
+	fr := &fakeRepo{
+		byID: map[int64]model.Message{
+			1: {ID: 1, Status: model.Failed},
+			2: {ID: 2, Status: model.Pending},
+		},
+	}
+	fa := &fakeAttempts{items: map[int64][]model.Attempt{
+		1: {{ID: 10, MessageID: 1, HTTPStatus: &status, ErrorClass: &class, InstanceID: "api-1"}},
+	}}
+
+	s, h := newTestHandler(t, fr)
+	defer s.Stop()
+	mux := Router(h.WithAttempts(fa))
+
+	cases := []struct {
+		path      string
+		wantCode  int
+		wantItems int
+	}{
+		{"/v1/messages/1/attempts", http.StatusOK, 1},
+		{"/v1/messages/2/attempts", http.StatusOK, 0},
+		{"/v1/messages/3/attempts", http.StatusNotFound, 0},
+	}
+
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+
+		if rr.Code != tc.wantCode {
+			t.Fatalf("%s: expected %d, got %d body=%q", tc.path, tc.wantCode, rr.Code, rr.Body.String())
+		}
+		if rr.Code != http.StatusOK {
+			continue
+		}
+
+		items, ok := decodeJSON(t, rr)["items"].([]any)
+		if !ok || len(items) != tc.wantItems {
+			t.Fatalf("%s: expected %d items, got %v", tc.path, tc.wantItems, items)
+		}
+	}
+}
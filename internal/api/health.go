@@ -0,0 +1,102 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ReadinessCheck probes one dependency; a nil error means it is up.
+type ReadinessCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// WithReadiness sets the checks GET /v1/health/ready runs. Each check gets
+// timeout to answer.
+func (h *Handler) WithReadiness(timeout time.Duration, checks ...ReadinessCheck) *Handler {
+	h.readyTimeout = timeout
+	h.readyChecks = checks
+	return h
+}
+
+// HeartbeatSource reports scheduler liveness.
+type HeartbeatSource interface {
+	IsRunning() bool
+	Heartbeat() time.Time
+}
+
+// HeartbeatCheck fails while the scheduler runs but has not finished a tick
+// for longer than staleAfter. A scheduler stopped through the API is up.
+func HeartbeatCheck(s HeartbeatSource, staleAfter time.Duration) ReadinessCheck {
+	return ReadinessCheck{
+		Name: "scheduler",
+		Check: func(ctx context.Context) error {
+			if !s.IsRunning() {
+				return nil
+			}
+			if age := time.Since(s.Heartbeat()); age > staleAfter {
+				return fmt.Errorf("no tick finished for %s", age.Round(time.Second))
+			}
+			return nil
+		},
+	}
+}
+
+type componentStatus struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latencyMs"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Live reports that the process serves HTTP. It checks no dependencies, so a
+// database outage does not get the process restarted.
+func (h *Handler) Live(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"status": "up"})
+}
+
+// Ready runs every readiness check concurrently and answers 503 when any of
+// them fails.
+func (h *Handler) Ready(w http.ResponseWriter, r *http.Request) {
+	components := make(map[string]componentStatus, len(h.readyChecks))
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, c := range h.readyChecks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(r.Context(), h.readyTimeout)
+			defer cancel()
+
+			start := time.Now()
+			err := c.Check(ctx)
+			st := componentStatus{
+				Status:    "up",
+				LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				st.Status = "down"
+				st.Error = err.Error()
+			}
+
+			mu.Lock()
+			components[c.Name] = st
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	status, code := "up", http.StatusOK
+	for _, c := range components {
+		if c.Status != "up" {
+			status, code = "down", http.StatusServiceUnavailable
+			break
+		}
+	}
+	writeJSON(w, code, map[string]any{"status": status, "components": components})
+}
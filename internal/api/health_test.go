@@ -0,0 +1,112 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLive(t *testing.T) {
+	s, mux := newTestServer(t, &fakeRepo{})
+	defer s.Stop()
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/health/live", nil))
+	if rr.Code != http.StatusOK || decodeJSON(t, rr)["status"] != "up" {
+		t.Fatalf("expected 200 up, got %d body=%q", rr.Code, rr.Body.String())
+	}
+}
+
+func TestReady(t *testing.T) {
+	up := func(ctx context.Context) error { return nil }
+	down := func(ctx context.Context) error { return errors.New("connection refused") }
+	slow := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	cases := []struct {
+		name     string
+		checks   []ReadinessCheck
+		wantCode int
+		wantDown string
+	}{
+		{
+			name:     "all up",
+			checks:   []ReadinessCheck{{"database", up}, {"redis", up}},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "one down",
+			checks:   []ReadinessCheck{{"database", up}, {"redis", down}},
+			wantCode: http.StatusServiceUnavailable,
+			wantDown: "redis",
+		},
+		{
+			name:     "check times out",
+			checks:   []ReadinessCheck{{"database", slow}},
+			wantCode: http.StatusServiceUnavailable,
+			wantDown: "database",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, h := newTestHandler(t, &fakeRepo{})
+			defer s.Stop()
+			mux := Router(h.WithReadiness(20*time.Millisecond, tc.checks...))
+
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/health/ready", nil))
+			if rr.Code != tc.wantCode {
+				t.Fatalf("expected %d, got %d body=%q", tc.wantCode, rr.Code, rr.Body.String())
+			}
+
+			components := decodeJSON(t, rr)["components"].(map[string]any)
+			if len(components) != len(tc.checks) {
+				t.Fatalf("expected %d components, got %v", len(tc.checks), components)
+			}
+			for name, v := range components {
+				c := v.(map[string]any)
+				want := "up"
+				if name == tc.wantDown {
+					want = "down"
+				}
+				if c["status"] != want {
+					t.Fatalf("expected %s %s, got %v", name, want, c)
+				}
+				if _, ok := c["latencyMs"].(float64); !ok {
+					t.Fatalf("expected latencyMs for %s, got %v", name, c)
+				}
+				if want == "down" && c["error"] == "" {
+					t.Fatalf("expected an error for %s, got %v", name, c)
+				}
+			}
+		})
+	}
+}
+
+type fakeHeartbeat struct {
+	running bool
+	beat    time.Time
+}
+
+func (f fakeHeartbeat) IsRunning() bool      { return f.running }
+func (f fakeHeartbeat) Heartbeat() time.Time { return f.beat }
+
+func TestHeartbeatCheck(t *testing.T) {
+	stale := time.Now().Add(-time.Hour)
+
+	if err := HeartbeatCheck(fakeHeartbeat{running: true, beat: time.Now()}, time.Minute).Check(context.Background()); err != nil {
+		t.Fatalf("expected a fresh heartbeat to pass, got %v", err)
+	}
+	if err := HeartbeatCheck(fakeHeartbeat{running: true, beat: stale}, time.Minute).Check(context.Background()); err == nil {
+		t.Fatalf("expected a stale heartbeat to fail")
+	}
+	if err := HeartbeatCheck(fakeHeartbeat{beat: stale}, time.Minute).Check(context.Background()); err != nil {
+		t.Fatalf("expected a stopped scheduler to pass, got %v", err)
+	}
+}
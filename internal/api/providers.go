@@ -0,0 +1,26 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/routing"
+)
+
+// ProviderHealthSource reports per-provider routing health.
+type ProviderHealthSource interface {
+	Health() []routing.ProviderHealth
+}
+
+// WithProviders enables the provider health endpoint.
+func (h *Handler) WithProviders(p ProviderHealthSource) *Handler {
+	h.providers = p
+	return h
+}
+
+func (h *Handler) ListProviders(w http.ResponseWriter, r *http.Request) {
+	items := []routing.ProviderHealth{}
+	if h.providers != nil {
+		items = h.providers.Health()
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": items})
+}
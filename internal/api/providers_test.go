@@ -0,0 +1,53 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/routing"
+)
+
+type fakeProviders []routing.ProviderHealth
+
+func (f fakeProviders) Health() []routing.ProviderHealth { return f }
+
+func TestListProviders(t *testing.T) {
+	s, h := newTestHandler(t, &fakeRepo{})
+	defer s.Stop()
+
+	h.WithProviders(fakeProviders{
+		{Name: "primary", Weight: 1, Healthy: false, ConsecutiveFailures: 3},
+		{Name: "backup", Priority: 1, Weight: 1, Healthy: true, Sent: 7},
+	})
+
+	rr := httptest.NewRecorder()
+	Router(h).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/providers", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+
+	var body struct {
+		Items []routing.ProviderHealth `json:"items"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(body.Items) != 2 || body.Items[0].Name != "primary" || body.Items[0].Healthy || body.Items[1].Sent != 7 {
+		t.Fatalf("unexpected providers: %+v", body.Items)
+	}
+}
+
+func TestListProviders_NotConfigured(t *testing.T) {
+	s, mux := newTestServer(t, &fakeRepo{})
+	defer s.Stop()
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/providers", nil))
+
+	if rr.Code != http.StatusOK || rr.Body.String() != "{\"items\":[]}\n" {
+		t.Fatalf("expected empty list, got %d %q", rr.Code, rr.Body.String())
+	}
+}
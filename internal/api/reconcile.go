@@ -0,0 +1,61 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+	"github.com/LeventeLantos/automatic-messaging/internal/reconcile"
+)
+
+// Reconciler runs reconciliation passes and keeps the latest report.
+type Reconciler interface {
+	Run(ctx context.Context) (reconcile.Report, error)
+	LastReport() (reconcile.Report, bool)
+}
+
+// WithReconciler enables the reconciliation report endpoints. A pass covers
+// every tenant and the report lists their messages, so both endpoints are
+// operator-only (see Router).
+func (h *Handler) WithReconciler(r Reconciler) *Handler {
+	h.reconciler = r
+	return h
+}
+
+// GetReconciliation returns the report of the latest reconciliation pass.
+func (h *Handler) GetReconciliation(w http.ResponseWriter, r *http.Request) {
+	if !h.reconcilerEnabled(w) {
+		return
+	}
+
+	rep, ok := h.reconciler.LastReport()
+	if !ok {
+		http.Error(w, "no reconciliation has run yet", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, rep)
+}
+
+// RunReconciliation runs a pass now and returns its report. It waits for a
+// pass already in progress.
+func (h *Handler) RunReconciliation(w http.ResponseWriter, r *http.Request) {
+	if !h.reconcilerEnabled(w) {
+		return
+	}
+
+	rep, err := h.reconciler.Run(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.audit(r, model.AuditReconciliationRun, "reconciliation", nil)
+	writeJSON(w, http.StatusOK, rep)
+}
+
+func (h *Handler) reconcilerEnabled(w http.ResponseWriter) bool {
+	if h.reconciler == nil {
+		http.Error(w, "reconciliation is not enabled", http.StatusNotFound)
+		return false
+	}
+	return true
+}
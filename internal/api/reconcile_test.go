@@ -0,0 +1,76 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/reconcile"
+)
+
+type fakeReconciler struct {
+	last *reconcile.Report
+	runs int
+}
+
+func (f *fakeReconciler) Run(ctx context.Context) (reconcile.Report, error) {
+	f.runs++
+	rep := reconcile.Report{Checked: 2, MarkedSent: 1, Released: []int64{7}, Items: []reconcile.Item{}}
+	f.last = &rep
+	return rep, nil
+}
+
+func (f *fakeReconciler) LastReport() (reconcile.Report, bool) {
+	if f.last == nil {
+		return reconcile.Report{}, false
+	}
+	return *f.last, true
+}
+
+func TestReconciliationEndpoints(t *testing.T) {
+	s, h := newTestHandler(t, &fakeRepo{})
+	defer s.Stop()
+	fr := &fakeReconciler{}
+	mux := Router(h.WithReconciler(fr))
+
+	cases := []struct {
+		method, path string
+		want         int
+	}{
+		{http.MethodGet, "/v1/reconciliation", http.StatusNotFound},
+		{http.MethodPost, "/v1/reconciliation/run", http.StatusOK},
+		{http.MethodGet, "/v1/reconciliation", http.StatusOK},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+
+		if rr.Code != tc.want {
+			t.Fatalf("%s %s: expected %d, got %d body=%q", tc.method, tc.path, tc.want, rr.Code, rr.Body.String())
+		}
+		if rr.Code == http.StatusOK {
+			body := decodeJSON(t, rr)
+			if body["checked"] != float64(2) || body["markedSent"] != float64(1) {
+				t.Fatalf("unexpected report: %v", body)
+			}
+		}
+	}
+	if fr.runs != 1 {
+		t.Fatalf("expected exactly one run, got %d", fr.runs)
+	}
+}
+
+func TestReconciliation_NotEnabled(t *testing.T) {
+	s, mux := newTestServer(t, &fakeRepo{})
+	defer s.Stop()
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/reconciliation", nil)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,22 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+	"github.com/LeventeLantos/automatic-messaging/internal/redact"
+)
+
+// WithRedaction masks messages in responses to clients without the
+// pii:read scope.
+func (h *Handler) WithRedaction(p redact.Policy) *Handler {
+	h.redaction = &p
+	return h
+}
+
+func (h *Handler) present(r *http.Request, m model.Message) model.Message {
+	if h.redaction == nil || HasScope(r.Context(), ScopePIIRead) {
+		return m
+	}
+	return h.redaction.Message(m)
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+	"github.com/LeventeLantos/automatic-messaging/internal/redact"
+)
+
+func TestRedaction(t *testing.T) {
+	msg := model.Message{ID: 7, RecipientPhone: "+36301234111", Content: "hello", Status: model.Sent}
+	policy := redact.Policy{Phones: true, Content: redact.ContentHash}
+
+	cases := []struct {
+		name        string
+		policy      *redact.Policy
+		scopes      []string
+		wantPhone   string
+		wantContent string
+	}{
+		{"redaction disabled", nil, nil, "+36301234111", "hello"},
+		{"masked without scope", &policy, nil, "+36******111", redact.Hash("hello")},
+		{"other scopes do not help", &policy, []string{"messages:read"}, "+36******111", redact.Hash("hello")},
+		{"full visibility with scope", &policy, []string{ScopePIIRead}, "+36301234111", "hello"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fr := &fakeRepo{items: []model.Message{msg}, byID: map[int64]model.Message{7: msg}}
+			s, h := newTestHandler(t, fr)
+			defer s.Stop()
+			if tc.policy != nil {
+				h.WithRedaction(*tc.policy)
+			}
+			mux := Router(h)
+
+			for _, path := range []string{"/v1/messages/sent", "/v1/messages/7"} {
+				req := httptest.NewRequest(http.MethodGet, path, nil)
+				req = req.WithContext(WithScopes(context.Background(), tc.scopes...))
+				rr := httptest.NewRecorder()
+				mux.ServeHTTP(rr, req)
+				if rr.Code != http.StatusOK {
+					t.Fatalf("%s: expected 200, got %d body=%q", path, rr.Code, rr.Body.String())
+				}
+
+				body := decodeJSON(t, rr)
+				if items, ok := body["items"].([]any); ok {
+					body = items[0].(map[string]any)
+				}
+				if body["RecipientPhone"] != tc.wantPhone || body["Content"] != tc.wantContent {
+					t.Fatalf("%s: unexpected message %v", path, body)
+				}
+			}
+
+			if fr.items[0].RecipientPhone != msg.RecipientPhone {
+				t.Fatalf("expected the repository data to be untouched")
+			}
+		})
+	}
+}
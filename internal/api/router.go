@@ -2,16 +2,59 @@ package api
 
 import "net/http"
 
+// Router registers every route with the scope it requires (see WithAuth).
+// The scheduler, the routing table, reconciliation, the cache and the
+// providers are shared by every tenant, so only operator keys may start or
+// stop the scheduler, change routes, run and read reconciliation, or read
+// cache and provider figures.
+// Health checks and the delivery receipt callback, which is authenticated by
+// its signature, are public.
 func Router(h *Handler) http.Handler {
 	mux := http.NewServeMux()
+	route := func(pattern, scope string, fn http.HandlerFunc) {
+		mux.HandleFunc(pattern, h.authorize(scope, fn))
+	}
+	operatorRoute := func(pattern, scope string, fn http.HandlerFunc) {
+		route(pattern, scope, h.operatorOnly(fn))
+	}
 
 	mux.HandleFunc("GET /v1/health", h.Health)
+	mux.HandleFunc("GET /v1/health/live", h.Live)
+	mux.HandleFunc("GET /v1/health/ready", h.Ready)
 
-	mux.HandleFunc("GET /v1/scheduler/status", h.SchedulerStatus)
-	mux.HandleFunc("POST /v1/scheduler/start", h.SchedulerStart)
-	mux.HandleFunc("POST /v1/scheduler/stop", h.SchedulerStop)
+	route("GET /v1/scheduler/status", ScopeSchedulerRead, h.SchedulerStatus)
+	operatorRoute("POST /v1/scheduler/start", ScopeSchedulerAdmin, h.SchedulerStart)
+	operatorRoute("POST /v1/scheduler/stop", ScopeSchedulerAdmin, h.SchedulerStop)
 
-	mux.HandleFunc("GET /v1/messages/sent", h.ListSentMessages)
+	route("GET /v1/messages/sent", ScopeMessagesRead, h.ListSentMessages)
+	route("POST /v1/messages/requeue", ScopeMessagesWrite, h.RequeueMessages)
+	route("GET /v1/messages/{id}", ScopeMessagesRead, h.GetMessage)
+	route("PATCH /v1/messages/{id}", ScopeMessagesWrite, h.UpdateMessage)
+	route("DELETE /v1/messages/{id}", ScopeMessagesWrite, h.CancelMessage)
+	route("POST /v1/messages/{id}/cancel", ScopeMessagesWrite, h.CancelMessage)
+	route("GET /v1/messages/{id}/attempts", ScopeMessagesRead, h.ListAttempts)
+
+	route("GET /v1/stats", ScopeStatsRead, h.GetStats)
+	operatorRoute("GET /v1/cache/stats", ScopeStatsRead, h.CacheStats)
+	operatorRoute("GET /v1/providers", ScopeStatsRead, h.ListProviders)
+	operatorRoute("GET /v1/reconciliation", ScopeStatsRead, h.GetReconciliation)
+	operatorRoute("POST /v1/reconciliation/run", ScopeReconciliationAdmin, h.RunReconciliation)
+
+	route("GET /v1/audit", ScopeAuditRead, h.ListAudit)
+
+	route("GET /v1/tenant", ScopeTenantRead, h.GetTenant)
+	route("PUT /v1/tenant", ScopeTenantWrite, h.PutTenant)
+
+	route("GET /v1/routes", ScopeRoutesRead, h.ListRoutes)
+	operatorRoute("PUT /v1/routes/{prefix}", ScopeRoutesWrite, h.PutRoute)
+	operatorRoute("DELETE /v1/routes/{prefix}", ScopeRoutesWrite, h.DeleteRoute)
+
+	mux.HandleFunc("POST /v1/callbacks/delivery", h.DeliveryReceipt)
+
+	route("POST /v1/subscriptions", ScopeSubscriptionsWrite, h.CreateSubscription)
+	route("GET /v1/subscriptions", ScopeSubscriptionsRead, h.ListSubscriptions)
+	route("DELETE /v1/subscriptions/{id}", ScopeSubscriptionsWrite, h.DeleteSubscription)
+	route("GET /v1/subscriptions/{id}/deliveries", ScopeSubscriptionsRead, h.ListSubscriptionDeliveries)
 
 	mux.HandleFunc("GET /", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -0,0 +1,181 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+	"github.com/LeventeLantos/automatic-messaging/internal/repo"
+	"github.com/LeventeLantos/automatic-messaging/internal/routing"
+)
+
+// maxSenderID caps sender IDs; providers allow 11 alphanumeric characters or
+// a phone number.
+const maxSenderID = 16
+
+var routePrefixRE = regexp.MustCompile(`^\+[0-9]{0,14}$`)
+
+// WithRoutes enables the routing table endpoints. changed is called after
+// every successful edit so the sender picks the new table up immediately.
+func (h *Handler) WithRoutes(r repo.RouteRepository, changed func()) *Handler {
+	h.routes = r
+	h.routesChanged = changed
+	return h
+}
+
+type putRouteRequest struct {
+	Provider string  `json:"provider"`
+	SenderID *string `json:"senderId"`
+}
+
+type routeResponse struct {
+	Prefix    string    `json:"prefix"`
+	Provider  string    `json:"provider"`
+	SenderID  *string   `json:"senderId,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func toRouteResponse(rt model.Route) routeResponse {
+	return routeResponse{
+		Prefix:    rt.Prefix,
+		Provider:  rt.Provider,
+		SenderID:  rt.SenderID,
+		CreatedAt: rt.CreatedAt,
+		UpdatedAt: rt.UpdatedAt,
+	}
+}
+
+func (h *Handler) ListRoutes(w http.ResponseWriter, r *http.Request) {
+	if !h.routesEnabled(w) {
+		return
+	}
+
+	routes, err := h.routes.ListRoutes(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	items := make([]routeResponse, 0, len(routes))
+	for _, rt := range routes {
+		items = append(items, toRouteResponse(rt))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": items})
+}
+
+func (h *Handler) PutRoute(w http.ResponseWriter, r *http.Request) {
+	if !h.routesEnabled(w) {
+		return
+	}
+	prefix, ok := parseRoutePrefix(w, r)
+	if !ok {
+		return
+	}
+
+	var req putRouteRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if err := h.validateRoute(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rt, err := h.routes.PutRoute(r.Context(), model.Route{
+		Prefix:   prefix,
+		Provider: req.Provider,
+		SenderID: req.SenderID,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.notifyRoutesChanged()
+	h.audit(r, model.AuditRoutePut, "route:"+prefix, map[string]any{"provider": req.Provider, "senderId": req.SenderID})
+
+	writeJSON(w, http.StatusOK, toRouteResponse(*rt))
+}
+
+func (h *Handler) DeleteRoute(w http.ResponseWriter, r *http.Request) {
+	if !h.routesEnabled(w) {
+		return
+	}
+	prefix, ok := parseRoutePrefix(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.routes.DeleteRoute(r.Context(), prefix)
+	if errors.Is(err, repo.ErrNotFound) {
+		http.Error(w, "route not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.notifyRoutesChanged()
+	h.audit(r, model.AuditRouteDelete, "route:"+prefix, nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) validateRoute(req putRouteRequest) error {
+	if req.Provider == "" {
+		return errors.New("provider is required")
+	}
+	if err := h.validateProvider(req.Provider); err != nil {
+		return err
+	}
+	return validateSenderID(req.SenderID)
+}
+
+// validateProvider rejects providers that are not configured, when the
+// handler knows the provider list.
+func (h *Handler) validateProvider(name string) error {
+	if h.providers == nil {
+		return nil
+	}
+	known := slices.ContainsFunc(h.providers.Health(), func(p routing.ProviderHealth) bool {
+		return p.Name == name
+	})
+	if !known {
+		return errors.New("unknown provider: " + name)
+	}
+	return nil
+}
+
+func validateSenderID(senderID *string) error {
+	if senderID != nil && (*senderID == "" || len(*senderID) > maxSenderID) {
+		return fmt.Errorf("senderId must be 1-%d characters", maxSenderID)
+	}
+	return nil
+}
+
+func (h *Handler) notifyRoutesChanged() {
+	if h.routesChanged != nil {
+		h.routesChanged()
+	}
+}
+
+func (h *Handler) routesEnabled(w http.ResponseWriter) bool {
+	if h.routes == nil {
+		http.Error(w, "routes are not enabled", http.StatusNotFound)
+		return false
+	}
+	return true
+}
+
+func parseRoutePrefix(w http.ResponseWriter, r *http.Request) (string, bool) {
+	prefix := r.PathValue("prefix")
+	if !routePrefixRE.MatchString(prefix) {
+		http.Error(w, `invalid prefix: expected "+" followed by up to 14 digits`, http.StatusBadRequest)
+		return "", false
+	}
+	return prefix, true
+}
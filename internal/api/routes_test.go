@@ -0,0 +1,179 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/apikey"
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+	"github.com/LeventeLantos/automatic-messaging/internal/repo"
+)
+
+type fakeRoutes struct {
+	routes map[string]model.Route
+}
+
+func (f *fakeRoutes) ListRoutes(ctx context.Context) ([]model.Route, error) {
+	var out []model.Route
+	for _, rt := range f.routes {
+		out = append(out, rt)
+	}
+	return out, nil
+}
+
+func (f *fakeRoutes) PutRoute(ctx context.Context, rt model.Route) (*model.Route, error) {
+	if f.routes == nil {
+		f.routes = map[string]model.Route{}
+	}
+	rt.CreatedAt = time.Now()
+	rt.UpdatedAt = rt.CreatedAt
+	f.routes[rt.Prefix] = rt
+	return &rt, nil
+}
+
+func (f *fakeRoutes) DeleteRoute(ctx context.Context, prefix string) error {
+	if _, ok := f.routes[prefix]; !ok {
+		return repo.ErrNotFound
+	}
+	delete(f.routes, prefix)
+	return nil
+}
+
+func newRoutesServer(t *testing.T, fr *fakeRoutes, changed *int) (http.Handler, func()) {
+	t.Helper()
+
+	s, h := newTestHandler(t, &fakeRepo{})
+	h.WithProviders(fakeProviders{{Name: "vendor-a"}, {Name: "vendor-b"}}).
+		WithRoutes(fr, func() { *changed++ })
+	return Router(h), func() { s.Stop() }
+}
+
+func TestPutRoute_CreatesAndNotifies(t *testing.T) {
+	fr := &fakeRoutes{}
+	var changed int
+	mux, stop := newRoutesServer(t, fr, &changed)
+	defer stop()
+
+	req := httptest.NewRequest(http.MethodPut, "/v1/routes/+36", strings.NewReader(`{"provider":"vendor-a","senderId":"ACME"}`))
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%q", rr.Code, rr.Body.String())
+	}
+	body := decodeJSON(t, rr)
+	if body["prefix"] != "+36" || body["provider"] != "vendor-a" || body["senderId"] != "ACME" {
+		t.Fatalf("unexpected route: %v", body)
+	}
+	if rt := fr.routes["+36"]; rt.Provider != "vendor-a" || rt.SenderID == nil || *rt.SenderID != "ACME" {
+		t.Fatalf("route not stored: %+v", rt)
+	}
+	if changed != 1 {
+		t.Fatalf("expected change hook once, got %d", changed)
+	}
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/routes", nil))
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"prefix":"+36"`) {
+		t.Fatalf("expected route in list, got %d %q", rr.Code, rr.Body.String())
+	}
+}
+
+func TestPutRoute_Validation(t *testing.T) {
+	var changed int
+	mux, stop := newRoutesServer(t, &fakeRoutes{}, &changed)
+	defer stop()
+
+	cases := []struct {
+		name, path, body string
+	}{
+		{"prefix without plus", "/v1/routes/36", `{"provider":"vendor-a"}`},
+		{"prefix with letters", "/v1/routes/+3a", `{"provider":"vendor-a"}`},
+		{"missing provider", "/v1/routes/+36", `{}`},
+		{"unknown provider", "/v1/routes/+36", `{"provider":"vendor-z"}`},
+		{"empty sender id", "/v1/routes/+36", `{"provider":"vendor-a","senderId":""}`},
+		{"sender id too long", "/v1/routes/+36", `{"provider":"vendor-a","senderId":"ABCDEFGHIJKLMNOPQ"}`},
+	}
+	for _, tc := range cases {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPut, tc.path, strings.NewReader(tc.body)))
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d body=%q", tc.name, rr.Code, rr.Body.String())
+		}
+	}
+	if changed != 0 {
+		t.Fatalf("expected no change notifications, got %d", changed)
+	}
+}
+
+func TestDeleteRoute(t *testing.T) {
+	fr := &fakeRoutes{routes: map[string]model.Route{"+1": {Prefix: "+1", Provider: "vendor-b"}}}
+	var changed int
+	mux, stop := newRoutesServer(t, fr, &changed)
+	defer stop()
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/v1/routes/%2B1", nil))
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d body=%q", rr.Code, rr.Body.String())
+	}
+	if _, ok := fr.routes["+1"]; ok || changed != 1 {
+		t.Fatalf("expected route deleted and hook called, routes=%v changed=%d", fr.routes, changed)
+	}
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/v1/routes/+1", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rr.Code)
+	}
+}
+
+func TestRoutes_WritesNeedOperatorKey(t *testing.T) {
+	fr := &fakeRoutes{}
+	var changed int
+	s, h := newTestHandler(t, &fakeRepo{})
+	defer s.Stop()
+	scopes := []string{ScopeRoutesRead, ScopeRoutesWrite}
+	keys := tenantKeys(scopes, "payments")
+	keys.byHash[apikey.Hash("operator-key")] = model.APIKey{Prefix: "oper", Scopes: scopes}
+	mux := Router(h.WithAuth(keys, time.Minute).
+		WithProviders(fakeProviders{{Name: "vendor-a"}}).
+		WithRoutes(fr, func() { changed++ }))
+
+	do := func(key, method string) int {
+		t.Helper()
+		req := httptest.NewRequest(method, "/v1/routes/%2B36", strings.NewReader(`{"provider":"vendor-a"}`))
+		req.Header.Set("X-API-Key", key)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if code := do("payments", http.MethodPut); code != http.StatusForbidden {
+		t.Fatalf("expected a tenant key to be refused, got %d", code)
+	}
+	if code := do("operator-key", http.MethodPut); code != http.StatusOK {
+		t.Fatalf("expected the operator key to put the route, got %d", code)
+	}
+	if code := do("payments", http.MethodDelete); code != http.StatusForbidden {
+		t.Fatalf("expected a tenant key to be refused, got %d", code)
+	}
+	if code := do("operator-key", http.MethodDelete); code != http.StatusNoContent || changed != 2 {
+		t.Fatalf("expected the operator key to delete the route, got %d changed=%d", code, changed)
+	}
+}
+
+func TestRoutes_NotEnabled(t *testing.T) {
+	s, mux := newTestServer(t, &fakeRepo{})
+	defer s.Stop()
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/routes", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rr.Code)
+	}
+}
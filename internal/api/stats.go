@@ -0,0 +1,129 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+	"github.com/LeventeLantos/automatic-messaging/internal/repo"
+)
+
+// SLOThresholds are the limits GET /v1/stats compares its figures with. A
+// zero threshold is not checked.
+type SLOThresholds struct {
+	OldestPendingAge time.Duration
+	FailureRatio     float64
+	SendLatencyP50   time.Duration
+	SendLatencyP95   time.Duration
+}
+
+// WithStats enables the queue health endpoint.
+func (h *Handler) WithStats(s repo.StatsRepository, slo SLOThresholds) *Handler {
+	h.stats = s
+	h.slo = slo
+	return h
+}
+
+type statsResponse struct {
+	GeneratedAt             time.Time              `json:"generatedAt"`
+	Counts                  map[model.Status]int64 `json:"counts"`
+	OldestPendingAgeSeconds float64                `json:"oldestPendingAgeSeconds"`
+	Throughput              throughput             `json:"throughput"`
+	FailureRatio            *float64               `json:"failureRatio"`
+	SendLatencySeconds      sendLatency            `json:"sendLatencySeconds"`
+	SLO                     map[string]sloCheck    `json:"slo"`
+}
+
+type throughput struct {
+	Last1m  int64 `json:"last1m"`
+	Last5m  int64 `json:"last5m"`
+	Last60m int64 `json:"last60m"`
+}
+
+type sendLatency struct {
+	P50 *float64 `json:"p50"`
+	P95 *float64 `json:"p95"`
+}
+
+// sloCheck compares one figure with its threshold. Value is null when there
+// is nothing to measure, which never counts as a breach.
+type sloCheck struct {
+	Threshold float64  `json:"threshold"`
+	Value     *float64 `json:"value"`
+	Breached  bool     `json:"breached"`
+}
+
+// GetStats reports queue depth, throughput, failure ratio and send latency
+// over the last hour, each checked against the configured SLO thresholds.
+func (h *Handler) GetStats(w http.ResponseWriter, r *http.Request) {
+	if h.stats == nil {
+		http.Error(w, "stats are not enabled", http.StatusNotFound)
+		return
+	}
+
+	st, err := h.stats.MessageStats(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, buildStats(st, h.slo))
+}
+
+func buildStats(st repo.MessageStats, slo SLOThresholds) statsResponse {
+	out := statsResponse{
+		GeneratedAt: st.At.UTC(),
+		Counts:      st.Counts,
+		Throughput: throughput{
+			Last1m:  st.SentLast1m,
+			Last5m:  st.SentLast5m,
+			Last60m: st.SentLast60m,
+		},
+		SendLatencySeconds: sendLatency{
+			P50: durationSeconds(st.SendLatencyP50),
+			P95: durationSeconds(st.SendLatencyP95),
+		},
+		SLO: map[string]sloCheck{},
+	}
+	if out.Counts == nil {
+		out.Counts = map[model.Status]int64{}
+	}
+	if st.OldestPending != nil {
+		out.OldestPendingAgeSeconds = max(st.At.Sub(*st.OldestPending).Seconds(), 0)
+	}
+	if st.AttemptsLast60m > 0 {
+		ratio := float64(st.FailedAttemptsLast60m) / float64(st.AttemptsLast60m)
+		out.FailureRatio = &ratio
+	}
+
+	oldest := out.OldestPendingAgeSeconds
+	checks := []struct {
+		name      string
+		threshold float64
+		value     *float64
+	}{
+		{"oldestPendingAgeSeconds", slo.OldestPendingAge.Seconds(), &oldest},
+		{"failureRatio", slo.FailureRatio, out.FailureRatio},
+		{"sendLatencyP50Seconds", slo.SendLatencyP50.Seconds(), out.SendLatencySeconds.P50},
+		{"sendLatencyP95Seconds", slo.SendLatencyP95.Seconds(), out.SendLatencySeconds.P95},
+	}
+	for _, c := range checks {
+		if c.threshold <= 0 {
+			continue
+		}
+		out.SLO[c.name] = sloCheck{
+			Threshold: c.threshold,
+			Value:     c.value,
+			Breached:  c.value != nil && *c.value > c.threshold,
+		}
+	}
+	return out
+}
+
+func durationSeconds(d *time.Duration) *float64 {
+	if d == nil {
+		return nil
+	}
+	s := d.Seconds()
+	return &s
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+	"github.com/LeventeLantos/automatic-messaging/internal/repo"
+	"github.com/LeventeLantos/automatic-messaging/internal/tenant"
+)
+
+type fakeStats struct {
+	st repo.MessageStats
+}
+
+func (f fakeStats) MessageStats(ctx context.Context) (repo.MessageStats, error) {
+	return f.st, nil
+}
+
+type statsFunc func(ctx context.Context) (repo.MessageStats, error)
+
+func (f statsFunc) MessageStats(ctx context.Context) (repo.MessageStats, error) {
+	return f(ctx)
+}
+
+func TestGetStats_ScopedToTenant(t *testing.T) {
+	var got string
+	stats := statsFunc(func(ctx context.Context) (repo.MessageStats, error) {
+		got = tenant.From(ctx)
+		return repo.MessageStats{At: time.Now(), Counts: map[model.Status]int64{}}, nil
+	})
+
+	s, h := newTestHandler(t, &fakeRepo{})
+	defer s.Stop()
+	mux := Router(h.WithAuth(tenantKeys([]string{ScopeStatsRead}, "payments"), time.Minute).WithStats(stats, SLOThresholds{}))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/stats", nil)
+	req.Header.Set("X-API-Key", "payments")
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK || got != "payments" {
+		t.Fatalf("expected stats read for the payments tenant, got %d tenant=%q", rr.Code, got)
+	}
+}
+
+func TestGetStats(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	oldest := now.Add(-10 * time.Minute)
+	p50, p95 := 30*time.Second, 5*time.Minute
+
+	s, h := newTestHandler(t, &fakeRepo{})
+	defer s.Stop()
+	mux := Router(h.WithStats(fakeStats{st: repo.MessageStats{
+		At:                    now,
+		Counts:                map[model.Status]int64{model.Pending: 4, model.Sent: 90},
+		OldestPending:         &oldest,
+		SentLast1m:            2,
+		SentLast5m:            9,
+		SentLast60m:           90,
+		AttemptsLast60m:       100,
+		FailedAttemptsLast60m: 10,
+		SendLatencyP50:        &p50,
+		SendLatencyP95:        &p95,
+	}}, SLOThresholds{
+		OldestPendingAge: 5 * time.Minute,
+		FailureRatio:     0.2,
+		SendLatencyP95:   2 * time.Minute,
+	}))
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/stats", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%q", rr.Code, rr.Body.String())
+	}
+
+	body := decodeJSON(t, rr)
+	if body["oldestPendingAgeSeconds"] != float64(600) || body["failureRatio"] != 0.1 {
+		t.Fatalf("unexpected figures: %v", body)
+	}
+	if tp := body["throughput"].(map[string]any); tp["last1m"] != float64(2) || tp["last60m"] != float64(90) {
+		t.Fatalf("unexpected throughput: %v", tp)
+	}
+
+	slo := body["slo"].(map[string]any)
+	breached := map[string]bool{}
+	for name, v := range slo {
+		breached[name] = v.(map[string]any)["breached"].(bool)
+	}
+	want := map[string]bool{
+		"oldestPendingAgeSeconds": true,
+		"failureRatio":            false,
+		"sendLatencyP95Seconds":   true,
+	}
+	if len(breached) != len(want) {
+		t.Fatalf("expected only configured thresholds to be checked, got %v", slo)
+	}
+	for name, b := range want {
+		if breached[name] != b {
+			t.Fatalf("expected %s breached=%v, got %v", name, b, slo)
+		}
+	}
+}
+
+func TestGetStats_NothingToMeasure(t *testing.T) {
+	st := buildStats(repo.MessageStats{At: time.Now()}, SLOThresholds{FailureRatio: 0.1, SendLatencyP50: time.Minute})
+
+	if st.FailureRatio != nil || st.SendLatencySeconds.P50 != nil {
+		t.Fatalf("expected no ratio or latency without traffic, got %+v", st)
+	}
+	for name, c := range st.SLO {
+		if c.Breached || c.Value != nil {
+			t.Fatalf("expected %s not to be breached without data, got %+v", name, c)
+		}
+	}
+}
+
+func TestGetStats_NotEnabled(t *testing.T) {
+	s, h := newTestHandler(t, &fakeRepo{})
+	defer s.Stop()
+
+	rr := httptest.NewRecorder()
+	Router(h).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/stats", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rr.Code)
+	}
+}
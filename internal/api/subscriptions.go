@@ -0,0 +1,195 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+	"github.com/LeventeLantos/automatic-messaging/internal/repo"
+)
+
+// WithSubscriptions enables the event subscription endpoints.
+func (h *Handler) WithSubscriptions(s repo.SubscriptionRepository) *Handler {
+	h.subs = s
+	return h
+}
+
+type createSubscriptionRequest struct {
+	URL        string            `json:"url"`
+	Secret     string            `json:"secret"`
+	EventTypes []model.EventType `json:"eventTypes"`
+}
+
+// subscriptionResponse omits the secret, which is only returned on creation.
+type subscriptionResponse struct {
+	ID         int64             `json:"id"`
+	TenantID   string            `json:"tenantId,omitempty"`
+	URL        string            `json:"url"`
+	Secret     string            `json:"secret,omitempty"`
+	EventTypes []model.EventType `json:"eventTypes"`
+	Active     bool              `json:"active"`
+	CreatedAt  time.Time         `json:"createdAt"`
+}
+
+func toSubscriptionResponse(s model.Subscription) subscriptionResponse {
+	return subscriptionResponse{
+		ID:         s.ID,
+		TenantID:   s.TenantID,
+		URL:        s.URL,
+		EventTypes: s.EventTypes,
+		Active:     s.Active,
+		CreatedAt:  s.CreatedAt,
+	}
+}
+
+func (h *Handler) CreateSubscription(w http.ResponseWriter, r *http.Request) {
+	if !h.subscriptionsEnabled(w) {
+		return
+	}
+
+	var req createSubscriptionRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if err := req.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.Secret == "" {
+		secret, err := newSecret()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		req.Secret = secret
+	}
+
+	sub, err := h.subs.CreateSubscription(r.Context(), model.Subscription{
+		URL:        req.URL,
+		Secret:     req.Secret,
+		EventTypes: req.EventTypes,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.audit(r, model.AuditSubscriptionCreate, subscriptionTarget(sub.ID), map[string]any{"url": sub.URL, "eventTypes": sub.EventTypes})
+
+	resp := toSubscriptionResponse(*sub)
+	resp.Secret = sub.Secret
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+func (req createSubscriptionRequest) validate() error {
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("url must be an absolute http(s) URL")
+	}
+	if len(req.EventTypes) == 0 {
+		return errors.New("eventTypes must not be empty")
+	}
+	for _, t := range req.EventTypes {
+		if !t.Valid() {
+			return errors.New("unknown event type: " + string(t))
+		}
+	}
+	return nil
+}
+
+func (h *Handler) ListSubscriptions(w http.ResponseWriter, r *http.Request) {
+	if !h.subscriptionsEnabled(w) {
+		return
+	}
+
+	subs, err := h.subs.ListSubscriptions(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	items := make([]subscriptionResponse, 0, len(subs))
+	for _, s := range subs {
+		items = append(items, toSubscriptionResponse(s))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": items})
+}
+
+func (h *Handler) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	if !h.subscriptionsEnabled(w) {
+		return
+	}
+	id, ok := parseSubscriptionID(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.subs.DeactivateSubscription(r.Context(), id)
+	if errors.Is(err, repo.ErrNotFound) {
+		http.Error(w, "subscription not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.audit(r, model.AuditSubscriptionDelete, subscriptionTarget(id), nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) ListSubscriptionDeliveries(w http.ResponseWriter, r *http.Request) {
+	if !h.subscriptionsEnabled(w) {
+		return
+	}
+	id, ok := parseSubscriptionID(w, r)
+	if !ok {
+		return
+	}
+
+	items, err := h.subs.ListDeliveries(r.Context(), id, parseInt(r.URL.Query().Get("limit"), 50))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if items == nil {
+		items = []model.EventDelivery{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"items": items})
+}
+
+func (h *Handler) subscriptionsEnabled(w http.ResponseWriter) bool {
+	if h.subs == nil {
+		http.Error(w, "subscriptions are not enabled", http.StatusNotFound)
+		return false
+	}
+	return true
+}
+
+func parseSubscriptionID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		http.Error(w, "invalid subscription id", http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+func subscriptionTarget(id int64) string {
+	return "subscription:" + strconv.FormatInt(id, 10)
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
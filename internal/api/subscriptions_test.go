@@ -0,0 +1,201 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+	"github.com/LeventeLantos/automatic-messaging/internal/repo"
+	"github.com/LeventeLantos/automatic-messaging/internal/tenant"
+)
+
+type fakeSubs struct {
+	subs       map[int64]model.Subscription
+	deliveries map[int64][]model.EventDelivery
+	nextID     int64
+}
+
+func (f *fakeSubs) CreateSubscription(ctx context.Context, s model.Subscription) (*model.Subscription, error) {
+	f.nextID++
+	s.ID = f.nextID
+	s.TenantID = tenant.From(ctx)
+	s.Active = true
+	s.CreatedAt = time.Now()
+	if f.subs == nil {
+		f.subs = map[int64]model.Subscription{}
+	}
+	f.subs[s.ID] = s
+	return &s, nil
+}
+
+func (f *fakeSubs) ListSubscriptions(ctx context.Context) ([]model.Subscription, error) {
+	var out []model.Subscription
+	for _, s := range f.subs {
+		if s.Active && visible(ctx, s) {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeSubs) DeactivateSubscription(ctx context.Context, id int64) error {
+	s, ok := f.subs[id]
+	if !ok || !s.Active || !visible(ctx, s) {
+		return repo.ErrNotFound
+	}
+	s.Active = false
+	f.subs[id] = s
+	return nil
+}
+
+func (f *fakeSubs) ListDeliveries(ctx context.Context, subscriptionID int64, limit int) ([]model.EventDelivery, error) {
+	if !visible(ctx, f.subs[subscriptionID]) {
+		return nil, nil
+	}
+	return f.deliveries[subscriptionID], nil
+}
+
+func visible(ctx context.Context, s model.Subscription) bool {
+	t := tenant.From(ctx)
+	return t == "" || s.TenantID == t
+}
+
+func newSubscriptionsServer(t *testing.T, fs *fakeSubs) (http.Handler, func()) {
+	t.Helper()
+
+	s, h := newTestHandler(t, &fakeRepo{})
+	return Router(h.WithSubscriptions(fs)), func() { s.Stop() }
+}
+
+func TestCreateSubscription_GeneratesSecretAndHidesItOnList(t *testing.T) {
+	fs := &fakeSubs{}
+	mux, stop := newSubscriptionsServer(t, fs)
+	defer stop()
+
+	body := `{"url":"https://crm.example.com/hooks","eventTypes":["message.sent","message.delivered"]}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/subscriptions", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d body=%q", rr.Code, rr.Body.String())
+	}
+	created := decodeJSON(t, rr)
+	if secret, _ := created["secret"].(string); len(secret) != 64 {
+		t.Fatalf("expected a generated 32-byte hex secret, got %v", created["secret"])
+	}
+	if fs.subs[1].Secret != created["secret"] {
+		t.Fatalf("expected stored secret to match the returned one")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/subscriptions", nil)
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	if strings.Contains(rr.Body.String(), fs.subs[1].Secret) {
+		t.Fatalf("expected list response not to leak the secret: %s", rr.Body.String())
+	}
+	items, _ := decodeJSON(t, rr)["items"].([]any)
+	if len(items) != 1 {
+		t.Fatalf("expected 1 subscription, got %v", items)
+	}
+}
+
+func TestCreateSubscription_Validation(t *testing.T) {
+	mux, stop := newSubscriptionsServer(t, &fakeSubs{})
+	defer stop()
+
+	cases := []struct {
+		name string
+		body string
+	}{
+		{"relative url", `{"url":"/hooks","eventTypes":["message.sent"]}`},
+		{"ftp url", `{"url":"ftp://example.com","eventTypes":["message.sent"]}`},
+		{"no event types", `{"url":"https://example.com"}`},
+		{"unknown event type", `{"url":"https://example.com","eventTypes":["message.read"]}`},
+	}
+
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/v1/subscriptions", strings.NewReader(tc.body))
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d body=%q", tc.name, rr.Code, rr.Body.String())
+		}
+	}
+}
+
+func TestDeleteSubscriptionAndListDeliveries(t *testing.T) {
+	status := http.StatusOK
+	fs := &fakeSubs{
+		subs: map[int64]model.Subscription{
+			1: {ID: 1, URL: "https://example.com", Active: true},
+		},
+		deliveries: map[int64][]model.EventDelivery{
+			1: {{ID: 9, SubscriptionID: 1, State: model.DeliverySucceeded, LastStatus: &status}},
+		},
+	}
+	mux, stop := newSubscriptionsServer(t, fs)
+	defer stop()
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/subscriptions/1/deliveries", nil)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	items, _ := decodeJSON(t, rr)["items"].([]any)
+	if rr.Code != http.StatusOK || len(items) != 1 {
+		t.Fatalf("expected 1 delivery, got %d body=%q", rr.Code, rr.Body.String())
+	}
+
+	for i, want := range []int{http.StatusNoContent, http.StatusNotFound} {
+		req := httptest.NewRequest(http.MethodDelete, "/v1/subscriptions/1", nil)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+
+		if rr.Code != want {
+			t.Fatalf("delete %d: expected %d, got %d body=%q", i, want, rr.Code, rr.Body.String())
+		}
+	}
+}
+
+func TestSubscriptions_ScopedToTenant(t *testing.T) {
+	fs := &fakeSubs{
+		deliveries: map[int64][]model.EventDelivery{1: {{ID: 9, SubscriptionID: 1}}},
+	}
+	s, h := newTestHandler(t, &fakeRepo{})
+	defer s.Stop()
+	keys := tenantKeys([]string{ScopeSubscriptionsRead, ScopeSubscriptionsWrite}, "payments", tenant.Default)
+	mux := Router(h.WithAuth(keys, time.Minute).WithSubscriptions(fs))
+
+	do := func(key, method, path, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-API-Key", key)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := do("payments", http.MethodPost, "/v1/subscriptions", `{"url":"https://pay.example.com","eventTypes":["message.sent"]}`)
+	if rr.Code != http.StatusCreated || decodeJSON(t, rr)["tenantId"] != "payments" {
+		t.Fatalf("expected a payments subscription, got %d body=%q", rr.Code, rr.Body.String())
+	}
+
+	if items, _ := decodeJSON(t, do(tenant.Default, http.MethodGet, "/v1/subscriptions", ""))["items"].([]any); len(items) != 0 {
+		t.Fatalf("expected another tenant not to see the subscription, got %v", items)
+	}
+	if items, _ := decodeJSON(t, do(tenant.Default, http.MethodGet, "/v1/subscriptions/1/deliveries", ""))["items"].([]any); len(items) != 0 {
+		t.Fatalf("expected another tenant not to see the deliveries, got %v", items)
+	}
+	if rr := do(tenant.Default, http.MethodDelete, "/v1/subscriptions/1", ""); rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 deleting another tenant's subscription, got %d", rr.Code)
+	}
+	if rr := do("payments", http.MethodDelete, "/v1/subscriptions/1", ""); rr.Code != http.StatusNoContent {
+		t.Fatalf("expected the owner to delete it, got %d", rr.Code)
+	}
+}
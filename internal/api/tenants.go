@@ -0,0 +1,121 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+	"github.com/LeventeLantos/automatic-messaging/internal/repo"
+	"github.com/LeventeLantos/automatic-messaging/internal/tenant"
+)
+
+// WithTenants enables the tenant settings endpoints. Edits call the same
+// changed hook as route edits (see WithRoutes), since the sender applies
+// tenant overrides together with the routing table.
+func (h *Handler) WithTenants(t repo.TenantRepository) *Handler {
+	h.tenants = t
+	return h
+}
+
+// putTenantRequest replaces the tenant's settings; an omitted or null field
+// clears the override.
+type putTenantRequest struct {
+	Provider *string `json:"provider"`
+	SenderID *string `json:"senderId"`
+}
+
+type tenantResponse struct {
+	ID        string    `json:"id"`
+	Provider  *string   `json:"provider"`
+	SenderID  *string   `json:"senderId"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func toTenantResponse(t model.Tenant) tenantResponse {
+	return tenantResponse{
+		ID:        t.ID,
+		Provider:  t.Provider,
+		SenderID:  t.SenderID,
+		CreatedAt: t.CreatedAt,
+		UpdatedAt: t.UpdatedAt,
+	}
+}
+
+// GetTenant returns the settings of the caller's tenant.
+func (h *Handler) GetTenant(w http.ResponseWriter, r *http.Request) {
+	if !h.tenantsEnabled(w) {
+		return
+	}
+
+	t, err := h.tenants.GetTenant(r.Context(), requestTenant(r))
+	if err != nil {
+		writeTenantError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toTenantResponse(*t))
+}
+
+// PutTenant replaces the provider and sender ID used for the caller's
+// tenant's messages.
+func (h *Handler) PutTenant(w http.ResponseWriter, r *http.Request) {
+	if !h.tenantsEnabled(w) {
+		return
+	}
+
+	var req putTenantRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if req.Provider != nil {
+		if err := h.validateProvider(*req.Provider); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if err := validateSenderID(req.SenderID); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id := requestTenant(r)
+	t, err := h.tenants.UpdateTenant(r.Context(), model.Tenant{
+		ID:       id,
+		Provider: req.Provider,
+		SenderID: req.SenderID,
+	})
+	if err != nil {
+		writeTenantError(w, err)
+		return
+	}
+	h.notifyRoutesChanged()
+	h.audit(r, model.AuditTenantUpdate, "tenant:"+id, map[string]any{"provider": req.Provider, "senderId": req.SenderID})
+
+	writeJSON(w, http.StatusOK, toTenantResponse(*t))
+}
+
+func (h *Handler) tenantsEnabled(w http.ResponseWriter) bool {
+	if h.tenants == nil {
+		http.Error(w, "tenants are not enabled", http.StatusNotFound)
+		return false
+	}
+	return true
+}
+
+// requestTenant is the tenant of the request's API key, or the default
+// tenant when authentication is disabled.
+func requestTenant(r *http.Request) string {
+	if id := tenant.From(r.Context()); id != "" {
+		return id
+	}
+	return tenant.Default
+}
+
+func writeTenantError(w http.ResponseWriter, err error) {
+	if errors.Is(err, repo.ErrNotFound) {
+		http.Error(w, "tenant not found", http.StatusNotFound)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
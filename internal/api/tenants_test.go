@@ -0,0 +1,152 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/apikey"
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+	"github.com/LeventeLantos/automatic-messaging/internal/repo"
+	"github.com/LeventeLantos/automatic-messaging/internal/tenant"
+)
+
+type fakeTenants struct {
+	tenants map[string]model.Tenant
+}
+
+func (f *fakeTenants) ListTenants(ctx context.Context) ([]model.Tenant, error) {
+	var out []model.Tenant
+	for _, t := range f.tenants {
+		out = append(out, t)
+	}
+	return out, nil
+}
+
+func (f *fakeTenants) GetTenant(ctx context.Context, id string) (*model.Tenant, error) {
+	t, ok := f.tenants[id]
+	if !ok {
+		return nil, repo.ErrNotFound
+	}
+	return &t, nil
+}
+
+func (f *fakeTenants) UpdateTenant(ctx context.Context, t model.Tenant) (*model.Tenant, error) {
+	if _, ok := f.tenants[t.ID]; !ok {
+		return nil, repo.ErrNotFound
+	}
+	f.tenants[t.ID] = t
+	return &t, nil
+}
+
+// tenantKeys returns a key lookup where each raw key belongs to the tenant
+// of the same name and has scopes.
+func tenantKeys(scopes []string, tenants ...string) *fakeKeys {
+	f := &fakeKeys{byHash: map[string]model.APIKey{}}
+	for _, id := range tenants {
+		f.byHash[apikey.Hash(id)] = model.APIKey{Name: id, TenantID: id, Prefix: id, Scopes: scopes}
+	}
+	return f
+}
+
+func TestTenantIsolation(t *testing.T) {
+	pay := model.Message{ID: 1, TenantID: "payments", Status: model.Sent}
+	other := model.Message{ID: 2, TenantID: tenant.Default, Status: model.Sent}
+	fr := &fakeRepo{byID: map[int64]model.Message{1: pay, 2: other}}
+	fc := &fakeCache{records: map[int64]model.Message{1: pay, 2: other}}
+
+	s, h := newTestHandler(t, fr)
+	defer s.Stop()
+	keys := tenantKeys([]string{ScopeMessagesRead}, "payments", tenant.Default)
+	mux := Router(h.WithCache(fc).WithAuth(keys, time.Minute))
+
+	cases := []struct {
+		key      string
+		path     string
+		wantCode int
+	}{
+		{"payments", "/v1/messages/1", http.StatusOK},
+		{"payments", "/v1/messages/2", http.StatusNotFound},
+		{tenant.Default, "/v1/messages/2", http.StatusOK},
+		{tenant.Default, "/v1/messages/1", http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		req.Header.Set("X-API-Key", tc.key)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		if rr.Code != tc.wantCode {
+			t.Fatalf("%s %s: expected %d, got %d body=%q", tc.key, tc.path, tc.wantCode, rr.Code, rr.Body.String())
+		}
+	}
+}
+
+func TestTenantSettings(t *testing.T) {
+	t.Run("not enabled", func(t *testing.T) {
+		s, h := newTestHandler(t, &fakeRepo{})
+		defer s.Stop()
+
+		rr := httptest.NewRecorder()
+		Router(h).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/tenant", nil))
+		if rr.Code != http.StatusNotFound {
+			t.Fatalf("expected 404, got %d", rr.Code)
+		}
+	})
+
+	ft := &fakeTenants{tenants: map[string]model.Tenant{
+		"payments":     {ID: "payments"},
+		tenant.Default: {ID: tenant.Default},
+	}}
+	audit := &fakeAudit{}
+	changed := 0
+
+	s, h := newTestHandler(t, &fakeRepo{})
+	defer s.Stop()
+	keys := tenantKeys([]string{ScopeTenantRead, ScopeTenantWrite}, "payments")
+	h.WithTenants(ft).
+		WithRoutes(&fakeRoutes{}, func() { changed++ }).
+		WithProviders(fakeProviders{{Name: "primary"}, {Name: "backup"}}).
+		WithAudit(audit).
+		WithAuth(keys, time.Minute)
+	mux := Router(h)
+
+	do := func(method, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/v1/tenant", strings.NewReader(body))
+		req.Header.Set("X-API-Key", "payments")
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	for _, body := range []string{`{"provider":"nope"}`, `{"senderId":""}`, `{"senderId":"WAY-TOO-LONG-SENDER"}`} {
+		if rr := do(http.MethodPut, body); rr.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", body, rr.Code)
+		}
+	}
+
+	rr := do(http.MethodPut, `{"provider":"backup","senderId":"PAYCO"}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%q", rr.Code, rr.Body.String())
+	}
+	got := ft.tenants["payments"]
+	if got.Provider == nil || *got.Provider != "backup" || got.SenderID == nil || *got.SenderID != "PAYCO" {
+		t.Fatalf("unexpected tenant %+v", got)
+	}
+	if ft.tenants[tenant.Default].Provider != nil {
+		t.Fatalf("expected other tenants to be untouched")
+	}
+	if changed != 1 {
+		t.Fatalf("expected the routing table to be invalidated once, got %d", changed)
+	}
+	if len(audit.events) != 1 || audit.events[0].Action != model.AuditTenantUpdate || audit.events[0].Target != "tenant:payments" {
+		t.Fatalf("unexpected audit events %+v", audit.events)
+	}
+
+	body := decodeJSON(t, do(http.MethodGet, ""))
+	if body["id"] != "payments" || body["provider"] != "backup" || body["senderId"] != "PAYCO" {
+		t.Fatalf("unexpected tenant %v", body)
+	}
+}
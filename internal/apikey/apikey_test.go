@@ -0,0 +1,24 @@
+package apikey
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	key, prefix, err := Generate()
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	if !strings.HasPrefix(key, "am_") || len(key) != 46 || !strings.HasPrefix(key, prefix) || len(prefix) != 11 {
+		t.Fatalf("unexpected key %q prefix %q", key, prefix)
+	}
+
+	other, _, _ := Generate()
+	if other == key {
+		t.Fatalf("expected distinct keys")
+	}
+	if Hash(key) == Hash(other) || Hash(key) != Hash(key) || len(Hash(key)) != 64 {
+		t.Fatalf("expected a stable 64-char hash per key")
+	}
+}
@@ -0,0 +1,216 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+	"github.com/LeventeLantos/automatic-messaging/internal/service"
+)
+
+type State string
+
+const (
+	Closed   State = "closed"
+	Open     State = "open"
+	HalfOpen State = "half_open"
+)
+
+// Settings control when the breaker trips. It opens once at least
+// MinRequests sends finished within Window and the share of provider failures
+// among them reaches FailureRatio. After OpenFor it lets a single probe through
+// (half-open); the probe's outcome closes or reopens it.
+type Settings struct {
+	FailureRatio float64
+	MinRequests  int
+	Window       time.Duration
+	OpenFor      time.Duration
+}
+
+// Status is a snapshot of the breaker for status endpoints and metrics.
+type Status struct {
+	State    State      `json:"state"`
+	Requests int        `json:"requests"`
+	Failures int        `json:"failures"`
+	OpenedAt *time.Time `json:"openedAt,omitempty"`
+	RetryAt  *time.Time `json:"retryAt,omitempty"`
+	Opens    uint64     `json:"opens"`
+	Rejected uint64     `json:"rejected"`
+}
+
+type outcome struct {
+	at     time.Time
+	failed bool
+}
+
+// Breaker is a SendClient guarding next. Only failures that point at the
+// provider (service.Retryable classes) count; rejected numbers and routing
+// errors do not. While open, Send returns service.ErrCircuitOpen without
+// calling next.
+type Breaker struct {
+	next     service.SendClient
+	settings Settings
+
+	mu       sync.Mutex
+	state    State
+	outcomes []outcome
+	openedAt time.Time
+	probing  bool
+	opens    uint64
+	rejected uint64
+
+	now func() time.Time
+}
+
+func New(next service.SendClient, s Settings) (*Breaker, error) {
+	switch {
+	case s.FailureRatio <= 0 || s.FailureRatio > 1:
+		return nil, errors.New("failure ratio must be in (0, 1]")
+	case s.MinRequests <= 0:
+		return nil, errors.New("min requests must be > 0")
+	case s.Window <= 0:
+		return nil, errors.New("window must be > 0")
+	case s.OpenFor <= 0:
+		return nil, errors.New("open duration must be > 0")
+	}
+
+	return &Breaker{
+		next:     next,
+		settings: s,
+		state:    Closed,
+		now:      time.Now,
+	}, nil
+}
+
+// Allow reports whether a send would currently be let through. Callers use
+// it to avoid claiming work while the breaker is open.
+func (b *Breaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.advance(b.now())
+	return b.state == Closed || (b.state == HalfOpen && !b.probing)
+}
+
+func (b *Breaker) Send(ctx context.Context, phoneNumber, message string) (model.SendResult, error) {
+	probe, ok := b.acquire()
+	if !ok {
+		return model.SendResult{}, service.ErrCircuitOpen
+	}
+
+	res, err := b.next.Send(ctx, phoneNumber, message)
+	b.record(probe, service.Retryable(service.ClassifyError(res, err)))
+	return res, err
+}
+
+func (b *Breaker) Status() Status {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.advance(now)
+	b.prune(now)
+
+	st := Status{
+		State:    b.state,
+		Requests: len(b.outcomes),
+		Opens:    b.opens,
+		Rejected: b.rejected,
+	}
+	for _, o := range b.outcomes {
+		if o.failed {
+			st.Failures++
+		}
+	}
+	if b.state != Closed {
+		openedAt := b.openedAt
+		retryAt := openedAt.Add(b.settings.OpenFor)
+		st.OpenedAt, st.RetryAt = &openedAt, &retryAt
+	}
+	return st
+}
+
+func (b *Breaker) acquire() (probe bool, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.advance(b.now())
+	switch {
+	case b.state == Closed:
+		return false, true
+	case b.state == HalfOpen && !b.probing:
+		b.probing = true
+		return true, true
+	default:
+		b.rejected++
+		return false, false
+	}
+}
+
+func (b *Breaker) record(probe, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	if probe {
+		b.probing = false
+		if failed {
+			b.transition(Open, now)
+		} else {
+			b.transition(Closed, now)
+		}
+		return
+	}
+	if b.state != Closed {
+		return
+	}
+
+	b.outcomes = append(b.outcomes, outcome{at: now, failed: failed})
+	b.prune(now)
+
+	failures := 0
+	for _, o := range b.outcomes {
+		if o.failed {
+			failures++
+		}
+	}
+	if len(b.outcomes) >= b.settings.MinRequests &&
+		float64(failures) >= b.settings.FailureRatio*float64(len(b.outcomes)) {
+		b.transition(Open, now)
+	}
+}
+
+// advance moves an open breaker to half-open once OpenFor has passed.
+func (b *Breaker) advance(now time.Time) {
+	if b.state == Open && !now.Before(b.openedAt.Add(b.settings.OpenFor)) {
+		b.transition(HalfOpen, now)
+	}
+}
+
+func (b *Breaker) prune(now time.Time) {
+	cutoff := now.Add(-b.settings.Window)
+	i := 0
+	for i < len(b.outcomes) && b.outcomes[i].at.Before(cutoff) {
+		i++
+	}
+	b.outcomes = b.outcomes[i:]
+}
+
+func (b *Breaker) transition(to State, now time.Time) {
+	if b.state == to {
+		return
+	}
+	slog.Warn("circuit breaker state changed", "from", b.state, "to", to)
+
+	switch to {
+	case Open:
+		b.opens++
+		b.openedAt = now
+	case Closed:
+		b.outcomes = nil
+	}
+	b.state = to
+}
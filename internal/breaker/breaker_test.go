@@ -0,0 +1,159 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+	"github.com/LeventeLantos/automatic-messaging/internal/service"
+)
+
+type stubClient struct {
+	err   error
+	calls int
+}
+
+func (c *stubClient) Send(ctx context.Context, phoneNumber, message string) (model.SendResult, error) {
+	c.calls++
+	if c.err != nil {
+		return model.SendResult{}, c.err
+	}
+	return model.SendResult{RemoteMessageID: "r"}, nil
+}
+
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time          { return c.t }
+func (c *clock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestBreaker(t *testing.T, next service.SendClient) (*Breaker, *clock) {
+	t.Helper()
+
+	b, err := New(next, Settings{FailureRatio: 0.5, MinRequests: 4, Window: time.Minute, OpenFor: 30 * time.Second})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	c := &clock{t: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+	b.now = c.now
+	return b, c
+}
+
+func send(b *Breaker) error {
+	_, err := b.Send(context.Background(), "+36301234567", "hi")
+	return err
+}
+
+func TestBreaker_OpensOnFailureRatioAfterMinRequests(t *testing.T) {
+	next := &stubClient{err: errors.New("dial tcp: connection refused")}
+	b, _ := newTestBreaker(t, next)
+
+	for i := 0; i < 3; i++ {
+		_ = send(b)
+		if b.Status().State != Closed {
+			t.Fatalf("expected closed below min requests, opened after %d", i+1)
+		}
+	}
+	_ = send(b)
+
+	st := b.Status()
+	if st.State != Open || st.Opens != 1 || st.RetryAt == nil {
+		t.Fatalf("expected open after 4 failures, got %+v", st)
+	}
+	if b.Allow() {
+		t.Fatalf("expected Allow=false while open")
+	}
+	if err := send(b); !errors.Is(err, service.ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+	if next.calls != 4 || b.Status().Rejected != 1 {
+		t.Fatalf("expected the rejected send not to reach the provider, calls=%d status=%+v", next.calls, b.Status())
+	}
+}
+
+func TestBreaker_IgnoresNonRetryableFailures(t *testing.T) {
+	next := &stubClient{err: fmt.Errorf("%w for destination +44", service.ErrNoRoute)}
+	b, _ := newTestBreaker(t, next)
+
+	for i := 0; i < 10; i++ {
+		_ = send(b)
+	}
+	if st := b.Status(); st.State != Closed || st.Failures != 0 {
+		t.Fatalf("expected routing errors not to count, got %+v", st)
+	}
+}
+
+func TestBreaker_ForgetsOutcomesOutsideWindow(t *testing.T) {
+	next := &stubClient{err: errors.New("dial tcp: connection refused")}
+	b, c := newTestBreaker(t, next)
+
+	for i := 0; i < 3; i++ {
+		_ = send(b)
+	}
+	c.advance(2 * time.Minute)
+	_ = send(b)
+
+	if st := b.Status(); st.State != Closed || st.Requests != 1 {
+		t.Fatalf("expected old failures to expire, got %+v", st)
+	}
+}
+
+func TestBreaker_HalfOpenProbe(t *testing.T) {
+	cases := []struct {
+		name     string
+		probeErr error
+		want     State
+	}{
+		{"success closes", nil, Closed},
+		{"failure reopens", errors.New("dial tcp: connection refused"), Open},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			next := &stubClient{err: errors.New("dial tcp: connection refused")}
+			b, c := newTestBreaker(t, next)
+			for i := 0; i < 4; i++ {
+				_ = send(b)
+			}
+
+			c.advance(30 * time.Second)
+			if st := b.Status(); st.State != HalfOpen || !b.Allow() {
+				t.Fatalf("expected half-open with a probe allowed, got %+v", st)
+			}
+
+			next.err = tc.probeErr
+			_ = send(b)
+
+			st := b.Status()
+			if st.State != tc.want {
+				t.Fatalf("expected %s after probe, got %+v", tc.want, st)
+			}
+			if tc.want == Open && st.Opens != 2 {
+				t.Fatalf("expected the reopen to be counted, got %+v", st)
+			}
+			if tc.want == Closed && st.Requests != 0 {
+				t.Fatalf("expected a fresh window after closing, got %+v", st)
+			}
+		})
+	}
+}
+
+func TestNew_RejectsInvalidSettings(t *testing.T) {
+	valid := Settings{FailureRatio: 0.5, MinRequests: 1, Window: time.Second, OpenFor: time.Second}
+
+	for _, mutate := range []func(*Settings){
+		func(s *Settings) { s.FailureRatio = 0 },
+		func(s *Settings) { s.FailureRatio = 1.5 },
+		func(s *Settings) { s.MinRequests = 0 },
+		func(s *Settings) { s.Window = 0 },
+		func(s *Settings) { s.OpenFor = 0 },
+	} {
+		s := valid
+		mutate(&s)
+		if _, err := New(&stubClient{}, s); err == nil {
+			t.Fatalf("expected error for %+v", s)
+		}
+	}
+}
@@ -3,8 +3,29 @@ package cache
 import (
 	"context"
 	"time"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
 )
 
 type MessageCache interface {
 	StoreSent(ctx context.Context, internalID int64, remoteMessageID string, sentAt time.Time) error
+	GetSent(ctx context.Context, internalID int64) (SentEntry, bool, error)
+	LookupRemote(ctx context.Context, remoteMessageID string) (int64, bool, error)
+
+	GetMessage(ctx context.Context, id int64) (*model.Message, bool, error)
+	StoreMessage(ctx context.Context, m model.Message) error
+	Invalidate(ctx context.Context, id int64) error
+
+	Stats() Stats
+}
+
+type SentEntry struct {
+	RemoteMessageID string    `json:"remoteMessageId"`
+	SentAt          time.Time `json:"sentAt"`
+}
+
+// Stats counts read outcomes since the cache was created.
+type Stats struct {
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
 }
@@ -3,29 +3,54 @@ package cache
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
+	"sync/atomic"
 	"time"
 
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+	"github.com/LeventeLantos/automatic-messaging/internal/tracing"
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type RedisCache struct {
 	rdb *redis.Client
 	ttl time.Duration
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
 }
 
 func NewRedisCache(rdb *redis.Client, ttl time.Duration) *RedisCache {
 	return &RedisCache{rdb: rdb, ttl: ttl}
 }
 
-type sentValue struct {
-	RemoteMessageID string    `json:"remoteMessageId"`
-	SentAt          time.Time `json:"sentAt"`
+func sentKey(internalID int64) string {
+	return fmt.Sprintf("msg:%d", internalID)
+}
+
+func remoteKey(remoteMessageID string) string {
+	return "remote:" + remoteMessageID
+}
+
+func recordKey(internalID int64) string {
+	return fmt.Sprintf("msgrec:%d", internalID)
 }
 
-func (c *RedisCache) StoreSent(ctx context.Context, internalID int64, remoteMessageID string, sentAt time.Time) error {
-	key := fmt.Sprintf("msg:%d", internalID)
-	val := sentValue{
+func (c *RedisCache) StoreSent(ctx context.Context, internalID int64, remoteMessageID string, sentAt time.Time) (err error) {
+	ctx, span := tracing.Start(ctx, "StoreSent",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system.name", "redis"),
+			attribute.Int64("message.id", internalID),
+		),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	val := SentEntry{
 		RemoteMessageID: remoteMessageID,
 		SentAt:          sentAt.UTC(),
 	}
@@ -35,5 +60,79 @@ func (c *RedisCache) StoreSent(ctx context.Context, internalID int64, remoteMess
 		return err
 	}
 
-	return c.rdb.Set(ctx, key, b, c.ttl).Err()
+	_, err = c.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, sentKey(internalID), b, c.ttl)
+		pipe.Set(ctx, remoteKey(remoteMessageID), internalID, c.ttl)
+		return nil
+	})
+	return err
+}
+
+func (c *RedisCache) GetSent(ctx context.Context, internalID int64) (SentEntry, bool, error) {
+	var entry SentEntry
+	ok, err := c.getJSON(ctx, sentKey(internalID), &entry)
+	return entry, ok, err
+}
+
+func (c *RedisCache) LookupRemote(ctx context.Context, remoteMessageID string) (int64, bool, error) {
+	raw, err := c.rdb.Get(ctx, remoteKey(remoteMessageID)).Result()
+	if errors.Is(err, redis.Nil) {
+		c.misses.Add(1)
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid cached id for %s: %q", remoteKey(remoteMessageID), raw)
+	}
+	c.hits.Add(1)
+	return id, true, nil
+}
+
+func (c *RedisCache) GetMessage(ctx context.Context, id int64) (*model.Message, bool, error) {
+	var m model.Message
+	ok, err := c.getJSON(ctx, recordKey(id), &m)
+	if !ok || err != nil {
+		return nil, ok, err
+	}
+	return &m, true, nil
+}
+
+func (c *RedisCache) StoreMessage(ctx context.Context, m model.Message) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return c.rdb.Set(ctx, recordKey(m.ID), b, c.ttl).Err()
+}
+
+func (c *RedisCache) Invalidate(ctx context.Context, id int64) error {
+	return c.rdb.Del(ctx, recordKey(id)).Err()
+}
+
+func (c *RedisCache) Stats() Stats {
+	return Stats{
+		Hits:   c.hits.Load(),
+		Misses: c.misses.Load(),
+	}
+}
+
+func (c *RedisCache) getJSON(ctx context.Context, key string, dst any) (bool, error) {
+	raw, err := c.rdb.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		c.misses.Add(1)
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if err := json.Unmarshal(raw, dst); err != nil {
+		return false, fmt.Errorf("invalid cached value for %s: %w", key, err)
+	}
+	c.hits.Add(1)
+	return true, nil
 }
@@ -6,6 +6,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+	"github.com/LeventeLantos/automatic-messaging/internal/tracing"
+	"github.com/LeventeLantos/automatic-messaging/internal/tracing/tracingtest"
 	"github.com/alicebob/miniredis/v2"
 	"github.com/redis/go-redis/v9"
 )
@@ -49,7 +52,7 @@ func TestRedisCache_StoreSent_Success(t *testing.T) {
 		t.Fatalf("failed to get key %q: %v", key, err)
 	}
 
-	var got sentValue
+	var got SentEntry
 	if err := json.Unmarshal([]byte(raw), &got); err != nil {
 		t.Fatalf("failed to unmarshal value: %v", err)
 	}
@@ -93,7 +96,7 @@ func TestRedisCache_StoreSent_OverwritesExistingValue(t *testing.T) {
 		t.Fatalf("failed to get key msg:1: %v", err)
 	}
 
-	var got sentValue
+	var got SentEntry
 	if err := json.Unmarshal([]byte(raw), &got); err != nil {
 		t.Fatalf("failed to unmarshal value: %v", err)
 	}
@@ -123,3 +126,142 @@ func TestRedisCache_StoreSent_ContextCanceled(t *testing.T) {
 		t.Fatalf("expected error due to canceled context, got nil")
 	}
 }
+
+func TestRedisCache_StoreSent_RecordsSpan(t *testing.T) {
+	spans := tracingtest.Record(t)
+
+	mr := miniredis.RunT(t)
+	cache := NewRedisCache(redis.NewClient(&redis.Options{Addr: mr.Addr()}), time.Minute)
+
+	ctx, parent := tracing.Start(context.Background(), "tick")
+	if err := cache.StoreSent(ctx, 7, "remote-7", time.Now()); err != nil {
+		t.Fatalf("StoreSent() error: %v", err)
+	}
+	parent.End()
+
+	span, ok := tracingtest.Find(spans, "StoreSent")
+	if !ok {
+		t.Fatalf("expected a StoreSent span, got %v", spans.GetSpans())
+	}
+	if span.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Fatalf("expected StoreSent to be a child of the caller's span")
+	}
+}
+
+func TestRedisCache_GetSent_HitAndMiss(t *testing.T) {
+	t.Parallel()
+
+	mr := miniredis.RunT(t)
+	defer mr.Close()
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: mr.Addr(),
+	})
+
+	cache := NewRedisCache(rdb, time.Minute)
+	ctx := context.Background()
+	sentAt := time.Date(2026, 2, 2, 18, 0, 0, 0, time.UTC)
+
+	if _, ok, err := cache.GetSent(ctx, 7); err != nil || ok {
+		t.Fatalf("expected miss, got ok=%v err=%v", ok, err)
+	}
+
+	if err := cache.StoreSent(ctx, 7, "remote-7", sentAt); err != nil {
+		t.Fatalf("StoreSent() error: %v", err)
+	}
+
+	got, ok, err := cache.GetSent(ctx, 7)
+	if err != nil {
+		t.Fatalf("GetSent() error: %v", err)
+	}
+	if !ok {
+		t.Fatalf("expected hit after StoreSent")
+	}
+	if got.RemoteMessageID != "remote-7" || !got.SentAt.Equal(sentAt) {
+		t.Fatalf("unexpected entry: %+v", got)
+	}
+
+	if stats := cache.Stats(); stats.Hits != 1 || stats.Misses != 1 {
+		t.Fatalf("expected hits=1 misses=1, got %+v", stats)
+	}
+}
+
+func TestRedisCache_LookupRemote(t *testing.T) {
+	t.Parallel()
+
+	mr := miniredis.RunT(t)
+	defer mr.Close()
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: mr.Addr(),
+	})
+
+	cache := NewRedisCache(rdb, time.Minute)
+	ctx := context.Background()
+
+	if _, ok, err := cache.LookupRemote(ctx, "unknown"); err != nil || ok {
+		t.Fatalf("expected miss, got ok=%v err=%v", ok, err)
+	}
+
+	if err := cache.StoreSent(ctx, 99, "remote-99", time.Now()); err != nil {
+		t.Fatalf("StoreSent() error: %v", err)
+	}
+
+	if ttl := mr.TTL("remote:remote-99"); ttl <= 0 {
+		t.Fatalf("expected TTL on reverse key, got %v", ttl)
+	}
+
+	id, ok, err := cache.LookupRemote(ctx, "remote-99")
+	if err != nil {
+		t.Fatalf("LookupRemote() error: %v", err)
+	}
+	if !ok || id != 99 {
+		t.Fatalf("expected id=99 hit, got id=%d ok=%v", id, ok)
+	}
+}
+
+func TestRedisCache_MessageRecord_StoreGetInvalidate(t *testing.T) {
+	t.Parallel()
+
+	mr := miniredis.RunT(t)
+	defer mr.Close()
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: mr.Addr(),
+	})
+
+	cache := NewRedisCache(rdb, time.Minute)
+	ctx := context.Background()
+
+	remoteID := "remote-5"
+	msg := model.Message{
+		ID:              5,
+		RecipientPhone:  "+361234567",
+		Content:         "hello",
+		Status:          model.Sent,
+		RemoteMessageID: &remoteID,
+	}
+
+	if err := cache.StoreMessage(ctx, msg); err != nil {
+		t.Fatalf("StoreMessage() error: %v", err)
+	}
+
+	got, ok, err := cache.GetMessage(ctx, 5)
+	if err != nil {
+		t.Fatalf("GetMessage() error: %v", err)
+	}
+	if !ok {
+		t.Fatalf("expected hit after StoreMessage")
+	}
+	if got.ID != 5 || got.Status != model.Sent || got.RemoteMessageID == nil || *got.RemoteMessageID != remoteID {
+		t.Fatalf("unexpected message: %+v", got)
+	}
+
+	if err := cache.Invalidate(ctx, 5); err != nil {
+		t.Fatalf("Invalidate() error: %v", err)
+	}
+
+	if _, ok, err := cache.GetMessage(ctx, 5); err != nil || ok {
+		t.Fatalf("expected miss after Invalidate, got ok=%v err=%v", ok, err)
+	}
+}
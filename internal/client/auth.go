@@ -0,0 +1,85 @@
+package client
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/signature"
+)
+
+type AuthMode string
+
+const (
+	AuthNone   AuthMode = "none"
+	AuthBearer AuthMode = "bearer"
+	AuthBasic  AuthMode = "basic"
+	AuthHMAC   AuthMode = "hmac"
+)
+
+// Auth describes how outbound webhook requests are authenticated. Headers are
+// sent in every mode, so an API-key header works with AuthNone.
+type Auth struct {
+	Mode    AuthMode
+	Headers map[string]string
+
+	Token string
+
+	Username string
+	Password string
+
+	HMAC HMACAuth
+}
+
+// HMACAuth signs "<timestamp>.<body>" with signature.Sign. Empty header names
+// fall back to signature.HeaderSignature and signature.HeaderTimestamp.
+// Tolerance is the clock window the receiver enforces; Verify applies it.
+type HMACAuth struct {
+	Secret          []byte
+	Header          string
+	TimestampHeader string
+	Tolerance       time.Duration
+}
+
+func (a Auth) apply(req *http.Request, body []byte, now time.Time) {
+	for k, v := range a.Headers {
+		req.Header.Set(k, v)
+	}
+
+	switch a.Mode {
+	case AuthBearer:
+		req.Header.Set("Authorization", "Bearer "+a.Token)
+	case AuthBasic:
+		req.SetBasicAuth(a.Username, a.Password)
+	case AuthHMAC:
+		ts := signature.Timestamp(now)
+		req.Header.Set(a.HMAC.timestampHeader(), ts)
+		req.Header.Set(a.HMAC.header(), signature.Sign(a.HMAC.Secret, ts, body))
+	}
+}
+
+// Verify checks a request signed with the same settings. It is meant for
+// receivers such as test doubles and mock providers.
+func (h HMACAuth) Verify(r *http.Request, body []byte, now time.Time) error {
+	return signature.Verify(
+		h.Secret,
+		r.Header.Get(h.timestampHeader()),
+		r.Header.Get(h.header()),
+		body,
+		h.Tolerance,
+		now,
+	)
+}
+
+func (h HMACAuth) header() string {
+	if h.Header == "" {
+		return signature.HeaderSignature
+	}
+	return h.Header
+}
+
+func (h HMACAuth) timestampHeader() string {
+	if h.TimestampHeader == "" {
+		return signature.HeaderTimestamp
+	}
+	return h.TimestampHeader
+}
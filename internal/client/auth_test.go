@@ -0,0 +1,155 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/signature"
+)
+
+// receiver runs check against every request and answers like the default
+// provider, or with 401 when check fails.
+func receiver(t *testing.T, check func(r *http.Request, body []byte) error) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioReadAll(r)
+		if err := check(r, body); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(err.Error()))
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"message":"Accepted","messageId":"abc"}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestWebhookClient_Auth_HMAC(t *testing.T) {
+	t.Parallel()
+
+	hmacAuth := HMACAuth{
+		Secret:          []byte("s3cret"),
+		Header:          "X-Provider-Signature",
+		TimestampHeader: "X-Provider-Timestamp",
+		Tolerance:       time.Minute,
+	}
+
+	var verifyErr error
+	srv := receiver(t, func(r *http.Request, body []byte) error {
+		verifyErr = hmacAuth.Verify(r, body, time.Now())
+		return verifyErr
+	})
+
+	c := NewWebhookClient(srv.URL).WithAuth(Auth{Mode: AuthHMAC, HMAC: hmacAuth})
+	if _, err := c.Send(context.Background(), "+361", "hi"); err != nil {
+		t.Fatalf("Send() error: %v (verify: %v)", err, verifyErr)
+	}
+}
+
+func TestWebhookClient_Auth_HMAC_DefaultHeadersAndWrongSecret(t *testing.T) {
+	t.Parallel()
+
+	var headerErr, verifyErr error
+	srv := receiver(t, func(r *http.Request, body []byte) error {
+		if r.Header.Get(signature.HeaderSignature) == "" || r.Header.Get(signature.HeaderTimestamp) == "" {
+			headerErr = errors.New("default signature headers not set")
+			return headerErr
+		}
+		verifyErr = HMACAuth{Secret: []byte("other")}.Verify(r, body, time.Now())
+		return verifyErr
+	})
+
+	c := NewWebhookClient(srv.URL).WithAuth(Auth{Mode: AuthHMAC, HMAC: HMACAuth{Secret: []byte("s3cret")}})
+	if _, err := c.Send(context.Background(), "+361", "hi"); err == nil {
+		t.Fatalf("expected the receiver to reject the request")
+	}
+	if headerErr != nil {
+		t.Fatal(headerErr)
+	}
+	if !errors.Is(verifyErr, signature.ErrMismatch) {
+		t.Fatalf("expected signature mismatch, got %v", verifyErr)
+	}
+}
+
+func TestWebhookClient_Auth_HMAC_OutsideTolerance(t *testing.T) {
+	t.Parallel()
+
+	hmacAuth := HMACAuth{Secret: []byte("s3cret"), Tolerance: 5 * time.Minute}
+
+	var verifyErr error
+	srv := receiver(t, func(r *http.Request, body []byte) error {
+		verifyErr = hmacAuth.Verify(r, body, time.Now())
+		return verifyErr
+	})
+
+	c := NewWebhookClient(srv.URL).WithAuth(Auth{Mode: AuthHMAC, HMAC: hmacAuth})
+	c.now = func() time.Time { return time.Now().Add(-time.Hour) }
+
+	if _, err := c.Send(context.Background(), "+361", "hi"); err == nil {
+		t.Fatalf("expected stale signature to be rejected")
+	}
+	if !errors.Is(verifyErr, signature.ErrExpired) {
+		t.Fatalf("expected expired signature, got %v", verifyErr)
+	}
+}
+
+func TestWebhookClient_Auth_BearerBasicAndHeaders(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name  string
+		auth  Auth
+		check func(r *http.Request) error
+	}{
+		{
+			name: "bearer",
+			auth: Auth{Mode: AuthBearer, Token: "tok"},
+			check: func(r *http.Request) error {
+				if got := r.Header.Get("Authorization"); got != "Bearer tok" {
+					return errors.New("bad bearer: " + got)
+				}
+				return nil
+			},
+		},
+		{
+			name: "basic",
+			auth: Auth{Mode: AuthBasic, Username: "user", Password: "pass"},
+			check: func(r *http.Request) error {
+				u, p, ok := r.BasicAuth()
+				if !ok || u != "user" || p != "pass" {
+					return errors.New("bad basic auth")
+				}
+				return nil
+			},
+		},
+		{
+			name: "static headers",
+			auth: Auth{Mode: AuthNone, Headers: map[string]string{"X-Api-Key": "k1"}},
+			check: func(r *http.Request) error {
+				if r.Header.Get("X-Api-Key") != "k1" || r.Header.Get("Authorization") != "" {
+					return errors.New("unexpected auth headers")
+				}
+				return nil
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := receiver(t, func(r *http.Request, _ []byte) error { return tc.check(r) })
+			res, err := NewWebhookClient(srv.URL).WithAuth(tc.auth).Send(context.Background(), "+361", "hi")
+			if err != nil {
+				t.Fatalf("Send() error: %v body=%q", err, res.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+const (
+	ContentTypeJSON = "json"
+	ContentTypeForm = "form"
+
+	defaultRemoteIDPath = "messageId"
+)
+
+// Mapping describes a provider's request and response shape. The zero value
+// is the original provider: JSON {"to","content"}, 202 Accepted and the remote
+// ID in "messageId".
+type Mapping struct {
+	body             *template.Template
+	contentType      string
+	acceptedStatuses []int
+	remoteIDPath     []string
+}
+
+// TemplateData is what body templates render against. From is the sender ID
+// chosen by routing, or "" to use the provider's default.
+type TemplateData struct {
+	To      string
+	Content string
+	From    string
+}
+
+// NewMapping builds a Mapping. bodyTemplate is a text/template rendered with
+// TemplateData; besides the builtins (e.g. urlquery for form bodies) it can
+// use json, which JSON-encodes a value. An empty template keeps the default
+// body. remoteIDPath is a dotted path into the JSON response, where numeric
+// segments index arrays (e.g. "messages.0.message-id").
+func NewMapping(bodyTemplate, contentType string, acceptedStatuses []int, remoteIDPath string) (Mapping, error) {
+	var m Mapping
+
+	if bodyTemplate != "" {
+		tmpl, err := template.New("body").
+			Funcs(template.FuncMap{"json": jsonValue}).
+			Option("missingkey=error").
+			Parse(bodyTemplate)
+		if err != nil {
+			return Mapping{}, fmt.Errorf("parse body template: %w", err)
+		}
+		m.body = tmpl
+	}
+
+	switch contentType {
+	case "", ContentTypeJSON, ContentTypeForm:
+		m.contentType = contentType
+	default:
+		return Mapping{}, fmt.Errorf("unknown content type %q", contentType)
+	}
+	if contentType == ContentTypeForm && m.body == nil {
+		return Mapping{}, errors.New("form content type requires a body template")
+	}
+
+	for _, code := range acceptedStatuses {
+		if code < 100 || code > 599 {
+			return Mapping{}, fmt.Errorf("invalid accepted status %d", code)
+		}
+	}
+	m.acceptedStatuses = acceptedStatuses
+
+	if remoteIDPath != "" {
+		m.remoteIDPath = strings.Split(remoteIDPath, ".")
+		if slices.Contains(m.remoteIDPath, "") {
+			return Mapping{}, fmt.Errorf("invalid remote ID path %q", remoteIDPath)
+		}
+	}
+
+	return m, nil
+}
+
+func (m Mapping) encode(data TemplateData) ([]byte, string, error) {
+	if m.body == nil {
+		b, err := json.Marshal(sendRequest{To: data.To, Content: data.Content, From: data.From})
+		return b, "application/json", err
+	}
+
+	var buf bytes.Buffer
+	if err := m.body.Execute(&buf, data); err != nil {
+		return nil, "", err
+	}
+	if m.contentType == ContentTypeForm {
+		return buf.Bytes(), "application/x-www-form-urlencoded", nil
+	}
+	return buf.Bytes(), "application/json", nil
+}
+
+func (m Mapping) accepts(status int) bool {
+	if len(m.acceptedStatuses) == 0 {
+		return status == 202
+	}
+	return slices.Contains(m.acceptedStatuses, status)
+}
+
+func (m Mapping) pathName() string {
+	if len(m.remoteIDPath) == 0 {
+		return defaultRemoteIDPath
+	}
+	return strings.Join(m.remoteIDPath, ".")
+}
+
+// remoteID extracts the remote message ID from a decoded JSON response.
+// Strings and numbers are accepted; anything else counts as missing.
+func (m Mapping) remoteID(v any) string {
+	path := m.remoteIDPath
+	if len(path) == 0 {
+		path = []string{defaultRemoteIDPath}
+	}
+	return lookupPath(v, path)
+}
+
+// lookupPath walks a dotted path through decoded JSON and returns the string
+// or number found there, or "".
+func lookupPath(v any, path []string) string {
+	for _, seg := range path {
+		switch node := v.(type) {
+		case map[string]any:
+			v = node[seg]
+		case []any:
+			i, err := strconv.Atoi(seg)
+			if err != nil || i < 0 || i >= len(node) {
+				return ""
+			}
+			v = node[i]
+		default:
+			return ""
+		}
+	}
+
+	switch id := v.(type) {
+	case string:
+		return id
+	case json.Number:
+		return id.String()
+	default:
+		return ""
+	}
+}
+
+func jsonValue(v any) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
@@ -0,0 +1,151 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestWebhookClient_Mapping_FormEncodedTwilioStyle(t *testing.T) {
+	t.Parallel()
+
+	var form url.Values
+	var contentType string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		b, _ := ioReadAll(r)
+		form, _ = url.ParseQuery(string(b))
+
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"sid":"SM123","status":"queued"}`))
+	}))
+	defer srv.Close()
+
+	m, err := NewMapping(
+		`To={{urlquery .To}}&From=Acme&Body={{urlquery .Content}}`,
+		ContentTypeForm,
+		[]int{http.StatusCreated},
+		"sid",
+	)
+	if err != nil {
+		t.Fatalf("NewMapping: %v", err)
+	}
+
+	res, err := NewWebhookClient(srv.URL).WithMapping(m).Send(context.Background(), "+361234567", "hi & bye")
+	if err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+	if res.RemoteMessageID != "SM123" {
+		t.Fatalf("expected remote ID SM123, got %q", res.RemoteMessageID)
+	}
+	if contentType != "application/x-www-form-urlencoded" {
+		t.Fatalf("unexpected Content-Type %q", contentType)
+	}
+	if form.Get("To") != "+361234567" || form.Get("Body") != "hi & bye" || form.Get("From") != "Acme" {
+		t.Fatalf("unexpected form body: %v", form)
+	}
+}
+
+func TestWebhookClient_Mapping_JSONVonageStyle(t *testing.T) {
+	t.Parallel()
+
+	var got map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioReadAll(r)
+		_ = json.Unmarshal(b, &got)
+
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"message-count":"1","messages":[{"message-id":"0A00","status":"0"}]}`))
+	}))
+	defer srv.Close()
+
+	m, err := NewMapping(
+		`{"from":"Acme","to":{{json .To}},"text":{{json .Content}}}`,
+		ContentTypeJSON,
+		[]int{http.StatusOK},
+		"messages.0.message-id",
+	)
+	if err != nil {
+		t.Fatalf("NewMapping: %v", err)
+	}
+
+	res, err := NewWebhookClient(srv.URL).WithMapping(m).Send(context.Background(), "+361", `say "hi"`)
+	if err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+	if res.RemoteMessageID != "0A00" {
+		t.Fatalf("expected remote ID 0A00, got %q", res.RemoteMessageID)
+	}
+	if got["to"] != "+361" || got["text"] != `say "hi"` {
+		t.Fatalf("unexpected request body: %v", got)
+	}
+}
+
+func TestWebhookClient_Mapping_NumericIDAndMissingPath(t *testing.T) {
+	t.Parallel()
+
+	body := `{"data":{"id":12345678901234567}}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	m, _ := NewMapping("", "", nil, "data.id")
+	res, err := NewWebhookClient(srv.URL).WithMapping(m).Send(context.Background(), "+361", "hi")
+	if err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+	if res.RemoteMessageID != "12345678901234567" {
+		t.Fatalf("expected numeric ID without precision loss, got %q", res.RemoteMessageID)
+	}
+
+	m, _ = NewMapping("", "", nil, "data.sid")
+	_, err = NewWebhookClient(srv.URL).WithMapping(m).Send(context.Background(), "+361", "hi")
+	if err == nil || !strings.Contains(err.Error(), "missing data.sid") {
+		t.Fatalf("expected missing path error, got %v", err)
+	}
+}
+
+func TestWebhookClient_Mapping_StatusNotAccepted(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"messageId":"abc"}`))
+	}))
+	defer srv.Close()
+
+	m, _ := NewMapping("", "", []int{http.StatusOK, http.StatusCreated}, "")
+	_, err := NewWebhookClient(srv.URL).WithMapping(m).Send(context.Background(), "+361", "hi")
+	if err == nil || !strings.Contains(err.Error(), "unexpected status code: 202") {
+		t.Fatalf("expected unexpected status error, got %v", err)
+	}
+}
+
+func TestNewMapping_Invalid(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name        string
+		body, ctype string
+		statuses    []int
+		path        string
+	}{
+		{name: "bad template", body: "{{.To"},
+		{name: "unknown content type", ctype: "xml"},
+		{name: "form without template", ctype: ContentTypeForm},
+		{name: "bad status", statuses: []int{42}},
+		{name: "empty path segment", path: "messages..id"},
+	}
+
+	for _, tc := range cases {
+		if _, err := NewMapping(tc.body, tc.ctype, tc.statuses, tc.path); err == nil {
+			t.Fatalf("%s: expected error", tc.name)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+	"github.com/LeventeLantos/automatic-messaging/internal/service"
+	"github.com/LeventeLantos/automatic-messaging/internal/tracing"
+)
+
+const defaultStatusPath = "status"
+
+// defaultStatusValues maps common provider status strings when no explicit
+// mapping is configured.
+var defaultStatusValues = map[string]model.Status{
+	"accepted":    model.Sent,
+	"sent":        model.Sent,
+	"delivered":   model.Delivered,
+	"undelivered": model.Undelivered,
+	"failed":      model.Undelivered,
+}
+
+// StatusLookup describes how to ask a provider about a message. The zero value
+// disables lookups.
+type StatusLookup struct {
+	url    *template.Template
+	path   []string
+	values map[string]model.Status
+}
+
+// StatusURLData is what status URL templates render against.
+type StatusURLData struct {
+	RemoteMessageID string
+	IdempotencyKey  string
+}
+
+// NewStatusLookup builds a StatusLookup. urlTemplate is a text/template
+// rendered with StatusURLData; path escapes a URL path segment and the
+// builtin urlquery escapes a query value. statusPath is a dotted path to the
+// status string in the JSON response and values maps those strings to sent,
+// delivered or undelivered. An empty urlTemplate disables lookups.
+func NewStatusLookup(urlTemplate, statusPath string, values map[string]string) (StatusLookup, error) {
+	if urlTemplate == "" {
+		return StatusLookup{}, nil
+	}
+
+	tmpl, err := template.New("status").
+		Funcs(template.FuncMap{"path": url.PathEscape}).
+		Parse(urlTemplate)
+	if err != nil {
+		return StatusLookup{}, fmt.Errorf("parse status url template: %w", err)
+	}
+
+	if statusPath == "" {
+		statusPath = defaultStatusPath
+	}
+	path := strings.Split(statusPath, ".")
+	if slices.Contains(path, "") {
+		return StatusLookup{}, fmt.Errorf("invalid status path %q", statusPath)
+	}
+
+	mapped := defaultStatusValues
+	if len(values) > 0 {
+		mapped = make(map[string]model.Status, len(values))
+		for raw, status := range values {
+			switch s := model.Status(status); s {
+			case model.Sent, model.Delivered, model.Undelivered:
+				mapped[raw] = s
+			default:
+				return StatusLookup{}, fmt.Errorf("status value %q: unsupported status %q", raw, status)
+			}
+		}
+	}
+
+	return StatusLookup{url: tmpl, path: path, values: mapped}, nil
+}
+
+func (l StatusLookup) enabled() bool {
+	return l.url != nil
+}
+
+// WithStatusLookup makes Status ask the provider using l.
+func (c *WebhookClient) WithStatusLookup(l StatusLookup) *WebhookClient {
+	c.status = l
+	return c
+}
+
+// Status looks the message up at the provider. A 404 is reported as
+// service.ErrStatusNotFound; without a configured lookup every call returns
+// service.ErrStatusUnsupported.
+func (c *WebhookClient) Status(ctx context.Context, q service.StatusQuery) (service.ProviderStatus, error) {
+	if !c.status.enabled() {
+		return service.ProviderStatus{}, service.ErrStatusUnsupported
+	}
+
+	var u bytes.Buffer
+	if err := c.status.url.Execute(&u, StatusURLData{
+		RemoteMessageID: q.RemoteMessageID,
+		IdempotencyKey:  q.IdempotencyKey,
+	}); err != nil {
+		return service.ProviderStatus{}, fmt.Errorf("render status url: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return service.ProviderStatus{}, err
+	}
+	tracing.Inject(ctx, req.Header)
+	c.auth.apply(req, nil, c.now())
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return service.ProviderStatus{}, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return service.ProviderStatus{}, service.ErrStatusNotFound
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return service.ProviderStatus{}, fmt.Errorf("unexpected status code: %d body=%q", resp.StatusCode, string(body))
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var decoded any
+	if err := dec.Decode(&decoded); err != nil {
+		return service.ProviderStatus{}, fmt.Errorf("failed to decode json: %w body=%q", err, string(body))
+	}
+
+	raw := lookupPath(decoded, c.status.path)
+	if raw == "" {
+		return service.ProviderStatus{}, fmt.Errorf("missing %s in response body=%q", strings.Join(c.status.path, "."), string(body))
+	}
+	status, ok := c.status.values[raw]
+	if !ok {
+		return service.ProviderStatus{}, fmt.Errorf("unknown provider status %q", raw)
+	}
+
+	remoteID := c.mapping.remoteID(decoded)
+	if remoteID == "" {
+		remoteID = q.RemoteMessageID
+	}
+
+	return service.ProviderStatus{
+		RemoteMessageID: remoteID,
+		Status:          status,
+		At:              c.now().UTC(),
+	}, nil
+}
@@ -0,0 +1,96 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+	"github.com/LeventeLantos/automatic-messaging/internal/service"
+)
+
+func TestWebhookClient_Status(t *testing.T) {
+	t.Parallel()
+
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.RequestURI())
+		if r.Header.Get("Authorization") != "Bearer t" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.EscapedPath() {
+		case "/messages/a%2Fb":
+			_, _ = w.Write([]byte(`{"data":{"state":"DELIVRD","id":"a/b"}}`))
+		case "/lookup":
+			_, _ = w.Write([]byte(`{"data":{"state":"ACCEPTD","id":"r-9"}}`))
+		case "/messages/odd":
+			_, _ = w.Write([]byte(`{"data":{"state":"QUEUED"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	lookup, err := NewStatusLookup(
+		srv.URL+`{{if .RemoteMessageID}}/messages/{{path .RemoteMessageID}}{{else}}/lookup?key={{urlquery .IdempotencyKey}}{{end}}`,
+		"data.state",
+		map[string]string{"DELIVRD": "delivered", "ACCEPTD": "sent"},
+	)
+	if err != nil {
+		t.Fatalf("new status lookup: %v", err)
+	}
+	mapping, err := NewMapping("", "", nil, "data.id")
+	if err != nil {
+		t.Fatalf("new mapping: %v", err)
+	}
+	c := NewWebhookClient(srv.URL).
+		WithAuth(Auth{Mode: AuthBearer, Token: "t"}).
+		WithMapping(mapping).
+		WithStatusLookup(lookup)
+
+	st, err := c.Status(context.Background(), service.StatusQuery{RemoteMessageID: "a/b"})
+	if err != nil || st.Status != model.Delivered || st.RemoteMessageID != "a/b" {
+		t.Fatalf("expected delivered, got %+v err=%v", st, err)
+	}
+
+	st, err = c.Status(context.Background(), service.StatusQuery{IdempotencyKey: "msg-1-0"})
+	if err != nil || st.Status != model.Sent || st.RemoteMessageID != "r-9" {
+		t.Fatalf("expected lookup by key to find r-9, got %+v err=%v", st, err)
+	}
+	if paths[1] != "/lookup?key=msg-1-0" {
+		t.Fatalf("unexpected lookup url %q", paths[1])
+	}
+
+	if _, err := c.Status(context.Background(), service.StatusQuery{RemoteMessageID: "gone"}); !errors.Is(err, service.ErrStatusNotFound) {
+		t.Fatalf("expected ErrStatusNotFound on 404, got %v", err)
+	}
+	if _, err := c.Status(context.Background(), service.StatusQuery{RemoteMessageID: "odd"}); err == nil {
+		t.Fatalf("expected an error for an unmapped provider status")
+	}
+}
+
+func TestWebhookClient_StatusUnsupportedByDefault(t *testing.T) {
+	t.Parallel()
+
+	c := NewWebhookClient("http://127.0.0.1:0")
+	if _, err := c.Status(context.Background(), service.StatusQuery{RemoteMessageID: "x"}); !errors.Is(err, service.ErrStatusUnsupported) {
+		t.Fatalf("expected ErrStatusUnsupported, got %v", err)
+	}
+}
+
+func TestNewStatusLookup_Validation(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewStatusLookup("http://x/{{", "", nil); err == nil {
+		t.Fatalf("expected template parse error")
+	}
+	if _, err := NewStatusLookup("http://x", "a..b", nil); err == nil {
+		t.Fatalf("expected invalid path error")
+	}
+	if _, err := NewStatusLookup("http://x", "", map[string]string{"X": "pending"}); err == nil {
+		t.Fatalf("expected unsupported status error")
+	}
+}
@@ -0,0 +1,123 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// Transport describes how a WebhookClient connects to its provider. Zero
+// timeouts and limits fall back to the defaults of NewWebhookClient.
+type Transport struct {
+	// CertFile and KeyFile hold a PEM client certificate for mTLS; CAFile
+	// replaces the system roots for verifying the provider.
+	CertFile string
+	KeyFile  string
+	CAFile   string
+
+	// ProxyURL overrides the HTTP_PROXY / HTTPS_PROXY environment.
+	ProxyURL string
+
+	Timeout             time.Duration
+	DialTimeout         time.Duration
+	TLSHandshakeTimeout time.Duration
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+
+	// HTTP2 enables HTTP/2 when the provider supports it.
+	HTTP2 bool
+}
+
+const (
+	defaultTimeout             = 10 * time.Second
+	defaultDialTimeout         = 5 * time.Second
+	defaultTLSHandshakeTimeout = 5 * time.Second
+)
+
+// NewHTTPClient builds the http.Client described by t.
+func NewHTTPClient(t Transport) (*http.Client, error) {
+	tlsCfg, err := t.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	proxy := http.ProxyFromEnvironment
+	if t.ProxyURL != "" {
+		u, err := url.Parse(t.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("parse proxy url: %w", err)
+		}
+		proxy = http.ProxyURL(u)
+	}
+
+	tr := &http.Transport{
+		Proxy: proxy,
+		DialContext: (&net.Dialer{
+			Timeout:   orDefault(t.DialTimeout, defaultDialTimeout),
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSClientConfig:     tlsCfg,
+		TLSHandshakeTimeout: orDefault(t.TLSHandshakeTimeout, defaultTLSHandshakeTimeout),
+		MaxIdleConns:        t.MaxIdleConns,
+		MaxIdleConnsPerHost: t.MaxIdleConnsPerHost,
+		IdleConnTimeout:     90 * time.Second,
+		ForceAttemptHTTP2:   t.HTTP2,
+	}
+	if !t.HTTP2 {
+		// A non-nil empty map is how net/http is told not to negotiate h2.
+		tr.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+
+	return &http.Client{
+		Timeout:   orDefault(t.Timeout, defaultTimeout),
+		Transport: tr,
+	}, nil
+}
+
+func (t Transport) tlsConfig() (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	switch {
+	case t.CertFile != "" && t.KeyFile != "":
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	case t.CertFile != "" || t.KeyFile != "":
+		return nil, errors.New("client certificate and key must be set together")
+	}
+
+	if t.CAFile != "" {
+		pem, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read CA bundle: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", t.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+	return cfg, nil
+}
+
+func orDefault(d, def time.Duration) time.Duration {
+	if d <= 0 {
+		return def
+	}
+	return d
+}
+
+// WithHTTPClient replaces the default client, e.g. with one from
+// NewHTTPClient.
+func (c *WebhookClient) WithHTTPClient(hc *http.Client) *WebhookClient {
+	c.client = hc
+	return c
+}
@@ -0,0 +1,184 @@
+package client
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCA issues certificates for the mTLS tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create ca: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a PEM certificate and key for a server (127.0.0.1) or client.
+func (ca *testCA) issue(t *testing.T, server bool) (certPEM, keyPEM []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if server {
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+		tmpl.IPAddresses = []net.IP{net.ParseIP("127.0.0.1")}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("create cert: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeTemp(t *testing.T, name string, b []byte) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestNewHTTPClient_MutualTLS(t *testing.T) {
+	t.Parallel()
+
+	ca := newTestCA(t)
+	serverCert, serverKey := ca.issue(t, true)
+	pair, err := tls.X509KeyPair(serverCert, serverKey)
+	if err != nil {
+		t.Fatalf("server key pair: %v", err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"messageId":"tls-1"}`))
+	}))
+	srv.TLS = &tls.Config{
+		Certificates: []tls.Certificate{pair},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+	}
+	srv.StartTLS()
+	defer srv.Close()
+
+	caFile := writeTemp(t, "ca.pem", ca.pem)
+
+	// Without a client certificate the handshake is refused.
+	hc, err := NewHTTPClient(Transport{CAFile: caFile})
+	if err != nil {
+		t.Fatalf("NewHTTPClient: %v", err)
+	}
+	if _, err := NewWebhookClient(srv.URL).WithHTTPClient(hc).Send(context.Background(), "+361", "hi"); err == nil {
+		t.Fatalf("expected the server to reject a client without a certificate")
+	}
+
+	clientCert, clientKey := ca.issue(t, false)
+	hc, err = NewHTTPClient(Transport{
+		CertFile: writeTemp(t, "client.pem", clientCert),
+		KeyFile:  writeTemp(t, "client.key", clientKey),
+		CAFile:   caFile,
+	})
+	if err != nil {
+		t.Fatalf("NewHTTPClient: %v", err)
+	}
+	res, err := NewWebhookClient(srv.URL).WithHTTPClient(hc).Send(context.Background(), "+361", "hi")
+	if err != nil || res.RemoteMessageID != "tls-1" {
+		t.Fatalf("expected mTLS send to succeed, got %+v err=%v", res, err)
+	}
+}
+
+func TestNewHTTPClient_Proxy(t *testing.T) {
+	t.Parallel()
+
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"messageId":"via-proxy"}`))
+	}))
+	defer proxy.Close()
+
+	hc, err := NewHTTPClient(Transport{ProxyURL: proxy.URL})
+	if err != nil {
+		t.Fatalf("NewHTTPClient: %v", err)
+	}
+
+	res, err := NewWebhookClient("http://provider.invalid/send").WithHTTPClient(hc).Send(context.Background(), "+361", "hi")
+	if err != nil || res.RemoteMessageID != "via-proxy" {
+		t.Fatalf("expected the request to go through the proxy, got %+v err=%v", res, err)
+	}
+	if proxied != "http://provider.invalid/send" {
+		t.Fatalf("expected an absolute-form request at the proxy, got %q", proxied)
+	}
+}
+
+func TestNewHTTPClient_Invalid(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]Transport{
+		"cert without key": {CertFile: "client.pem"},
+		"missing cert":     {CertFile: "/nonexistent.pem", KeyFile: "/nonexistent.key"},
+		"missing ca":       {CAFile: "/nonexistent.pem"},
+		"empty ca":         {CAFile: writeTemp(t, "empty.pem", []byte("not a cert"))},
+	}
+	for name, tr := range cases {
+		if _, err := NewHTTPClient(tr); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
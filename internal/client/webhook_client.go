@@ -8,66 +8,117 @@ import (
 	"io"
 	"net/http"
 	"time"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+	"github.com/LeventeLantos/automatic-messaging/internal/service"
+	"github.com/LeventeLantos/automatic-messaging/internal/tracing"
 )
 
 type WebhookClient struct {
-	url    string
-	client *http.Client
+	url     string
+	client  *http.Client
+	auth    Auth
+	mapping Mapping
+	status  StatusLookup
+	observe Observer
+	now     func() time.Time
 }
 
+// Observer is told about every send request that reached the transport, with
+// the result (StatusCode is 0 when no response arrived), latency and error.
+type Observer func(res model.SendResult, d time.Duration, err error)
+
 func NewWebhookClient(url string) *WebhookClient {
 	return &WebhookClient{
 		url: url,
 		client: &http.Client{
-			Timeout: 10 * time.Second,
+			Timeout: defaultTimeout,
 		},
+		auth: Auth{Mode: AuthNone},
+		now:  time.Now,
 	}
 }
 
+func (c *WebhookClient) WithMapping(m Mapping) *WebhookClient {
+	c.mapping = m
+	return c
+}
+
+func (c *WebhookClient) WithAuth(auth Auth) *WebhookClient {
+	c.auth = auth
+	return c
+}
+
+// WithObserver registers o, e.g. to export provider latency metrics.
+func (c *WebhookClient) WithObserver(o Observer) *WebhookClient {
+	c.observe = o
+	return c
+}
+
 type sendRequest struct {
 	To      string `json:"to"`
 	Content string `json:"content"`
+	From    string `json:"from,omitempty"`
 }
 
-type sendResponse struct {
-	Message   string `json:"message"`
-	MessageID string `json:"messageId"`
-}
-
-func (c *WebhookClient) Send(ctx context.Context, phoneNumber, message string) (string, error) {
-	reqBody, err := json.Marshal(sendRequest{
+func (c *WebhookClient) Send(ctx context.Context, phoneNumber, message string) (model.SendResult, error) {
+	reqBody, contentType, err := c.mapping.encode(TemplateData{
 		To:      phoneNumber,
 		Content: message,
+		From:    service.SenderID(ctx),
 	})
 	if err != nil {
-		return "", err
+		return model.SendResult{}, err
 	}
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(reqBody))
 	if err != nil {
-		return "", err
+		return model.SendResult{}, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if key := service.IdempotencyKey(ctx); key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	tracing.Inject(ctx, req.Header)
+	c.auth.apply(req, reqBody, c.now())
+
+	start := time.Now()
+	res, err := c.do(req)
+	if c.observe != nil {
+		c.observe(res, time.Since(start), err)
 	}
-	req.Header.Set("Content-Type", "application/json")
+	return res, err
+}
 
+func (c *WebhookClient) do(req *http.Request) (model.SendResult, error) {
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return "", err
+		return model.SendResult{}, err
 	}
 	defer resp.Body.Close()
 
 	body, _ := io.ReadAll(resp.Body)
+	res := model.SendResult{
+		StatusCode: resp.StatusCode,
+		Body:       string(body),
+	}
 
-	if resp.StatusCode != http.StatusAccepted {
-		return "", fmt.Errorf("unexpected status code: %d body=%q", resp.StatusCode, string(body))
+	if !c.mapping.accepts(resp.StatusCode) {
+		return res, fmt.Errorf("unexpected status code: %d body=%q", resp.StatusCode, string(body))
 	}
 
-	var sr sendResponse
-	if err := json.Unmarshal(body, &sr); err != nil {
-		return "", fmt.Errorf("failed to decode json: %w body=%q", err, string(body))
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var decoded any
+	if err := dec.Decode(&decoded); err != nil {
+		return res, fmt.Errorf("failed to decode json: %w body=%q", err, string(body))
 	}
-	if sr.MessageID == "" {
-		return "", fmt.Errorf("missing messageId in response body=%q", string(body))
+
+	remoteID := c.mapping.remoteID(decoded)
+	if remoteID == "" {
+		return res, fmt.Errorf("missing %s in response body=%q", c.mapping.pathName(), string(body))
 	}
 
-	return sr.MessageID, nil
+	res.RemoteMessageID = remoteID
+	return res, nil
 }
@@ -9,6 +9,11 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+	"github.com/LeventeLantos/automatic-messaging/internal/service"
+	"github.com/LeventeLantos/automatic-messaging/internal/tracing"
+	"github.com/LeventeLantos/automatic-messaging/internal/tracing/tracingtest"
 )
 
 func TestWebhookClient_Send_Success(t *testing.T) {
@@ -40,12 +45,15 @@ func TestWebhookClient_Send_Success(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
 
-	msgID, err := c.Send(ctx, "+361234567", "hello")
+	res, err := c.Send(ctx, "+361234567", "hello")
 	if err != nil {
 		t.Fatalf("Send() error: %v", err)
 	}
-	if msgID != "abc-123" {
-		t.Fatalf("expected messageId %q, got %q", "abc-123", msgID)
+	if res.RemoteMessageID != "abc-123" {
+		t.Fatalf("expected messageId %q, got %q", "abc-123", res.RemoteMessageID)
+	}
+	if res.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected status %d, got %d", http.StatusAccepted, res.StatusCode)
 	}
 
 	if captured.Method != http.MethodPost {
@@ -78,10 +86,13 @@ func TestWebhookClient_Send_Non202_ReturnsErrorWithBody(t *testing.T) {
 
 	c := NewWebhookClient(srv.URL)
 
-	_, err := c.Send(context.Background(), "+361", "hi")
+	res, err := c.Send(context.Background(), "+361", "hi")
 	if err == nil {
 		t.Fatalf("expected error, got nil")
 	}
+	if res.StatusCode != http.StatusOK || res.Body != "not accepted" {
+		t.Fatalf("expected result to carry the response, got %+v", res)
+	}
 
 	msg := err.Error()
 	if !strings.Contains(msg, "unexpected status code: 200") {
@@ -137,6 +148,32 @@ func TestWebhookClient_Send_MissingMessageId_ReturnsError(t *testing.T) {
 	}
 }
 
+func TestWebhookClient_Send_IdempotencyKeyHeader(t *testing.T) {
+	t.Parallel()
+
+	var keys []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"messageId":"abc"}`))
+	}))
+	defer srv.Close()
+
+	c := NewWebhookClient(srv.URL)
+
+	if _, err := c.Send(context.Background(), "+361", "hi"); err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+	ctx := service.WithIdempotencyKey(context.Background(), "msg-7-1")
+	if _, err := c.Send(ctx, "+361", "hi"); err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+
+	if len(keys) != 2 || keys[0] != "" || keys[1] != "msg-7-1" {
+		t.Fatalf("expected the header only when a key is set, got %q", keys)
+	}
+}
+
 func TestWebhookClient_Send_ContextCanceled(t *testing.T) {
 	t.Parallel()
 
@@ -165,6 +202,58 @@ func TestWebhookClient_Send_ContextCanceled(t *testing.T) {
 	}
 }
 
+func TestWebhookClient_Send_Observer(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	var (
+		calls  int
+		got    model.SendResult
+		gotErr error
+	)
+	c := NewWebhookClient(srv.URL).WithObserver(func(res model.SendResult, d time.Duration, err error) {
+		calls++
+		got, gotErr = res, err
+	})
+
+	_, err := c.Send(context.Background(), "+361", "hi")
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+	if calls != 1 || got.StatusCode != http.StatusServiceUnavailable || gotErr != err {
+		t.Fatalf("expected one observation of the 503, got calls=%d res=%+v err=%v", calls, got, gotErr)
+	}
+}
+
+func TestWebhookClient_Send_PropagatesTraceparent(t *testing.T) {
+	tracingtest.Record(t)
+
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"messageId":"abc"}`))
+	}))
+	defer srv.Close()
+
+	ctx, span := tracing.Start(context.Background(), "Send")
+	defer span.End()
+
+	if _, err := NewWebhookClient(srv.URL).Send(ctx, "+361", "hi"); err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+
+	sc := span.SpanContext()
+	want := "00-" + sc.TraceID().String() + "-" + sc.SpanID().String() + "-01"
+	if got != want {
+		t.Fatalf("expected traceparent %q, got %q", want, got)
+	}
+}
+
 func ioReadAll(r *http.Request) ([]byte, error) {
 	defer r.Body.Close()
 	return io.ReadAll(r.Body)
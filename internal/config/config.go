@@ -1,23 +1,41 @@
 package config
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
+	"log/slog"
+	"net/url"
 	"os"
+	"regexp"
 	"strconv"
+	"strings"
 	"time"
 )
 
 type Config struct {
+	Log       LogConfig
 	Server    ServerConfig
 	Database  DatabaseConfig
 	Redis     RedisConfig
 	Scheduler SchedulerConfig
 	Webhook   WebhookConfig
+	Receipts  ReceiptConfig
+	Events    EventsConfig
+	Breaker   BreakerConfig
+	Reconcile ReconcileConfig
+	Tracing   TracingConfig
+	Stats     StatsConfig
+	Alerts    AlertConfig
+	Health    HealthConfig
+	Redact    RedactConfig
+	Auth      AuthConfig
 }
 
 type ServerConfig struct {
-	Address string
+	Address    string
+	InstanceID string
 }
 
 type DatabaseConfig struct {
@@ -32,25 +50,259 @@ type RedisConfig struct {
 	TTL      time.Duration
 }
 
+// SchedulerConfig controls the send loop. Messages left in processing or
+// sending for longer than StuckAfter are returned to pending every
+// ReconcileInterval.
 type SchedulerConfig struct {
 	Interval  time.Duration
 	BatchSize int
+
+	StuckAfter        time.Duration
+	ReconcileInterval time.Duration
 }
 
+// WebhookConfig holds the outbound provider settings. URL, Auth, Mapping and
+// Transport describe the single default provider; when WEBHOOK_PROVIDERS names
+// several providers they are configured in Providers instead (see
+// ProviderList).
 type WebhookConfig struct {
 	URL        string
 	ContentMax int
+	Auth       WebhookAuthConfig
+	Mapping    WebhookMappingConfig
+	Transport  WebhookTransportConfig
+
+	Providers         []ProviderConfig
+	UnhealthyAfter    int
+	UnhealthyCooldown time.Duration
+	RoutesRefresh     time.Duration
 }
 
-func LoadAll() (*Config, error) {
+// ProviderConfig is one named provider. Its settings use the env prefix
+// WEBHOOK_<NAME>_, e.g. WEBHOOK_BACKUP_URL or WEBHOOK_BACKUP_AUTH. Priority
+// defaults to the provider's position in WEBHOOK_PROVIDERS.
+type ProviderConfig struct {
+	Name      string
+	URL       string
+	Priority  int
+	Weight    int
+	Auth      WebhookAuthConfig
+	Mapping   WebhookMappingConfig
+	Transport WebhookTransportConfig
+}
+
+// DefaultProvider names the provider built from the plain WEBHOOK_ settings.
+const DefaultProvider = "default"
+
+// ProviderList returns the named providers, or the default provider when
+// WEBHOOK_PROVIDERS is unset.
+func (w WebhookConfig) ProviderList() []ProviderConfig {
+	if len(w.Providers) > 0 {
+		return w.Providers
+	}
+	return []ProviderConfig{{
+		Name:      DefaultProvider,
+		URL:       w.URL,
+		Weight:    1,
+		Auth:      w.Auth,
+		Mapping:   w.Mapping,
+		Transport: w.Transport,
+	}}
+}
+
+// WebhookMappingConfig describes the provider's request and response shape.
+// An empty BodyTemplate keeps the default {"to","content"} JSON body. An
+// empty StatusURL disables provider status lookups.
+type WebhookMappingConfig struct {
+	BodyTemplate     string
+	ContentType      string
+	AcceptedStatuses []int
+	RemoteIDPath     string
+
+	StatusURL    string
+	StatusPath   string
+	StatusValues map[string]string
+}
+
+// HasStatusLookup reports whether any provider can be asked about messages.
+func (w WebhookConfig) HasStatusLookup() bool {
+	for _, p := range w.ProviderList() {
+		if p.Mapping.StatusURL != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookTransportConfig controls the provider connection: an optional client
+// certificate for mTLS, a CA bundle replacing the system roots, an explicit
+// proxy (otherwise HTTP_PROXY/HTTPS_PROXY apply), timeouts, idle connection
+// limits and HTTP/2.
+type WebhookTransportConfig struct {
+	CertFile string
+	KeyFile  string
+	CAFile   string
+	ProxyURL string
+
+	Timeout             time.Duration
+	DialTimeout         time.Duration
+	TLSHandshakeTimeout time.Duration
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	HTTP2               bool
+}
+
+// WebhookAuthConfig selects how outbound webhook requests authenticate. Mode
+// is one of none, bearer, basic or hmac; Headers are sent in every mode.
+type WebhookAuthConfig struct {
+	Mode                string
+	Headers             map[string]string
+	BearerToken         string
+	BasicUser           string
+	BasicPassword       string
+	HMACSecret          string
+	HMACHeader          string
+	HMACTimestampHeader string
+	HMACTolerance       time.Duration
+}
+
+// ReceiptConfig controls inbound delivery receipts. Receipts are rejected
+// while Secret is empty.
+type ReceiptConfig struct {
+	Secret        string
+	Tolerance     time.Duration
+	Timeout       time.Duration
+	SweepInterval time.Duration
+}
+
+// EventsConfig controls delivery of status-change events to subscribers and
+// to the optional event sinks ("file", "redis") listed in Sinks.
+type EventsConfig struct {
+	DispatchInterval  time.Duration
+	DispatchBatchSize int
+	MaxAttempts       int
+	BackoffBase       time.Duration
+	BackoffMax        time.Duration
+
+	Sinks          []string
+	FilePath       string
+	FileMaxBytes   int64
+	FileMaxBackups int
+	StreamKey      string
+	StreamMaxLen   int64
+}
+
+// ReconcileConfig controls provider status lookups by the reconciler, which
+// runs every SCHED_RECONCILE_INTERVAL_SECONDS. Sent messages without a
+// receipt after ReceiptAfter are looked up at most once per RecheckAfter.
+type ReconcileConfig struct {
+	BatchSize    int
+	ReceiptAfter time.Duration
+	RecheckAfter time.Duration
+}
+
+// BreakerConfig controls the circuit breaker around provider sends. It opens
+// when at least MinRequests sends finished within Window and FailureRatio of
+// them failed, and probes the provider again after OpenFor.
+type BreakerConfig struct {
+	Enabled      bool
+	FailureRatio float64
+	MinRequests  int
+	Window       time.Duration
+	OpenFor      time.Duration
+}
+
+// StatsConfig holds the SLO thresholds reported by GET /v1/stats. Zero
+// disables a check. CompactInterval is how often the per-status count deltas
+// are folded into the counters.
+type StatsConfig struct {
+	SLOOldestPending  time.Duration
+	SLOFailureRatio   float64
+	SLOSendLatencyP50 time.Duration
+	SLOSendLatencyP95 time.Duration
+	CompactInterval   time.Duration
+}
+
+// AlertConfig defines the alert rules and the webhook alerts are POSTed to.
+// Alerting is off while WebhookURL is empty; a zero threshold disables a rule.
+type AlertConfig struct {
+	WebhookURL       string
+	Secret           string
+	FailureRatio     float64
+	FailureTicks     int
+	OldestPending    time.Duration
+	SchedulerStopped time.Duration
+	Interval         time.Duration
+}
+
+// LogConfig selects the log output: Format is "text" or "json" and Level one
+// of debug, info, warn or error.
+type LogConfig struct {
+	Format string
+	Level  slog.Level
+}
+
+// AuthConfig controls API key authentication. Authenticated keys are cached
+// for CacheTTL, which bounds how long a revoked key keeps working.
+type AuthConfig struct {
+	Enabled  bool
+	CacheTTL time.Duration
+}
+
+// RedactConfig controls what API clients without the pii:read scope see:
+// masked phone numbers when Phones is set, and content as-is ("plain") or as
+// its SHA-256 ("hash").
+type RedactConfig struct {
+	Phones  bool
+	Content string
+}
+
+// HealthConfig tunes GET /v1/health/ready. The scheduler is reported down
+// once it runs without finishing a tick for SchedulerStale; zero means three
+// scheduler intervals.
+type HealthConfig struct {
+	CheckTimeout   time.Duration
+	SchedulerStale time.Duration
+}
+
+// TracingConfig controls OpenTelemetry tracing. Spans are exported over
+// OTLP/HTTP to Endpoint, or to the target of the standard
+// OTEL_EXPORTER_OTLP_* variables when Endpoint is empty.
+type TracingConfig struct {
+	Enabled     bool
+	Endpoint    string
+	ServiceName string
+	SampleRatio float64
+}
+
+const (
+	EventSinkFile  = "file"
+	EventSinkRedis = "redis"
+)
+
+// LoadDatabase reads only the database settings, for commands that need
+// nothing else.
+func LoadDatabase() (DatabaseConfig, error) {
 	pgURL, err := requireEnv("POSTGRES_URL")
 	if err != nil {
-		return nil, err
+		return DatabaseConfig{}, err
 	}
-	webhookURL, err := requireEnv("WEBHOOK_URL")
+	return DatabaseConfig{PostgresURL: pgURL}, nil
+}
+
+func LoadAll() (*Config, error) {
+	dbCfg, err := LoadDatabase()
 	if err != nil {
 		return nil, err
 	}
+	providerNames := getEnvList("WEBHOOK_PROVIDERS")
+	webhookURL := os.Getenv("WEBHOOK_URL")
+	if len(providerNames) == 0 {
+		webhookURL, err = requireEnv("WEBHOOK_URL")
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	contentMax, err := getEnvInt("CONTENT_MAX", 160)
 	if err != nil {
@@ -67,27 +319,159 @@ func LoadAll() (*Config, error) {
 		return nil, err
 	}
 
+	stuckAfterSeconds, err := getEnvInt("SCHED_STUCK_AFTER_SECONDS", 300)
+	if err != nil {
+		return nil, err
+	}
+
+	reconcileSeconds, err := getEnvInt("SCHED_RECONCILE_INTERVAL_SECONDS", 60)
+	if err != nil {
+		return nil, err
+	}
+
+	authCfg, err := loadWebhookAuthConfig(defaultProviderPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	mappingCfg, err := loadWebhookMappingConfig(defaultProviderPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	transportCfg, err := loadWebhookTransportConfig(defaultProviderPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	providers, err := loadProviders(providerNames)
+	if err != nil {
+		return nil, err
+	}
+
+	unhealthyAfter, err := getEnvInt("WEBHOOK_UNHEALTHY_AFTER", 3)
+	if err != nil {
+		return nil, err
+	}
+
+	unhealthyCooldownSeconds, err := getEnvInt("WEBHOOK_UNHEALTHY_COOLDOWN_SECONDS", 30)
+	if err != nil {
+		return nil, err
+	}
+
+	routesRefreshSeconds, err := getEnvInt("ROUTES_REFRESH_SECONDS", 30)
+	if err != nil {
+		return nil, err
+	}
+
 	redisCfg, err := loadRedisConfig()
 	if err != nil {
 		return nil, err
 	}
 
+	receiptCfg, err := loadReceiptConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	eventsCfg, err := loadEventsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	breakerCfg, err := loadBreakerConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	reconcileCfg, err := loadReconcileConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	tracingCfg, err := loadTracingConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	statsCfg, err := loadStatsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	alertCfg, err := loadAlertConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	healthCfg, err := loadHealthConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	logCfg, err := loadLogConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	redactPhones, err := getEnvBool("REDACT_PHONES", true)
+	if err != nil {
+		return nil, err
+	}
+
+	authEnabled, err := getEnvBool("AUTH_ENABLED", true)
+	if err != nil {
+		return nil, err
+	}
+
+	authCacheSeconds, err := getEnvInt("AUTH_CACHE_SECONDS", 30)
+	if err != nil {
+		return nil, err
+	}
+
 	cfg := &Config{
+		Log: logCfg,
 		Server: ServerConfig{
-			Address: getEnv("SERVER_ADDRESS", ":8080"),
-		},
-		Database: DatabaseConfig{
-			PostgresURL: pgURL,
+			Address:    getEnv("SERVER_ADDRESS", ":8080"),
+			InstanceID: getEnv("INSTANCE_ID", defaultInstanceID()),
 		},
+		Database: dbCfg,
 		Webhook: WebhookConfig{
 			URL:        webhookURL,
 			ContentMax: contentMax,
+			Auth:       authCfg,
+			Mapping:    mappingCfg,
+			Transport:  transportCfg,
+
+			Providers:         providers,
+			UnhealthyAfter:    unhealthyAfter,
+			UnhealthyCooldown: time.Duration(unhealthyCooldownSeconds) * time.Second,
+			RoutesRefresh:     time.Duration(routesRefreshSeconds) * time.Second,
 		},
 		Scheduler: SchedulerConfig{
 			Interval:  time.Duration(intervalSeconds) * time.Second,
 			BatchSize: batchSize,
+
+			StuckAfter:        time.Duration(stuckAfterSeconds) * time.Second,
+			ReconcileInterval: time.Duration(reconcileSeconds) * time.Second,
+		},
+		Redis:     redisCfg,
+		Receipts:  receiptCfg,
+		Events:    eventsCfg,
+		Breaker:   breakerCfg,
+		Reconcile: reconcileCfg,
+		Tracing:   tracingCfg,
+		Stats:     statsCfg,
+		Alerts:    alertCfg,
+		Health:    healthCfg,
+		Redact: RedactConfig{
+			Phones:  redactPhones,
+			Content: strings.ToLower(getEnv("REDACT_CONTENT", "plain")),
+		},
+		Auth: AuthConfig{
+			Enabled:  authEnabled,
+			CacheTTL: time.Duration(authCacheSeconds) * time.Second,
 		},
-		Redis: redisCfg,
 	}
 
 	if err := validate(cfg); err != nil {
@@ -97,6 +481,197 @@ func LoadAll() (*Config, error) {
 	return cfg, nil
 }
 
+const defaultProviderPrefix = "WEBHOOK_"
+
+// providerPrefix maps a provider name to its env prefix: "eu-backup" reads
+// WEBHOOK_EU_BACKUP_*.
+func providerPrefix(name string) string {
+	return defaultProviderPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+}
+
+func loadProviders(names []string) ([]ProviderConfig, error) {
+	var out []ProviderConfig
+	for i, name := range names {
+		prefix := providerPrefix(name)
+
+		priority, err := getEnvInt(prefix+"PRIORITY", i)
+		if err != nil {
+			return nil, err
+		}
+
+		weight, err := getEnvInt(prefix+"WEIGHT", 1)
+		if err != nil {
+			return nil, err
+		}
+
+		auth, err := loadWebhookAuthConfig(prefix)
+		if err != nil {
+			return nil, err
+		}
+
+		mapping, err := loadWebhookMappingConfig(prefix)
+		if err != nil {
+			return nil, err
+		}
+
+		transport, err := loadWebhookTransportConfig(prefix)
+		if err != nil {
+			return nil, err
+		}
+
+		out = append(out, ProviderConfig{
+			Name:      name,
+			URL:       os.Getenv(prefix + "URL"),
+			Priority:  priority,
+			Weight:    weight,
+			Auth:      auth,
+			Mapping:   mapping,
+			Transport: transport,
+		})
+	}
+	return out, nil
+}
+
+func loadWebhookAuthConfig(prefix string) (WebhookAuthConfig, error) {
+	headerList, err := getSecret(prefix + "HEADERS")
+	if err != nil {
+		return WebhookAuthConfig{}, err
+	}
+	headers, err := parseHeaders(prefix+"HEADERS", headerList)
+	if err != nil {
+		return WebhookAuthConfig{}, err
+	}
+
+	token, err := getSecret(prefix + "BEARER_TOKEN")
+	if err != nil {
+		return WebhookAuthConfig{}, err
+	}
+
+	password, err := getSecret(prefix + "BASIC_PASSWORD")
+	if err != nil {
+		return WebhookAuthConfig{}, err
+	}
+
+	hmacSecret, err := getSecret(prefix + "HMAC_SECRET")
+	if err != nil {
+		return WebhookAuthConfig{}, err
+	}
+
+	toleranceSeconds, err := getEnvInt(prefix+"HMAC_TOLERANCE_SECONDS", 300)
+	if err != nil {
+		return WebhookAuthConfig{}, err
+	}
+
+	return WebhookAuthConfig{
+		Mode:                getEnv(prefix+"AUTH", "none"),
+		Headers:             headers,
+		BearerToken:         token,
+		BasicUser:           os.Getenv(prefix + "BASIC_USER"),
+		BasicPassword:       password,
+		HMACSecret:          hmacSecret,
+		HMACHeader:          getEnv(prefix+"HMAC_HEADER", "X-Signature"),
+		HMACTimestampHeader: getEnv(prefix+"HMAC_TIMESTAMP_HEADER", "X-Signature-Timestamp"),
+		HMACTolerance:       time.Duration(toleranceSeconds) * time.Second,
+	}, nil
+}
+
+func loadWebhookMappingConfig(prefix string) (WebhookMappingConfig, error) {
+	// Templates may embed provider credentials, so they load like secrets.
+	bodyTemplate, err := getSecret(prefix + "BODY_TEMPLATE")
+	if err != nil {
+		return WebhookMappingConfig{}, err
+	}
+
+	var statuses []int
+	for _, item := range getEnvList(prefix + "ACCEPTED_STATUSES") {
+		code, err := strconv.Atoi(item)
+		if err != nil {
+			return WebhookMappingConfig{}, fmt.Errorf("invalid int for env %sACCEPTED_STATUSES: %q", prefix, item)
+		}
+		statuses = append(statuses, code)
+	}
+	if len(statuses) == 0 {
+		statuses = []int{202}
+	}
+
+	// Status URLs often carry an API key in the query string.
+	statusURL, err := getSecret(prefix + "STATUS_URL")
+	if err != nil {
+		return WebhookMappingConfig{}, err
+	}
+
+	statusValues := map[string]string{}
+	for i, item := range getEnvList(prefix + "STATUS_VALUES") {
+		raw, status, ok := strings.Cut(item, ":")
+		raw, status = strings.TrimSpace(raw), strings.TrimSpace(status)
+		if !ok || raw == "" {
+			return WebhookMappingConfig{}, fmt.Errorf("%sSTATUS_VALUES: entry %d is not \"value:status\"", prefix, i+1)
+		}
+		statusValues[raw] = status
+	}
+
+	return WebhookMappingConfig{
+		BodyTemplate:     bodyTemplate,
+		ContentType:      getEnv(prefix+"CONTENT_TYPE", "json"),
+		AcceptedStatuses: statuses,
+		RemoteIDPath:     getEnv(prefix+"REMOTE_ID_PATH", "messageId"),
+		StatusURL:        statusURL,
+		StatusPath:       getEnv(prefix+"STATUS_PATH", "status"),
+		StatusValues:     statusValues,
+	}, nil
+}
+
+func loadWebhookTransportConfig(prefix string) (WebhookTransportConfig, error) {
+	timeoutSeconds, err := getEnvInt(prefix+"TIMEOUT_SECONDS", 10)
+	if err != nil {
+		return WebhookTransportConfig{}, err
+	}
+
+	dialSeconds, err := getEnvInt(prefix+"DIAL_TIMEOUT_SECONDS", 5)
+	if err != nil {
+		return WebhookTransportConfig{}, err
+	}
+
+	tlsSeconds, err := getEnvInt(prefix+"TLS_HANDSHAKE_TIMEOUT_SECONDS", 5)
+	if err != nil {
+		return WebhookTransportConfig{}, err
+	}
+
+	maxIdle, err := getEnvInt(prefix+"MAX_IDLE_CONNS", 100)
+	if err != nil {
+		return WebhookTransportConfig{}, err
+	}
+
+	maxIdlePerHost, err := getEnvInt(prefix+"MAX_IDLE_CONNS_PER_HOST", 10)
+	if err != nil {
+		return WebhookTransportConfig{}, err
+	}
+
+	http2, err := getEnvBool(prefix+"HTTP2", true)
+	if err != nil {
+		return WebhookTransportConfig{}, err
+	}
+
+	// Proxy URLs may embed credentials.
+	proxyURL, err := getSecret(prefix + "PROXY_URL")
+	if err != nil {
+		return WebhookTransportConfig{}, err
+	}
+
+	return WebhookTransportConfig{
+		CertFile:            os.Getenv(prefix + "TLS_CERT_FILE"),
+		KeyFile:             os.Getenv(prefix + "TLS_KEY_FILE"),
+		CAFile:              os.Getenv(prefix + "TLS_CA_FILE"),
+		ProxyURL:            proxyURL,
+		Timeout:             time.Duration(timeoutSeconds) * time.Second,
+		DialTimeout:         time.Duration(dialSeconds) * time.Second,
+		TLSHandshakeTimeout: time.Duration(tlsSeconds) * time.Second,
+		MaxIdleConns:        maxIdle,
+		MaxIdleConnsPerHost: maxIdlePerHost,
+		HTTP2:               http2,
+	}, nil
+}
+
 func loadRedisConfig() (RedisConfig, error) {
 	addr := os.Getenv("REDIS_ADDR")
 	if addr == "" {
@@ -122,6 +697,279 @@ func loadRedisConfig() (RedisConfig, error) {
 	}, nil
 }
 
+func loadReceiptConfig() (ReceiptConfig, error) {
+	toleranceSeconds, err := getEnvInt("DLR_SIGNATURE_TOLERANCE_SECONDS", 300)
+	if err != nil {
+		return ReceiptConfig{}, err
+	}
+
+	timeoutHours, err := getEnvInt("DLR_TIMEOUT_HOURS", 48)
+	if err != nil {
+		return ReceiptConfig{}, err
+	}
+
+	sweepSeconds, err := getEnvInt("DLR_SWEEP_INTERVAL_SECONDS", 600)
+	if err != nil {
+		return ReceiptConfig{}, err
+	}
+
+	secret, err := getSecret("DLR_SECRET")
+	if err != nil {
+		return ReceiptConfig{}, err
+	}
+
+	return ReceiptConfig{
+		Secret:        secret,
+		Tolerance:     time.Duration(toleranceSeconds) * time.Second,
+		Timeout:       time.Duration(timeoutHours) * time.Hour,
+		SweepInterval: time.Duration(sweepSeconds) * time.Second,
+	}, nil
+}
+
+func loadEventsConfig() (EventsConfig, error) {
+	intervalSeconds, err := getEnvInt("EVENTS_DISPATCH_INTERVAL_SECONDS", 5)
+	if err != nil {
+		return EventsConfig{}, err
+	}
+
+	batchSize, err := getEnvInt("EVENTS_DISPATCH_BATCH_SIZE", 50)
+	if err != nil {
+		return EventsConfig{}, err
+	}
+
+	maxAttempts, err := getEnvInt("EVENTS_MAX_ATTEMPTS", 8)
+	if err != nil {
+		return EventsConfig{}, err
+	}
+
+	backoffBaseSeconds, err := getEnvInt("EVENTS_BACKOFF_BASE_SECONDS", 10)
+	if err != nil {
+		return EventsConfig{}, err
+	}
+
+	backoffMaxSeconds, err := getEnvInt("EVENTS_BACKOFF_MAX_SECONDS", 3600)
+	if err != nil {
+		return EventsConfig{}, err
+	}
+
+	fileMaxMB, err := getEnvInt("EVENT_FILE_MAX_MB", 100)
+	if err != nil {
+		return EventsConfig{}, err
+	}
+
+	fileMaxBackups, err := getEnvInt("EVENT_FILE_MAX_BACKUPS", 5)
+	if err != nil {
+		return EventsConfig{}, err
+	}
+
+	streamMaxLen, err := getEnvInt("EVENT_STREAM_MAXLEN", 100000)
+	if err != nil {
+		return EventsConfig{}, err
+	}
+
+	return EventsConfig{
+		DispatchInterval:  time.Duration(intervalSeconds) * time.Second,
+		DispatchBatchSize: batchSize,
+		MaxAttempts:       maxAttempts,
+		BackoffBase:       time.Duration(backoffBaseSeconds) * time.Second,
+		BackoffMax:        time.Duration(backoffMaxSeconds) * time.Second,
+		Sinks:             getEnvList("EVENT_SINKS"),
+		FilePath:          getEnv("EVENT_FILE_PATH", "events.ndjson"),
+		FileMaxBytes:      int64(fileMaxMB) << 20,
+		FileMaxBackups:    fileMaxBackups,
+		StreamKey:         getEnv("EVENT_STREAM_KEY", "message-events"),
+		StreamMaxLen:      int64(streamMaxLen),
+	}, nil
+}
+
+func loadBreakerConfig() (BreakerConfig, error) {
+	enabled, err := getEnvBool("BREAKER_ENABLED", true)
+	if err != nil {
+		return BreakerConfig{}, err
+	}
+
+	ratio, err := getEnvFloat("BREAKER_FAILURE_RATIO", 0.5)
+	if err != nil {
+		return BreakerConfig{}, err
+	}
+
+	minRequests, err := getEnvInt("BREAKER_MIN_REQUESTS", 5)
+	if err != nil {
+		return BreakerConfig{}, err
+	}
+
+	windowSeconds, err := getEnvInt("BREAKER_WINDOW_SECONDS", 300)
+	if err != nil {
+		return BreakerConfig{}, err
+	}
+
+	openSeconds, err := getEnvInt("BREAKER_OPEN_SECONDS", 60)
+	if err != nil {
+		return BreakerConfig{}, err
+	}
+
+	return BreakerConfig{
+		Enabled:      enabled,
+		FailureRatio: ratio,
+		MinRequests:  minRequests,
+		Window:       time.Duration(windowSeconds) * time.Second,
+		OpenFor:      time.Duration(openSeconds) * time.Second,
+	}, nil
+}
+
+func loadStatsConfig() (StatsConfig, error) {
+	oldestSeconds, err := getEnvInt("STATS_SLO_OLDEST_PENDING_SECONDS", 600)
+	if err != nil {
+		return StatsConfig{}, err
+	}
+
+	ratio, err := getEnvFloat("STATS_SLO_FAILURE_RATIO", 0.05)
+	if err != nil {
+		return StatsConfig{}, err
+	}
+
+	p50Seconds, err := getEnvInt("STATS_SLO_SEND_P50_SECONDS", 300)
+	if err != nil {
+		return StatsConfig{}, err
+	}
+
+	p95Seconds, err := getEnvInt("STATS_SLO_SEND_P95_SECONDS", 900)
+	if err != nil {
+		return StatsConfig{}, err
+	}
+
+	compactSeconds, err := getEnvInt("STATS_COMPACT_INTERVAL_SECONDS", 60)
+	if err != nil {
+		return StatsConfig{}, err
+	}
+
+	return StatsConfig{
+		SLOOldestPending:  time.Duration(oldestSeconds) * time.Second,
+		SLOFailureRatio:   ratio,
+		SLOSendLatencyP50: time.Duration(p50Seconds) * time.Second,
+		SLOSendLatencyP95: time.Duration(p95Seconds) * time.Second,
+		CompactInterval:   time.Duration(compactSeconds) * time.Second,
+	}, nil
+}
+
+func loadAlertConfig() (AlertConfig, error) {
+	webhookURL, err := getSecret("ALERT_WEBHOOK_URL")
+	if err != nil {
+		return AlertConfig{}, err
+	}
+
+	secret, err := getSecret("ALERT_WEBHOOK_SECRET")
+	if err != nil {
+		return AlertConfig{}, err
+	}
+
+	ratio, err := getEnvFloat("ALERT_FAILURE_RATIO", 0.5)
+	if err != nil {
+		return AlertConfig{}, err
+	}
+
+	ticks, err := getEnvInt("ALERT_FAILURE_TICKS", 5)
+	if err != nil {
+		return AlertConfig{}, err
+	}
+
+	oldestMinutes, err := getEnvInt("ALERT_OLDEST_PENDING_MINUTES", 30)
+	if err != nil {
+		return AlertConfig{}, err
+	}
+
+	stoppedMinutes, err := getEnvInt("ALERT_SCHEDULER_STOPPED_MINUTES", 10)
+	if err != nil {
+		return AlertConfig{}, err
+	}
+
+	intervalSeconds, err := getEnvInt("ALERT_EVAL_INTERVAL_SECONDS", 60)
+	if err != nil {
+		return AlertConfig{}, err
+	}
+
+	return AlertConfig{
+		WebhookURL:       webhookURL,
+		Secret:           secret,
+		FailureRatio:     ratio,
+		FailureTicks:     ticks,
+		OldestPending:    time.Duration(oldestMinutes) * time.Minute,
+		SchedulerStopped: time.Duration(stoppedMinutes) * time.Minute,
+		Interval:         time.Duration(intervalSeconds) * time.Second,
+	}, nil
+}
+
+func loadLogConfig() (LogConfig, error) {
+	var level slog.Level
+	if v := getEnv("LOG_LEVEL", "info"); level.UnmarshalText([]byte(v)) != nil {
+		return LogConfig{}, fmt.Errorf("invalid log level for env LOG_LEVEL: %q", v)
+	}
+
+	return LogConfig{
+		Format: strings.ToLower(getEnv("LOG_FORMAT", "text")),
+		Level:  level,
+	}, nil
+}
+
+func loadHealthConfig() (HealthConfig, error) {
+	timeoutSeconds, err := getEnvInt("HEALTH_CHECK_TIMEOUT_SECONDS", 2)
+	if err != nil {
+		return HealthConfig{}, err
+	}
+
+	staleSeconds, err := getEnvInt("HEALTH_SCHEDULER_STALE_SECONDS", 0)
+	if err != nil {
+		return HealthConfig{}, err
+	}
+
+	return HealthConfig{
+		CheckTimeout:   time.Duration(timeoutSeconds) * time.Second,
+		SchedulerStale: time.Duration(staleSeconds) * time.Second,
+	}, nil
+}
+
+func loadTracingConfig() (TracingConfig, error) {
+	enabled, err := getEnvBool("TRACING_ENABLED", false)
+	if err != nil {
+		return TracingConfig{}, err
+	}
+
+	ratio, err := getEnvFloat("TRACING_SAMPLE_RATIO", 1)
+	if err != nil {
+		return TracingConfig{}, err
+	}
+
+	return TracingConfig{
+		Enabled:     enabled,
+		Endpoint:    os.Getenv("TRACING_ENDPOINT"),
+		ServiceName: getEnv("TRACING_SERVICE_NAME", "automatic-messaging"),
+		SampleRatio: ratio,
+	}, nil
+}
+
+func loadReconcileConfig() (ReconcileConfig, error) {
+	batchSize, err := getEnvInt("RECONCILE_BATCH_SIZE", 100)
+	if err != nil {
+		return ReconcileConfig{}, err
+	}
+
+	receiptAfterSeconds, err := getEnvInt("RECONCILE_RECEIPT_AFTER_SECONDS", 3600)
+	if err != nil {
+		return ReconcileConfig{}, err
+	}
+
+	recheckSeconds, err := getEnvInt("RECONCILE_RECHECK_SECONDS", 3600)
+	if err != nil {
+		return ReconcileConfig{}, err
+	}
+
+	return ReconcileConfig{
+		BatchSize:    batchSize,
+		ReceiptAfter: time.Duration(receiptAfterSeconds) * time.Second,
+		RecheckAfter: time.Duration(recheckSeconds) * time.Second,
+	}, nil
+}
+
 func validate(cfg *Config) error {
 	var errs []error
 
@@ -131,13 +979,305 @@ func validate(cfg *Config) error {
 	if cfg.Scheduler.Interval <= 0 {
 		errs = append(errs, errors.New("SCHED_INTERVAL_SECONDS must be > 0"))
 	}
+	if cfg.Scheduler.StuckAfter <= 0 {
+		errs = append(errs, errors.New("SCHED_STUCK_AFTER_SECONDS must be > 0"))
+	}
+	if cfg.Scheduler.ReconcileInterval <= 0 {
+		errs = append(errs, errors.New("SCHED_RECONCILE_INTERVAL_SECONDS must be > 0"))
+	}
 	if cfg.Webhook.ContentMax <= 0 {
 		errs = append(errs, errors.New("CONTENT_MAX must be > 0"))
 	}
+	if cfg.Webhook.UnhealthyAfter < 0 {
+		errs = append(errs, errors.New("WEBHOOK_UNHEALTHY_AFTER must be >= 0"))
+	}
+	if cfg.Webhook.UnhealthyCooldown < 0 {
+		errs = append(errs, errors.New("WEBHOOK_UNHEALTHY_COOLDOWN_SECONDS must be >= 0"))
+	}
+	if cfg.Webhook.RoutesRefresh <= 0 {
+		errs = append(errs, errors.New("ROUTES_REFRESH_SECONDS must be > 0"))
+	}
+	errs = append(errs, validateProviders(cfg.Webhook)...)
+	if cfg.Receipts.Tolerance < 0 {
+		errs = append(errs, errors.New("DLR_SIGNATURE_TOLERANCE_SECONDS must be >= 0"))
+	}
+	if cfg.Receipts.Timeout <= 0 {
+		errs = append(errs, errors.New("DLR_TIMEOUT_HOURS must be > 0"))
+	}
+	if cfg.Receipts.SweepInterval <= 0 {
+		errs = append(errs, errors.New("DLR_SWEEP_INTERVAL_SECONDS must be > 0"))
+	}
+	if cfg.Events.DispatchInterval <= 0 {
+		errs = append(errs, errors.New("EVENTS_DISPATCH_INTERVAL_SECONDS must be > 0"))
+	}
+	if cfg.Events.DispatchBatchSize <= 0 {
+		errs = append(errs, errors.New("EVENTS_DISPATCH_BATCH_SIZE must be > 0"))
+	}
+	if cfg.Events.MaxAttempts <= 0 {
+		errs = append(errs, errors.New("EVENTS_MAX_ATTEMPTS must be > 0"))
+	}
+	if cfg.Events.BackoffBase <= 0 {
+		errs = append(errs, errors.New("EVENTS_BACKOFF_BASE_SECONDS must be > 0"))
+	}
+	if cfg.Events.BackoffMax < cfg.Events.BackoffBase {
+		errs = append(errs, errors.New("EVENTS_BACKOFF_MAX_SECONDS must be >= EVENTS_BACKOFF_BASE_SECONDS"))
+	}
+	for _, sink := range cfg.Events.Sinks {
+		switch sink {
+		case EventSinkFile:
+			if cfg.Events.FileMaxBytes <= 0 {
+				errs = append(errs, errors.New("EVENT_FILE_MAX_MB must be > 0"))
+			}
+			if cfg.Events.FileMaxBackups < 0 {
+				errs = append(errs, errors.New("EVENT_FILE_MAX_BACKUPS must be >= 0"))
+			}
+		case EventSinkRedis:
+			if !cfg.Redis.Enabled {
+				errs = append(errs, errors.New("EVENT_SINKS=redis requires REDIS_ADDR"))
+			}
+			if cfg.Events.StreamMaxLen < 0 {
+				errs = append(errs, errors.New("EVENT_STREAM_MAXLEN must be >= 0"))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("EVENT_SINKS: unknown sink %q", sink))
+		}
+	}
+	if cfg.Reconcile.BatchSize <= 0 {
+		errs = append(errs, errors.New("RECONCILE_BATCH_SIZE must be > 0"))
+	}
+	if cfg.Reconcile.ReceiptAfter <= 0 {
+		errs = append(errs, errors.New("RECONCILE_RECEIPT_AFTER_SECONDS must be > 0"))
+	}
+	if cfg.Reconcile.RecheckAfter <= 0 {
+		errs = append(errs, errors.New("RECONCILE_RECHECK_SECONDS must be > 0"))
+	}
+	if cfg.Breaker.Enabled {
+		if cfg.Breaker.FailureRatio <= 0 || cfg.Breaker.FailureRatio > 1 {
+			errs = append(errs, errors.New("BREAKER_FAILURE_RATIO must be in (0, 1]"))
+		}
+		if cfg.Breaker.MinRequests <= 0 {
+			errs = append(errs, errors.New("BREAKER_MIN_REQUESTS must be > 0"))
+		}
+		if cfg.Breaker.Window <= 0 {
+			errs = append(errs, errors.New("BREAKER_WINDOW_SECONDS must be > 0"))
+		}
+		if cfg.Breaker.OpenFor <= 0 {
+			errs = append(errs, errors.New("BREAKER_OPEN_SECONDS must be > 0"))
+		}
+	}
+	if cfg.Stats.SLOOldestPending < 0 {
+		errs = append(errs, errors.New("STATS_SLO_OLDEST_PENDING_SECONDS must be >= 0"))
+	}
+	if cfg.Stats.SLOFailureRatio < 0 || cfg.Stats.SLOFailureRatio > 1 {
+		errs = append(errs, errors.New("STATS_SLO_FAILURE_RATIO must be in [0, 1]"))
+	}
+	if cfg.Stats.SLOSendLatencyP50 < 0 {
+		errs = append(errs, errors.New("STATS_SLO_SEND_P50_SECONDS must be >= 0"))
+	}
+	if cfg.Stats.SLOSendLatencyP95 < 0 {
+		errs = append(errs, errors.New("STATS_SLO_SEND_P95_SECONDS must be >= 0"))
+	}
+	if cfg.Stats.CompactInterval <= 0 {
+		errs = append(errs, errors.New("STATS_COMPACT_INTERVAL_SECONDS must be > 0"))
+	}
+	if cfg.Alerts.WebhookURL != "" {
+		if u, err := url.Parse(cfg.Alerts.WebhookURL); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			errs = append(errs, errors.New("ALERT_WEBHOOK_URL must be an http or https URL"))
+		}
+		if cfg.Alerts.FailureRatio < 0 || cfg.Alerts.FailureRatio > 1 {
+			errs = append(errs, errors.New("ALERT_FAILURE_RATIO must be in [0, 1]"))
+		}
+		if cfg.Alerts.FailureTicks <= 0 {
+			errs = append(errs, errors.New("ALERT_FAILURE_TICKS must be > 0"))
+		}
+		if cfg.Alerts.OldestPending < 0 {
+			errs = append(errs, errors.New("ALERT_OLDEST_PENDING_MINUTES must be >= 0"))
+		}
+		if cfg.Alerts.SchedulerStopped < 0 {
+			errs = append(errs, errors.New("ALERT_SCHEDULER_STOPPED_MINUTES must be >= 0"))
+		}
+		if cfg.Alerts.Interval <= 0 {
+			errs = append(errs, errors.New("ALERT_EVAL_INTERVAL_SECONDS must be > 0"))
+		}
+	}
+	if cfg.Log.Format != "text" && cfg.Log.Format != "json" {
+		errs = append(errs, fmt.Errorf("LOG_FORMAT must be text or json, got %q", cfg.Log.Format))
+	}
+	if cfg.Redact.Content != "plain" && cfg.Redact.Content != "hash" {
+		errs = append(errs, fmt.Errorf("REDACT_CONTENT must be plain or hash, got %q", cfg.Redact.Content))
+	}
+	if cfg.Auth.CacheTTL < 0 {
+		errs = append(errs, errors.New("AUTH_CACHE_SECONDS must be >= 0"))
+	}
+	if cfg.Health.CheckTimeout <= 0 {
+		errs = append(errs, errors.New("HEALTH_CHECK_TIMEOUT_SECONDS must be > 0"))
+	}
+	if cfg.Health.SchedulerStale < 0 {
+		errs = append(errs, errors.New("HEALTH_SCHEDULER_STALE_SECONDS must be >= 0"))
+	}
+	if cfg.Tracing.Enabled {
+		if cfg.Tracing.SampleRatio < 0 || cfg.Tracing.SampleRatio > 1 {
+			errs = append(errs, errors.New("TRACING_SAMPLE_RATIO must be in [0, 1]"))
+		}
+		if cfg.Tracing.Endpoint != "" {
+			u, err := url.Parse(cfg.Tracing.Endpoint)
+			if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+				errs = append(errs, fmt.Errorf("TRACING_ENDPOINT must be an http or https URL, got %q", cfg.Tracing.Endpoint))
+			}
+		}
+	}
 
 	return joinErrors(errs)
 }
 
+var providerNameRE = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+func validateProviders(w WebhookConfig) []error {
+	if len(w.Providers) == 0 {
+		errs := validateMapping(defaultProviderPrefix, w.Mapping)
+		errs = append(errs, validateAuth(defaultProviderPrefix, w.Auth)...)
+		return append(errs, validateTransport(defaultProviderPrefix, w.Transport)...)
+	}
+
+	var errs []error
+	seen := map[string]bool{}
+	for _, p := range w.Providers {
+		prefix := providerPrefix(p.Name)
+		switch {
+		case !providerNameRE.MatchString(p.Name):
+			errs = append(errs, fmt.Errorf("WEBHOOK_PROVIDERS: invalid provider name %q", p.Name))
+			continue
+		case seen[prefix]:
+			errs = append(errs, fmt.Errorf("WEBHOOK_PROVIDERS: duplicate provider %q", p.Name))
+			continue
+		}
+		seen[prefix] = true
+
+		if p.URL == "" {
+			errs = append(errs, fmt.Errorf("missing required env var: %sURL", prefix))
+		}
+		if p.Priority < 0 {
+			errs = append(errs, fmt.Errorf("%sPRIORITY must be >= 0", prefix))
+		}
+		if p.Weight <= 0 {
+			errs = append(errs, fmt.Errorf("%sWEIGHT must be > 0", prefix))
+		}
+		errs = append(errs, validateMapping(prefix, p.Mapping)...)
+		errs = append(errs, validateAuth(prefix, p.Auth)...)
+		errs = append(errs, validateTransport(prefix, p.Transport)...)
+	}
+	return errs
+}
+
+func validateMapping(prefix string, m WebhookMappingConfig) []error {
+	var errs []error
+	switch m.ContentType {
+	case "json":
+	case "form":
+		if m.BodyTemplate == "" {
+			errs = append(errs, fmt.Errorf("%sCONTENT_TYPE=form requires %sBODY_TEMPLATE", prefix, prefix))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("%sCONTENT_TYPE: unknown content type %q", prefix, m.ContentType))
+	}
+	for _, code := range m.AcceptedStatuses {
+		if code < 100 || code > 599 {
+			errs = append(errs, fmt.Errorf("%sACCEPTED_STATUSES: invalid status %d", prefix, code))
+		}
+	}
+	for raw, status := range m.StatusValues {
+		switch status {
+		case "sent", "delivered", "undelivered":
+		default:
+			errs = append(errs, fmt.Errorf("%sSTATUS_VALUES: %q maps to unsupported status %q", prefix, raw, status))
+		}
+	}
+	return errs
+}
+
+func validateAuth(prefix string, a WebhookAuthConfig) []error {
+	var errs []error
+	switch a.Mode {
+	case "none":
+	case "bearer":
+		if a.BearerToken == "" {
+			errs = append(errs, fmt.Errorf("%sAUTH=bearer requires %sBEARER_TOKEN", prefix, prefix))
+		}
+	case "basic":
+		if a.BasicUser == "" {
+			errs = append(errs, fmt.Errorf("%sAUTH=basic requires %sBASIC_USER", prefix, prefix))
+		}
+	case "hmac":
+		if a.HMACSecret == "" {
+			errs = append(errs, fmt.Errorf("%sAUTH=hmac requires %sHMAC_SECRET", prefix, prefix))
+		}
+		if a.HMACTolerance < 0 {
+			errs = append(errs, fmt.Errorf("%sHMAC_TOLERANCE_SECONDS must be >= 0", prefix))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("%sAUTH: unknown mode %q", prefix, a.Mode))
+	}
+	return errs
+}
+
+// validateTransport also loads the certificate files, so a bad path or a
+// mismatched key fails at startup rather than on the first send.
+func validateTransport(prefix string, t WebhookTransportConfig) []error {
+	var errs []error
+	switch {
+	case t.CertFile != "" && t.KeyFile != "":
+		if _, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile); err != nil {
+			errs = append(errs, fmt.Errorf("%sTLS_CERT_FILE/%sTLS_KEY_FILE: %w", prefix, prefix, err))
+		}
+	case t.CertFile != "":
+		errs = append(errs, fmt.Errorf("%sTLS_CERT_FILE requires %sTLS_KEY_FILE", prefix, prefix))
+	case t.KeyFile != "":
+		errs = append(errs, fmt.Errorf("%sTLS_KEY_FILE requires %sTLS_CERT_FILE", prefix, prefix))
+	}
+	if t.CAFile != "" {
+		pem, err := os.ReadFile(t.CAFile)
+		switch {
+		case err != nil:
+			errs = append(errs, fmt.Errorf("%sTLS_CA_FILE: %w", prefix, err))
+		case !x509.NewCertPool().AppendCertsFromPEM(pem):
+			errs = append(errs, fmt.Errorf("%sTLS_CA_FILE: no PEM certificates found", prefix))
+		}
+	}
+	if t.ProxyURL != "" {
+		// The URL may carry credentials, so it is kept out of the error.
+		u, err := url.Parse(t.ProxyURL)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5") {
+			errs = append(errs, fmt.Errorf("%sPROXY_URL must be an http, https or socks5 URL with a host", prefix))
+		}
+	}
+	if t.Timeout <= 0 {
+		errs = append(errs, fmt.Errorf("%sTIMEOUT_SECONDS must be > 0", prefix))
+	}
+	if t.DialTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("%sDIAL_TIMEOUT_SECONDS must be > 0", prefix))
+	}
+	if t.TLSHandshakeTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("%sTLS_HANDSHAKE_TIMEOUT_SECONDS must be > 0", prefix))
+	}
+	if t.MaxIdleConns < 0 {
+		errs = append(errs, fmt.Errorf("%sMAX_IDLE_CONNS must be >= 0", prefix))
+	}
+	if t.MaxIdleConnsPerHost < 0 {
+		errs = append(errs, fmt.Errorf("%sMAX_IDLE_CONNS_PER_HOST must be >= 0", prefix))
+	}
+	return errs
+}
+
+// defaultInstanceID identifies this process in attempt records when
+// INSTANCE_ID is not set.
+func defaultInstanceID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		return "unknown"
+	}
+	return host
+}
+
 func requireEnv(key string) (string, error) {
 	val := os.Getenv(key)
 	if val == "" {
@@ -153,6 +1293,57 @@ func getEnv(key, def string) string {
 	return def
 }
 
+// getSecret reads key from the environment, or from the file named by
+// key_FILE so secrets can be mounted instead of passed inline. Setting both
+// is an error. A trailing newline in the file is ignored.
+func getSecret(key string) (string, error) {
+	val := os.Getenv(key)
+	path := os.Getenv(key + "_FILE")
+	if path == "" {
+		return val, nil
+	}
+	if val != "" {
+		return "", fmt.Errorf("only one of %s and %s_FILE may be set", key, key)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read %s_FILE: %w", key, err)
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+// parseHeaders parses "Name: value" pairs separated by newlines or commas.
+// key names the source env var in errors.
+func parseHeaders(key, s string) (map[string]string, error) {
+	headers := map[string]string{}
+	for i, line := range strings.FieldsFunc(s, func(r rune) bool { return r == '\n' || r == ',' }) {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(line, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			// The value may be a credential, so it is kept out of the error.
+			return nil, fmt.Errorf("%s: entry %d is not \"Name: value\"", key, i+1)
+		}
+		headers[name] = strings.TrimSpace(value)
+	}
+	return headers, nil
+}
+
+// getEnvList splits a comma-separated env var, dropping empty items.
+func getEnvList(key string) []string {
+	var out []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
 func getEnvInt(key string, def int) (int, error) {
 	v := os.Getenv(key)
 	if v == "" {
@@ -165,6 +1356,30 @@ func getEnvInt(key string, def int) (int, error) {
 	return i, nil
 }
 
+func getEnvFloat(key string, def float64) (float64, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid float for env %s: %q", key, v)
+	}
+	return f, nil
+}
+
+func getEnvBool(key string, def bool) (bool, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("invalid bool for env %s: %q", key, v)
+	}
+	return b, nil
+}
+
 func joinErrors(errs []error) error {
 	if len(errs) == 0 {
 		return nil
@@ -2,7 +2,9 @@ package config
 
 import (
 	"errors"
+	"log/slog"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"testing"
@@ -34,6 +36,9 @@ func TestLoadAll_HappyPath_NoRedis(t *testing.T) {
 	if cfg.Server.Address != ":8080" {
 		t.Fatalf("unexpected Server.Address default: %q", cfg.Server.Address)
 	}
+	if cfg.Server.InstanceID == "" {
+		t.Fatalf("expected Server.InstanceID to default to the hostname")
+	}
 	if cfg.Webhook.ContentMax != 160 {
 		t.Fatalf("unexpected ContentMax default: %d", cfg.Webhook.ContentMax)
 	}
@@ -43,10 +48,100 @@ func TestLoadAll_HappyPath_NoRedis(t *testing.T) {
 	if cfg.Scheduler.BatchSize != 2 {
 		t.Fatalf("unexpected Scheduler.BatchSize default: %d", cfg.Scheduler.BatchSize)
 	}
+	if cfg.Scheduler.StuckAfter != 5*time.Minute || cfg.Scheduler.ReconcileInterval != time.Minute {
+		t.Fatalf("unexpected Scheduler reconcile defaults: stuck=%v interval=%v", cfg.Scheduler.StuckAfter, cfg.Scheduler.ReconcileInterval)
+	}
 
 	if cfg.Redis.Enabled {
 		t.Fatalf("expected Redis disabled when REDIS_ADDR not set")
 	}
+
+	if cfg.Receipts.Secret != "" {
+		t.Fatalf("expected empty Receipts.Secret by default, got %q", cfg.Receipts.Secret)
+	}
+	if cfg.Receipts.Tolerance != 5*time.Minute {
+		t.Fatalf("unexpected Receipts.Tolerance default: %v", cfg.Receipts.Tolerance)
+	}
+	if cfg.Receipts.Timeout != 48*time.Hour {
+		t.Fatalf("unexpected Receipts.Timeout default: %v", cfg.Receipts.Timeout)
+	}
+	if cfg.Receipts.SweepInterval != 10*time.Minute {
+		t.Fatalf("unexpected Receipts.SweepInterval default: %v", cfg.Receipts.SweepInterval)
+	}
+
+	if cfg.Events.DispatchInterval != 5*time.Second {
+		t.Fatalf("unexpected Events.DispatchInterval default: %v", cfg.Events.DispatchInterval)
+	}
+	if cfg.Events.DispatchBatchSize != 50 {
+		t.Fatalf("unexpected Events.DispatchBatchSize default: %d", cfg.Events.DispatchBatchSize)
+	}
+	if cfg.Events.MaxAttempts != 8 {
+		t.Fatalf("unexpected Events.MaxAttempts default: %d", cfg.Events.MaxAttempts)
+	}
+	if cfg.Events.BackoffBase != 10*time.Second || cfg.Events.BackoffMax != time.Hour {
+		t.Fatalf("unexpected Events backoff defaults: base=%v max=%v", cfg.Events.BackoffBase, cfg.Events.BackoffMax)
+	}
+	if a := cfg.Webhook.Auth; a.Mode != "none" || len(a.Headers) != 0 || a.HMACHeader != "X-Signature" ||
+		a.HMACTimestampHeader != "X-Signature-Timestamp" || a.HMACTolerance != 5*time.Minute {
+		t.Fatalf("unexpected Webhook.Auth defaults: %+v", a)
+	}
+
+	if m := cfg.Webhook.Mapping; m.BodyTemplate != "" || m.ContentType != "json" || m.RemoteIDPath != "messageId" ||
+		len(m.AcceptedStatuses) != 1 || m.AcceptedStatuses[0] != 202 {
+		t.Fatalf("unexpected Webhook.Mapping defaults: %+v", m)
+	}
+
+	if len(cfg.Events.Sinks) != 0 {
+		t.Fatalf("expected no event sinks by default, got %v", cfg.Events.Sinks)
+	}
+	if cfg.Events.FilePath != "events.ndjson" || cfg.Events.FileMaxBytes != 100<<20 || cfg.Events.FileMaxBackups != 5 {
+		t.Fatalf("unexpected event file defaults: %+v", cfg.Events)
+	}
+	if cfg.Events.StreamKey != "message-events" || cfg.Events.StreamMaxLen != 100000 {
+		t.Fatalf("unexpected event stream defaults: key=%q maxlen=%d", cfg.Events.StreamKey, cfg.Events.StreamMaxLen)
+	}
+
+	if m := cfg.Webhook.Mapping; m.StatusURL != "" || m.StatusPath != "status" || len(m.StatusValues) != 0 || cfg.Webhook.HasStatusLookup() {
+		t.Fatalf("expected status lookups disabled by default: %+v", m)
+	}
+	if tr := cfg.Webhook.Transport; tr.CertFile != "" || tr.CAFile != "" || tr.ProxyURL != "" ||
+		tr.Timeout != 10*time.Second || tr.DialTimeout != 5*time.Second || tr.TLSHandshakeTimeout != 5*time.Second ||
+		tr.MaxIdleConns != 100 || tr.MaxIdleConnsPerHost != 10 || !tr.HTTP2 {
+		t.Fatalf("unexpected Webhook.Transport defaults: %+v", tr)
+	}
+	if r := cfg.Reconcile; r.BatchSize != 100 || r.ReceiptAfter != time.Hour || r.RecheckAfter != time.Hour {
+		t.Fatalf("unexpected Reconcile defaults: %+v", r)
+	}
+
+	if st := cfg.Stats; st.SLOOldestPending != 10*time.Minute || st.SLOFailureRatio != 0.05 ||
+		st.SLOSendLatencyP50 != 5*time.Minute || st.SLOSendLatencyP95 != 15*time.Minute || st.CompactInterval != time.Minute {
+		t.Fatalf("unexpected Stats defaults: %+v", st)
+	}
+
+	if tr := cfg.Tracing; tr.Enabled || tr.Endpoint != "" || tr.ServiceName != "automatic-messaging" || tr.SampleRatio != 1 {
+		t.Fatalf("unexpected Tracing defaults: %+v", tr)
+	}
+	if al := cfg.Alerts; al.WebhookURL != "" || al.FailureRatio != 0.5 || al.FailureTicks != 5 ||
+		al.OldestPending != 30*time.Minute || al.SchedulerStopped != 10*time.Minute || al.Interval != time.Minute {
+		t.Fatalf("unexpected Alerts defaults: %+v", al)
+	}
+	if ac := cfg.Auth; !ac.Enabled || ac.CacheTTL != 30*time.Second {
+		t.Fatalf("unexpected Auth defaults: %+v", ac)
+	}
+	if rc := cfg.Redact; !rc.Phones || rc.Content != "plain" {
+		t.Fatalf("unexpected Redact defaults: %+v", rc)
+	}
+	if lc := cfg.Log; lc.Format != "text" || lc.Level != slog.LevelInfo {
+		t.Fatalf("unexpected Log defaults: %+v", lc)
+	}
+	if hc := cfg.Health; hc.CheckTimeout != 2*time.Second || hc.SchedulerStale != 0 {
+		t.Fatalf("unexpected Health defaults: %+v", hc)
+	}
+
+	if b := cfg.Breaker; !b.Enabled || b.FailureRatio != 0.5 || b.MinRequests != 5 ||
+		b.Window != 5*time.Minute || b.OpenFor != time.Minute {
+		t.Fatalf("unexpected Breaker defaults: %+v", b)
+	}
 }
 
 func TestLoadAll_HappyPath_WithRedis(t *testing.T) {
@@ -62,6 +157,7 @@ func TestLoadAll_HappyPath_WithRedis(t *testing.T) {
 	t.Setenv("REDIS_PASSWORD", "secret")
 	t.Setenv("REDIS_DB", "3")
 	t.Setenv("REDIS_TTL_SECONDS", "42")
+	t.Setenv("EVENT_SINKS", " file, redis ")
 
 	cfg, err := LoadAll()
 	if err != nil {
@@ -83,6 +179,9 @@ func TestLoadAll_HappyPath_WithRedis(t *testing.T) {
 	if cfg.Redis.TTL != 42*time.Second {
 		t.Fatalf("unexpected Redis.TTL: %v", cfg.Redis.TTL)
 	}
+	if len(cfg.Events.Sinks) != 2 || cfg.Events.Sinks[0] != "file" || cfg.Events.Sinks[1] != "redis" {
+		t.Fatalf("unexpected Events.Sinks: %q", cfg.Events.Sinks)
+	}
 }
 
 func TestLoadAll_RequiredEnvMissing(t *testing.T) {
@@ -137,6 +236,24 @@ func TestLoadAll_InvalidInts(t *testing.T) {
 		{"invalid SCHED_BATCH_SIZE", "SCHED_BATCH_SIZE", "x"},
 		{"invalid REDIS_DB", "REDIS_DB", "bad"},
 		{"invalid REDIS_TTL_SECONDS", "REDIS_TTL_SECONDS", "bad"},
+		{"invalid DLR_TIMEOUT_HOURS", "DLR_TIMEOUT_HOURS", "soon"},
+		{"invalid DLR_SWEEP_INTERVAL_SECONDS", "DLR_SWEEP_INTERVAL_SECONDS", "x"},
+		{"invalid EVENTS_MAX_ATTEMPTS", "EVENTS_MAX_ATTEMPTS", "many"},
+		{"invalid WEBHOOK_ACCEPTED_STATUSES", "WEBHOOK_ACCEPTED_STATUSES", "200,ok"},
+		{"invalid BREAKER_ENABLED", "BREAKER_ENABLED", "maybe"},
+		{"invalid BREAKER_FAILURE_RATIO", "BREAKER_FAILURE_RATIO", "half"},
+		{"invalid WEBHOOK_TIMEOUT_SECONDS", "WEBHOOK_TIMEOUT_SECONDS", "soon"},
+		{"invalid WEBHOOK_HTTP2", "WEBHOOK_HTTP2", "h2"},
+		{"invalid TRACING_SAMPLE_RATIO", "TRACING_SAMPLE_RATIO", "all"},
+		{"invalid STATS_SLO_FAILURE_RATIO", "STATS_SLO_FAILURE_RATIO", "low"},
+		{"invalid STATS_COMPACT_INTERVAL_SECONDS", "STATS_COMPACT_INTERVAL_SECONDS", "often"},
+		{"invalid ALERT_FAILURE_TICKS", "ALERT_FAILURE_TICKS", "few"},
+		{"invalid ALERT_OLDEST_PENDING_MINUTES", "ALERT_OLDEST_PENDING_MINUTES", "late"},
+		{"invalid HEALTH_CHECK_TIMEOUT_SECONDS", "HEALTH_CHECK_TIMEOUT_SECONDS", "fast"},
+		{"invalid LOG_LEVEL", "LOG_LEVEL", "verbose"},
+		{"invalid REDACT_PHONES", "REDACT_PHONES", "sometimes"},
+		{"invalid AUTH_ENABLED", "AUTH_ENABLED", "yes please"},
+		{"invalid AUTH_CACHE_SECONDS", "AUTH_CACHE_SECONDS", "brief"},
 	}
 
 	for _, tc := range cases {
@@ -200,6 +317,283 @@ func TestLoadAll_ValidationFailures(t *testing.T) {
 			},
 			want: "CONTENT_MAX",
 		},
+		{
+			name: "stuck after <= 0",
+			set: func() {
+				t.Setenv("SCHED_STUCK_AFTER_SECONDS", "0")
+			},
+			want: "SCHED_STUCK_AFTER_SECONDS",
+		},
+		{
+			name: "receipt timeout <= 0",
+			set: func() {
+				t.Setenv("DLR_TIMEOUT_HOURS", "0")
+			},
+			want: "DLR_TIMEOUT_HOURS",
+		},
+		{
+			name: "negative signature tolerance",
+			set: func() {
+				t.Setenv("DLR_SIGNATURE_TOLERANCE_SECONDS", "-1")
+			},
+			want: "DLR_SIGNATURE_TOLERANCE_SECONDS",
+		},
+		{
+			name: "backoff max below base",
+			set: func() {
+				t.Setenv("EVENTS_BACKOFF_BASE_SECONDS", "60")
+				t.Setenv("EVENTS_BACKOFF_MAX_SECONDS", "30")
+			},
+			want: "EVENTS_BACKOFF_MAX_SECONDS",
+		},
+		{
+			name: "unknown webhook auth mode",
+			set: func() {
+				t.Setenv("WEBHOOK_AUTH", "digest")
+			},
+			want: "WEBHOOK_AUTH",
+		},
+		{
+			name: "hmac without secret",
+			set: func() {
+				t.Setenv("WEBHOOK_AUTH", "hmac")
+			},
+			want: "WEBHOOK_HMAC_SECRET",
+		},
+		{
+			name: "bearer without token",
+			set: func() {
+				t.Setenv("WEBHOOK_AUTH", "bearer")
+			},
+			want: "WEBHOOK_BEARER_TOKEN",
+		},
+		{
+			name: "malformed static header",
+			set: func() {
+				t.Setenv("WEBHOOK_HEADERS", "X-Api-Key: k, secret-without-name")
+			},
+			want: "WEBHOOK_HEADERS: entry 2",
+		},
+		{
+			name: "secret and secret file both set",
+			set: func() {
+				t.Setenv("DLR_SECRET", "a")
+				t.Setenv("DLR_SECRET_FILE", "/nonexistent")
+			},
+			want: "DLR_SECRET_FILE",
+		},
+		{
+			name: "routes refresh <= 0",
+			set: func() {
+				t.Setenv("ROUTES_REFRESH_SECONDS", "0")
+			},
+			want: "ROUTES_REFRESH_SECONDS",
+		},
+		{
+			name: "form without body template",
+			set: func() {
+				t.Setenv("WEBHOOK_CONTENT_TYPE", "form")
+			},
+			want: "WEBHOOK_BODY_TEMPLATE",
+		},
+		{
+			name: "accepted status out of range",
+			set: func() {
+				t.Setenv("WEBHOOK_ACCEPTED_STATUSES", "200,999")
+			},
+			want: "WEBHOOK_ACCEPTED_STATUSES",
+		},
+		{
+			name: "unknown event sink",
+			set: func() {
+				t.Setenv("EVENT_SINKS", "file,kafka")
+			},
+			want: "EVENT_SINKS",
+		},
+		{
+			name: "redis sink without redis",
+			set: func() {
+				t.Setenv("EVENT_SINKS", "redis")
+			},
+			want: "REDIS_ADDR",
+		},
+		{
+			name: "status value maps to unsupported status",
+			set: func() {
+				t.Setenv("WEBHOOK_STATUS_VALUES", "DELIVRD:delivered,QUEUED:pending")
+			},
+			want: "WEBHOOK_STATUS_VALUES",
+		},
+		{
+			name: "malformed status value",
+			set: func() {
+				t.Setenv("WEBHOOK_STATUS_VALUES", "DELIVRD")
+			},
+			want: "WEBHOOK_STATUS_VALUES: entry 1",
+		},
+		{
+			name: "client cert without key",
+			set: func() {
+				t.Setenv("WEBHOOK_TLS_CERT_FILE", "client.pem")
+			},
+			want: "WEBHOOK_TLS_CERT_FILE requires WEBHOOK_TLS_KEY_FILE",
+		},
+		{
+			name: "unreadable client cert",
+			set: func() {
+				t.Setenv("WEBHOOK_TLS_CERT_FILE", filepath.Join(t.TempDir(), "missing.pem"))
+				t.Setenv("WEBHOOK_TLS_KEY_FILE", filepath.Join(t.TempDir(), "missing.key"))
+			},
+			want: "WEBHOOK_TLS_CERT_FILE/WEBHOOK_TLS_KEY_FILE",
+		},
+		{
+			name: "CA bundle without certificates",
+			set: func() {
+				path := filepath.Join(t.TempDir(), "ca.pem")
+				if err := os.WriteFile(path, []byte("not a certificate"), 0o600); err != nil {
+					t.Fatal(err)
+				}
+				t.Setenv("WEBHOOK_TLS_CA_FILE", path)
+			},
+			want: "WEBHOOK_TLS_CA_FILE: no PEM certificates found",
+		},
+		{
+			name: "unsupported proxy scheme",
+			set: func() {
+				t.Setenv("WEBHOOK_PROXY_URL", "ftp://proxy:21")
+			},
+			want: "WEBHOOK_PROXY_URL",
+		},
+		{
+			name: "dial timeout <= 0",
+			set: func() {
+				t.Setenv("WEBHOOK_DIAL_TIMEOUT_SECONDS", "0")
+			},
+			want: "WEBHOOK_DIAL_TIMEOUT_SECONDS",
+		},
+		{
+			name: "negative idle conns",
+			set: func() {
+				t.Setenv("WEBHOOK_MAX_IDLE_CONNS_PER_HOST", "-1")
+			},
+			want: "WEBHOOK_MAX_IDLE_CONNS_PER_HOST",
+		},
+		{
+			name: "reconcile batch size <= 0",
+			set: func() {
+				t.Setenv("RECONCILE_BATCH_SIZE", "0")
+			},
+			want: "RECONCILE_BATCH_SIZE",
+		},
+		{
+			name: "stats failure ratio SLO above 1",
+			set: func() {
+				t.Setenv("STATS_SLO_FAILURE_RATIO", "5")
+			},
+			want: "STATS_SLO_FAILURE_RATIO",
+		},
+		{
+			name: "negative stats latency SLO",
+			set: func() {
+				t.Setenv("STATS_SLO_SEND_P95_SECONDS", "-1")
+			},
+			want: "STATS_SLO_SEND_P95_SECONDS",
+		},
+		{
+			name: "stats compact interval <= 0",
+			set: func() {
+				t.Setenv("STATS_COMPACT_INTERVAL_SECONDS", "0")
+			},
+			want: "STATS_COMPACT_INTERVAL_SECONDS",
+		},
+		{
+			name: "tracing sample ratio above 1",
+			set: func() {
+				t.Setenv("TRACING_ENABLED", "true")
+				t.Setenv("TRACING_SAMPLE_RATIO", "2")
+			},
+			want: "TRACING_SAMPLE_RATIO",
+		},
+		{
+			name: "tracing endpoint without scheme",
+			set: func() {
+				t.Setenv("TRACING_ENABLED", "true")
+				t.Setenv("TRACING_ENDPOINT", "collector:4318")
+			},
+			want: "TRACING_ENDPOINT",
+		},
+		{
+			name: "alert webhook URL without scheme",
+			set: func() {
+				t.Setenv("ALERT_WEBHOOK_URL", "alerts.local/hook")
+			},
+			want: "ALERT_WEBHOOK_URL",
+		},
+		{
+			name: "alert failure ticks zero",
+			set: func() {
+				t.Setenv("ALERT_WEBHOOK_URL", "https://alerts.local/hook")
+				t.Setenv("ALERT_FAILURE_TICKS", "0")
+			},
+			want: "ALERT_FAILURE_TICKS",
+		},
+		{
+			name: "alert failure ratio above 1",
+			set: func() {
+				t.Setenv("ALERT_WEBHOOK_URL", "https://alerts.local/hook")
+				t.Setenv("ALERT_FAILURE_RATIO", "1.5")
+			},
+			want: "ALERT_FAILURE_RATIO",
+		},
+		{
+			name: "negative auth cache",
+			set: func() {
+				t.Setenv("AUTH_CACHE_SECONDS", "-1")
+			},
+			want: "AUTH_CACHE_SECONDS",
+		},
+		{
+			name: "unknown content redaction",
+			set: func() {
+				t.Setenv("REDACT_CONTENT", "encrypt")
+			},
+			want: "REDACT_CONTENT",
+		},
+		{
+			name: "unknown log format",
+			set: func() {
+				t.Setenv("LOG_FORMAT", "xml")
+			},
+			want: "LOG_FORMAT",
+		},
+		{
+			name: "health check timeout zero",
+			set: func() {
+				t.Setenv("HEALTH_CHECK_TIMEOUT_SECONDS", "0")
+			},
+			want: "HEALTH_CHECK_TIMEOUT_SECONDS",
+		},
+		{
+			name: "negative scheduler staleness",
+			set: func() {
+				t.Setenv("HEALTH_SCHEDULER_STALE_SECONDS", "-1")
+			},
+			want: "HEALTH_SCHEDULER_STALE_SECONDS",
+		},
+		{
+			name: "breaker failure ratio above 1",
+			set: func() {
+				t.Setenv("BREAKER_FAILURE_RATIO", "1.5")
+			},
+			want: "BREAKER_FAILURE_RATIO",
+		},
+		{
+			name: "breaker min requests <= 0",
+			set: func() {
+				t.Setenv("BREAKER_MIN_REQUESTS", "0")
+			},
+			want: "BREAKER_MIN_REQUESTS",
+		},
 	}
 
 	for _, tc := range cases {
@@ -222,6 +616,125 @@ func TestLoadAll_ValidationFailures(t *testing.T) {
 	}
 }
 
+func TestLoadAll_WebhookAuthSecretsFromFiles(t *testing.T) {
+	envMu.Lock()
+	defer envMu.Unlock()
+
+	clearTestEnv(t)
+
+	dir := t.TempDir()
+	writeFile := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+		return path
+	}
+
+	t.Setenv("POSTGRES_URL", "postgres://u:p@localhost:5432/db?sslmode=disable")
+	t.Setenv("WEBHOOK_URL", "https://example.com/webhook")
+	t.Setenv("WEBHOOK_AUTH", "hmac")
+	t.Setenv("WEBHOOK_HMAC_SECRET_FILE", writeFile("hmac", "topsecret\n"))
+	t.Setenv("WEBHOOK_HMAC_HEADER", "X-Provider-Signature")
+	t.Setenv("WEBHOOK_HMAC_TOLERANCE_SECONDS", "60")
+	t.Setenv("WEBHOOK_HEADERS_FILE", writeFile("headers", "X-Api-Key: k1\nX-Tenant: acme\n"))
+	t.Setenv("DLR_SECRET_FILE", writeFile("dlr", "dlr-secret"))
+
+	cfg, err := LoadAll()
+	if err != nil {
+		t.Fatalf("LoadAll() error: %v", err)
+	}
+
+	a := cfg.Webhook.Auth
+	if a.Mode != "hmac" || a.HMACSecret != "topsecret" || a.HMACHeader != "X-Provider-Signature" || a.HMACTolerance != time.Minute {
+		t.Fatalf("unexpected Webhook.Auth: %+v", a)
+	}
+	if len(a.Headers) != 2 || a.Headers["X-Api-Key"] != "k1" || a.Headers["X-Tenant"] != "acme" {
+		t.Fatalf("unexpected Webhook.Auth.Headers: %v", a.Headers)
+	}
+	if cfg.Receipts.Secret != "dlr-secret" {
+		t.Fatalf("expected DLR_SECRET from file, got %q", cfg.Receipts.Secret)
+	}
+}
+
+func TestLoadAll_MultipleProviders(t *testing.T) {
+	envMu.Lock()
+	defer envMu.Unlock()
+
+	clearTestEnv(t)
+
+	t.Setenv("POSTGRES_URL", "postgres://u:p@localhost:5432/db?sslmode=disable")
+	t.Setenv("WEBHOOK_PROVIDERS", "primary,eu-backup")
+	t.Setenv("WEBHOOK_PRIMARY_URL", "https://a.example.com/send")
+	t.Setenv("WEBHOOK_PRIMARY_WEIGHT", "9")
+	t.Setenv("WEBHOOK_PRIMARY_AUTH", "bearer")
+	t.Setenv("WEBHOOK_PRIMARY_BEARER_TOKEN", "tok")
+	t.Setenv("WEBHOOK_EU_BACKUP_URL", "https://b.example.com/sms")
+	t.Setenv("WEBHOOK_EU_BACKUP_ACCEPTED_STATUSES", "200")
+	t.Setenv("WEBHOOK_EU_BACKUP_REMOTE_ID_PATH", "sid")
+	t.Setenv("WEBHOOK_EU_BACKUP_STATUS_URL", "https://b.example.com/sms/{{path .RemoteMessageID}}")
+	t.Setenv("WEBHOOK_EU_BACKUP_STATUS_VALUES", "DELIVRD:delivered, UNDELIV:undelivered")
+
+	cfg, err := LoadAll()
+	if err != nil {
+		t.Fatalf("LoadAll() error: %v", err)
+	}
+
+	ps := cfg.Webhook.ProviderList()
+	if len(ps) != 2 {
+		t.Fatalf("expected 2 providers, got %+v", ps)
+	}
+	if p := ps[0]; p.Name != "primary" || p.URL != "https://a.example.com/send" || p.Priority != 0 || p.Weight != 9 ||
+		p.Auth.Mode != "bearer" || p.Auth.BearerToken != "tok" {
+		t.Fatalf("unexpected primary provider: %+v", p)
+	}
+	if p := ps[1]; p.Name != "eu-backup" || p.Priority != 1 || p.Weight != 1 ||
+		p.Mapping.RemoteIDPath != "sid" || p.Mapping.AcceptedStatuses[0] != 200 ||
+		p.Mapping.StatusValues["UNDELIV"] != "undelivered" || len(p.Mapping.StatusValues) != 2 {
+		t.Fatalf("unexpected backup provider: %+v", p)
+	}
+	if !cfg.Webhook.HasStatusLookup() {
+		t.Fatalf("expected a status lookup to be configured")
+	}
+	if cfg.Webhook.RoutesRefresh != 30*time.Second {
+		t.Fatalf("unexpected RoutesRefresh default: %v", cfg.Webhook.RoutesRefresh)
+	}
+	if cfg.Webhook.UnhealthyAfter != 3 || cfg.Webhook.UnhealthyCooldown != 30*time.Second {
+		t.Fatalf("unexpected health policy defaults: %d %v", cfg.Webhook.UnhealthyAfter, cfg.Webhook.UnhealthyCooldown)
+	}
+}
+
+func TestLoadAll_ProviderValidation(t *testing.T) {
+	envMu.Lock()
+	defer envMu.Unlock()
+
+	clearTestEnv(t)
+
+	t.Setenv("POSTGRES_URL", "postgres://u:p@localhost:5432/db?sslmode=disable")
+	t.Setenv("WEBHOOK_PROVIDERS", "primary,Bad!")
+	t.Setenv("WEBHOOK_PRIMARY_AUTH", "hmac")
+
+	_, err := LoadAll()
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+	for _, want := range []string{"WEBHOOK_PRIMARY_URL", "WEBHOOK_PRIMARY_HMAC_SECRET", `invalid provider name "Bad!"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected error mentioning %s, got: %v", want, err)
+		}
+	}
+}
+
+func TestDefaultProviderList(t *testing.T) {
+	t.Parallel()
+
+	w := WebhookConfig{URL: "https://example.com", Auth: WebhookAuthConfig{Mode: "none"}}
+	ps := w.ProviderList()
+	if len(ps) != 1 || ps[0].Name != DefaultProvider || ps[0].URL != w.URL || ps[0].Weight != 1 {
+		t.Fatalf("unexpected default provider list: %+v", ps)
+	}
+}
+
 func TestRequireEnv(t *testing.T) {
 	envMu.Lock()
 	defer envMu.Unlock()
@@ -320,11 +833,101 @@ func clearTestEnv(t *testing.T) {
 		"CONTENT_MAX",
 		"SCHED_INTERVAL_SECONDS",
 		"SCHED_BATCH_SIZE",
+		"SCHED_STUCK_AFTER_SECONDS",
+		"SCHED_RECONCILE_INTERVAL_SECONDS",
 		"SERVER_ADDRESS",
+		"INSTANCE_ID",
 		"REDIS_ADDR",
 		"REDIS_PASSWORD",
 		"REDIS_DB",
 		"REDIS_TTL_SECONDS",
+		"DLR_SECRET",
+		"DLR_SECRET_FILE",
+		"DLR_SIGNATURE_TOLERANCE_SECONDS",
+		"DLR_TIMEOUT_HOURS",
+		"DLR_SWEEP_INTERVAL_SECONDS",
+		"EVENTS_DISPATCH_INTERVAL_SECONDS",
+		"EVENTS_DISPATCH_BATCH_SIZE",
+		"EVENTS_MAX_ATTEMPTS",
+		"EVENTS_BACKOFF_BASE_SECONDS",
+		"EVENTS_BACKOFF_MAX_SECONDS",
+		"WEBHOOK_PROVIDERS",
+		"WEBHOOK_UNHEALTHY_AFTER",
+		"WEBHOOK_UNHEALTHY_COOLDOWN_SECONDS",
+		"ROUTES_REFRESH_SECONDS",
+		"WEBHOOK_AUTH",
+		"WEBHOOK_HEADERS",
+		"WEBHOOK_HEADERS_FILE",
+		"WEBHOOK_BEARER_TOKEN",
+		"WEBHOOK_BEARER_TOKEN_FILE",
+		"WEBHOOK_BASIC_USER",
+		"WEBHOOK_BASIC_PASSWORD",
+		"WEBHOOK_BASIC_PASSWORD_FILE",
+		"WEBHOOK_HMAC_SECRET",
+		"WEBHOOK_HMAC_SECRET_FILE",
+		"WEBHOOK_HMAC_HEADER",
+		"WEBHOOK_HMAC_TIMESTAMP_HEADER",
+		"WEBHOOK_HMAC_TOLERANCE_SECONDS",
+		"WEBHOOK_BODY_TEMPLATE",
+		"WEBHOOK_BODY_TEMPLATE_FILE",
+		"WEBHOOK_CONTENT_TYPE",
+		"WEBHOOK_ACCEPTED_STATUSES",
+		"WEBHOOK_REMOTE_ID_PATH",
+		"WEBHOOK_STATUS_URL",
+		"WEBHOOK_STATUS_URL_FILE",
+		"WEBHOOK_STATUS_PATH",
+		"WEBHOOK_STATUS_VALUES",
+		"WEBHOOK_TLS_CERT_FILE",
+		"WEBHOOK_TLS_KEY_FILE",
+		"WEBHOOK_TLS_CA_FILE",
+		"WEBHOOK_PROXY_URL",
+		"WEBHOOK_PROXY_URL_FILE",
+		"WEBHOOK_TIMEOUT_SECONDS",
+		"WEBHOOK_DIAL_TIMEOUT_SECONDS",
+		"WEBHOOK_TLS_HANDSHAKE_TIMEOUT_SECONDS",
+		"WEBHOOK_MAX_IDLE_CONNS",
+		"WEBHOOK_MAX_IDLE_CONNS_PER_HOST",
+		"WEBHOOK_HTTP2",
+		"RECONCILE_BATCH_SIZE",
+		"RECONCILE_RECEIPT_AFTER_SECONDS",
+		"RECONCILE_RECHECK_SECONDS",
+		"EVENT_SINKS",
+		"EVENT_FILE_PATH",
+		"EVENT_FILE_MAX_MB",
+		"EVENT_FILE_MAX_BACKUPS",
+		"EVENT_STREAM_KEY",
+		"EVENT_STREAM_MAXLEN",
+		"BREAKER_ENABLED",
+		"BREAKER_FAILURE_RATIO",
+		"BREAKER_MIN_REQUESTS",
+		"BREAKER_WINDOW_SECONDS",
+		"BREAKER_OPEN_SECONDS",
+		"STATS_SLO_OLDEST_PENDING_SECONDS",
+		"STATS_SLO_FAILURE_RATIO",
+		"STATS_SLO_SEND_P50_SECONDS",
+		"STATS_SLO_SEND_P95_SECONDS",
+		"STATS_COMPACT_INTERVAL_SECONDS",
+		"TRACING_ENABLED",
+		"TRACING_ENDPOINT",
+		"TRACING_SERVICE_NAME",
+		"TRACING_SAMPLE_RATIO",
+		"ALERT_WEBHOOK_URL",
+		"ALERT_WEBHOOK_URL_FILE",
+		"ALERT_WEBHOOK_SECRET",
+		"ALERT_WEBHOOK_SECRET_FILE",
+		"ALERT_FAILURE_RATIO",
+		"ALERT_FAILURE_TICKS",
+		"ALERT_OLDEST_PENDING_MINUTES",
+		"ALERT_SCHEDULER_STOPPED_MINUTES",
+		"ALERT_EVAL_INTERVAL_SECONDS",
+		"HEALTH_CHECK_TIMEOUT_SECONDS",
+		"HEALTH_SCHEDULER_STALE_SECONDS",
+		"LOG_FORMAT",
+		"LOG_LEVEL",
+		"REDACT_PHONES",
+		"REDACT_CONTENT",
+		"AUTH_ENABLED",
+		"AUTH_CACHE_SECONDS",
 		"FOO",
 		"A",
 		"N",
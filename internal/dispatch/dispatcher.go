@@ -0,0 +1,158 @@
+package dispatch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+	"github.com/LeventeLantos/automatic-messaging/internal/repo"
+	"github.com/LeventeLantos/automatic-messaging/internal/signature"
+)
+
+const (
+	HeaderEventID   = "X-Event-Id"
+	HeaderEventType = "X-Event-Type"
+
+	// maxErrorBody caps how much of a subscriber's error response is kept.
+	maxErrorBody = 256
+)
+
+// Dispatcher delivers outbox events to subscribers as HMAC-signed JSON POSTs,
+// retrying failed deliveries with exponential backoff.
+type Dispatcher struct {
+	queue     repo.DeliveryQueue
+	client    *http.Client
+	batchSize int
+	timeout   time.Duration
+	lease     time.Duration
+
+	maxAttempts int
+	backoffBase time.Duration
+	backoffMax  time.Duration
+
+	now func() time.Time
+}
+
+// NewDispatcher claims batchSize deliveries at a time. Deliveries are sent one
+// after another, so the claim lease covers a whole batch of timed-out
+// requests plus one timeout to spare; otherwise another instance could
+// re-claim the tail of a slow batch and send those events twice.
+func NewDispatcher(queue repo.DeliveryQueue, batchSize int) *Dispatcher {
+	timeout := 10 * time.Second
+	return &Dispatcher{
+		queue:       queue,
+		client:      &http.Client{Timeout: timeout},
+		batchSize:   batchSize,
+		timeout:     timeout,
+		lease:       time.Duration(batchSize+1) * timeout,
+		maxAttempts: 8,
+		backoffBase: 10 * time.Second,
+		backoffMax:  time.Hour,
+		now:         time.Now,
+	}
+}
+
+// WithRetry sets how many attempts a delivery gets and the backoff between
+// them: base, 2*base, 4*base, ... capped at max.
+func (d *Dispatcher) WithRetry(maxAttempts int, base, max time.Duration) *Dispatcher {
+	d.maxAttempts = maxAttempts
+	d.backoffBase = base
+	d.backoffMax = max
+	return d
+}
+
+// Dispatch sends one batch of due deliveries. It stops before a delivery
+// that could still be running when the lease ends; the deliveries it leaves
+// are claimed again once the lease expires.
+func (d *Dispatcher) Dispatch(ctx context.Context) (delivered int, failed int, err error) {
+	leaseEnd := d.now().Add(d.lease)
+	due, err := d.queue.ClaimDueDeliveries(ctx, d.batchSize, d.lease)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for i, dd := range due {
+		if d.now().Add(d.timeout).After(leaseEnd) {
+			slog.WarnContext(ctx, "claim lease ending, leaving deliveries for the next run", "left", len(due)-i)
+			break
+		}
+
+		status, sendErr := d.deliver(ctx, dd)
+		if sendErr == nil {
+			delivered++
+			if err := d.queue.MarkDeliverySucceeded(ctx, dd.DeliveryID, status); err != nil {
+				slog.ErrorContext(ctx, "failed to mark delivery succeeded", "delivery_id", dd.DeliveryID, "err", err)
+			}
+			continue
+		}
+
+		failed++
+		retryAt := d.nextAttempt(dd.Attempts + 1)
+		if err := d.queue.MarkDeliveryFailed(ctx, dd.DeliveryID, status, sendErr.Error(), retryAt); err != nil {
+			slog.ErrorContext(ctx, "failed to mark delivery failed", "delivery_id", dd.DeliveryID, "err", err)
+		}
+		if retryAt == nil {
+			slog.WarnContext(ctx, "event delivery gave up", "delivery_id", dd.DeliveryID, "event_id", dd.Event.ID, "err", sendErr)
+		}
+	}
+	return delivered, failed, nil
+}
+
+// nextAttempt returns when to retry after the given number of attempts, or nil
+// once the attempts are exhausted.
+func (d *Dispatcher) nextAttempt(attempts int) *time.Time {
+	if attempts >= d.maxAttempts {
+		return nil
+	}
+
+	delay := d.backoffBase
+	for i := 1; i < attempts && delay < d.backoffMax; i++ {
+		delay *= 2
+	}
+	if delay > d.backoffMax {
+		delay = d.backoffMax
+	}
+
+	t := d.now().Add(delay)
+	return &t
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, dd model.DueDelivery) (int, error) {
+	body, err := json.Marshal(dd.Event)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dd.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	ts := signature.Timestamp(d.now())
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(signature.HeaderTimestamp, ts)
+	req.Header.Set(signature.HeaderSignature, signature.Sign([]byte(dd.Secret), ts, body))
+	req.Header.Set(HeaderEventID, strconv.FormatInt(dd.Event.ID, 10))
+	req.Header.Set(HeaderEventType, string(dd.Event.Type))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return resp.StatusCode, fmt.Errorf("unexpected status code: %d body=%q", resp.StatusCode, string(respBody))
+	}
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,232 @@
+package dispatch
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+	"github.com/LeventeLantos/automatic-messaging/internal/signature"
+)
+
+type markedFailure struct {
+	status  int
+	errMsg  string
+	retryAt *time.Time
+}
+
+type fakeQueue struct {
+	mu sync.Mutex
+
+	due       []model.DueDelivery
+	gotLimit  int
+	gotLease  time.Duration
+	succeeded map[int64]int
+	failed    map[int64]markedFailure
+}
+
+func (f *fakeQueue) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]model.DueDelivery, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.gotLimit = limit
+	f.gotLease = lease
+	return f.due, nil
+}
+
+func (f *fakeQueue) MarkDeliverySucceeded(ctx context.Context, id int64, status int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.succeeded == nil {
+		f.succeeded = map[int64]int{}
+	}
+	f.succeeded[id] = status
+	return nil
+}
+
+func (f *fakeQueue) MarkDeliveryFailed(ctx context.Context, id int64, status int, errMsg string, retryAt *time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failed == nil {
+		f.failed = map[int64]markedFailure{}
+	}
+	f.failed[id] = markedFailure{status: status, errMsg: errMsg, retryAt: retryAt}
+	return nil
+}
+
+func TestDispatcher_DeliversSignedEvent(t *testing.T) {
+	t.Parallel()
+
+	secret := "sub-secret"
+	var (
+		gotHeaders http.Header
+		gotBody    []byte
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeaders = r.Header.Clone()
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	q := &fakeQueue{due: []model.DueDelivery{{
+		DeliveryID: 1,
+		URL:        srv.URL,
+		Secret:     secret,
+		Event: model.Event{
+			ID:              42,
+			Type:            model.EventMessageSent,
+			MessageID:       7,
+			Status:          model.Sent,
+			RemoteMessageID: "remote-7",
+		},
+	}}}
+
+	delivered, failed, err := NewDispatcher(q, 10).Dispatch(context.Background())
+	if err != nil {
+		t.Fatalf("Dispatch() error: %v", err)
+	}
+	if delivered != 1 || failed != 0 {
+		t.Fatalf("expected delivered=1 failed=0, got %d/%d", delivered, failed)
+	}
+	if q.gotLimit != 10 {
+		t.Fatalf("expected claim limit 10, got %d", q.gotLimit)
+	}
+	if q.gotLease < 10*10*time.Second {
+		t.Fatalf("expected the lease to cover 10 timed-out deliveries, got %v", q.gotLease)
+	}
+	if q.succeeded[1] != http.StatusNoContent {
+		t.Fatalf("expected delivery 1 marked succeeded with 204, got %v", q.succeeded)
+	}
+
+	if err := signature.Verify(
+		[]byte(secret),
+		gotHeaders.Get(signature.HeaderTimestamp),
+		gotHeaders.Get(signature.HeaderSignature),
+		gotBody,
+		time.Minute,
+		time.Now(),
+	); err != nil {
+		t.Fatalf("expected a valid signature, got %v", err)
+	}
+	if gotHeaders.Get(HeaderEventID) != "42" || gotHeaders.Get(HeaderEventType) != "message.sent" {
+		t.Fatalf("unexpected event headers: %v", gotHeaders)
+	}
+
+	var ev model.Event
+	if err := json.Unmarshal(gotBody, &ev); err != nil {
+		t.Fatalf("failed to decode event: %v", err)
+	}
+	if ev.ID != 42 || ev.MessageID != 7 || ev.RemoteMessageID != "remote-7" {
+		t.Fatalf("unexpected event payload: %+v", ev)
+	}
+}
+
+func TestDispatcher_FailureSchedulesRetryWithBackoff(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte("try later"))
+	}))
+	defer srv.Close()
+
+	now := time.Date(2026, 2, 2, 18, 0, 0, 0, time.UTC)
+	q := &fakeQueue{due: []model.DueDelivery{
+		{DeliveryID: 1, Attempts: 0, URL: srv.URL, Event: model.Event{ID: 1}},
+		{DeliveryID: 2, Attempts: 2, URL: srv.URL, Event: model.Event{ID: 2}},
+		{DeliveryID: 3, Attempts: 4, URL: srv.URL, Event: model.Event{ID: 3}},
+	}}
+
+	d := NewDispatcher(q, 10).WithRetry(5, 10*time.Second, 30*time.Second)
+	d.now = func() time.Time { return now }
+
+	delivered, failed, err := d.Dispatch(context.Background())
+	if err != nil {
+		t.Fatalf("Dispatch() error: %v", err)
+	}
+	if delivered != 0 || failed != 3 {
+		t.Fatalf("expected delivered=0 failed=3, got %d/%d", delivered, failed)
+	}
+
+	first := q.failed[1]
+	if first.status != http.StatusServiceUnavailable || !strings.Contains(first.errMsg, "try later") {
+		t.Fatalf("unexpected failure record: %+v", first)
+	}
+	if first.retryAt == nil || !first.retryAt.Equal(now.Add(10*time.Second)) {
+		t.Fatalf("expected first retry after 10s, got %v", first.retryAt)
+	}
+
+	// Third attempt would wait 40s but is capped at 30s.
+	if third := q.failed[2].retryAt; third == nil || !third.Equal(now.Add(30*time.Second)) {
+		t.Fatalf("expected capped retry after 30s, got %v", third)
+	}
+
+	if last := q.failed[3].retryAt; last != nil {
+		t.Fatalf("expected no retry after max attempts, got %v", last)
+	}
+}
+
+func TestDispatcher_NetworkErrorHasNoStatus(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := srv.URL
+	srv.Close()
+
+	q := &fakeQueue{due: []model.DueDelivery{{DeliveryID: 1, URL: url, Event: model.Event{ID: 1}}}}
+
+	_, failed, err := NewDispatcher(q, 10).Dispatch(context.Background())
+	if err != nil {
+		t.Fatalf("Dispatch() error: %v", err)
+	}
+	if failed != 1 {
+		t.Fatalf("expected failed=1, got %d", failed)
+	}
+	if got := q.failed[1]; got.status != 0 || got.retryAt == nil {
+		t.Fatalf("expected retry without status, got %+v", got)
+	}
+}
+
+func TestDispatcher_SlowSubscriberStopsBeforeLeaseEnds(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(150 * time.Millisecond)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	q := &fakeQueue{}
+	for id := int64(1); id <= 5; id++ {
+		q.due = append(q.due, model.DueDelivery{DeliveryID: id, URL: srv.URL, Secret: "s", Event: model.Event{ID: id}})
+	}
+
+	// Each delivery takes 150ms, so after two only 100ms of the lease is left,
+	// less than a delivery may take.
+	d := NewDispatcher(q, 5)
+	d.client.Timeout = 200 * time.Millisecond
+	d.timeout = 200 * time.Millisecond
+	d.lease = 400 * time.Millisecond
+
+	delivered, failed, err := d.Dispatch(context.Background())
+	if err != nil {
+		t.Fatalf("Dispatch() error: %v", err)
+	}
+	if delivered != 2 || failed != 0 {
+		t.Fatalf("expected delivered=2 failed=0, got %d/%d", delivered, failed)
+	}
+	for id := int64(3); id <= 5; id++ {
+		if _, ok := q.succeeded[id]; ok {
+			t.Fatalf("expected delivery %d to be left for the next run", id)
+		}
+		if _, ok := q.failed[id]; ok {
+			t.Fatalf("expected delivery %d to be left unmarked", id)
+		}
+	}
+}
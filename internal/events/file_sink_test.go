@@ -0,0 +1,129 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+)
+
+func testEvent(id int64) model.Event {
+	return model.Event{
+		Type:            model.EventMessageSent,
+		OccurredAt:      time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		MessageID:       id,
+		Status:          model.Sent,
+		RemoteMessageID: "r-1",
+	}
+}
+
+func readLines(t *testing.T, path string) []model.Event {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open %s: %v", path, err)
+	}
+	defer f.Close()
+
+	var out []model.Event
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var ev model.Event
+		if err := json.Unmarshal(sc.Bytes(), &ev); err != nil {
+			t.Fatalf("invalid NDJSON line %q: %v", sc.Text(), err)
+		}
+		out = append(out, ev)
+	}
+	return out
+}
+
+func TestFileSink_WritesVersionedNDJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.ndjson")
+
+	s, err := NewFileSink(path, 1<<20, 1)
+	if err != nil {
+		t.Fatalf("NewFileSink: %v", err)
+	}
+	for i := int64(1); i <= 3; i++ {
+		if err := s.Publish(context.Background(), testEvent(i)); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	got := readLines(t, path)
+	if len(got) != 3 {
+		t.Fatalf("expected 3 lines, got %d", len(got))
+	}
+	for i, ev := range got {
+		if ev.Version != model.EventSchemaVersion || ev.MessageID != int64(i+1) || ev.Type != model.EventMessageSent {
+			t.Fatalf("unexpected event %d: %+v", i, ev)
+		}
+	}
+
+	if err := s.Publish(context.Background(), testEvent(4)); err == nil {
+		t.Fatalf("expected error publishing to a closed sink")
+	}
+}
+
+func TestFileSink_RotatesAndKeepsBackups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.ndjson")
+
+	line, _ := Encode(testEvent(1))
+	// Room for exactly two lines per file.
+	s, err := NewFileSink(path, int64(2*(len(line)+1)), 2)
+	if err != nil {
+		t.Fatalf("NewFileSink: %v", err)
+	}
+	defer s.Close()
+
+	for i := int64(1); i <= 7; i++ {
+		if err := s.Publish(context.Background(), testEvent(i)); err != nil {
+			t.Fatalf("Publish %d: %v", i, err)
+		}
+	}
+
+	cur := readLines(t, path)
+	b1 := readLines(t, path+".1")
+	b2 := readLines(t, path+".2")
+
+	if len(cur) != 1 || cur[0].MessageID != 7 {
+		t.Fatalf("unexpected current file: %+v", cur)
+	}
+	if len(b1) != 2 || b1[0].MessageID != 5 {
+		t.Fatalf("unexpected first backup: %+v", b1)
+	}
+	if len(b2) != 2 || b2[0].MessageID != 3 {
+		t.Fatalf("unexpected second backup: %+v", b2)
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Fatalf("expected no third backup, stat err=%v", err)
+	}
+}
+
+func TestFileSink_AppendsToExistingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.ndjson")
+
+	for i := int64(1); i <= 2; i++ {
+		s, err := NewFileSink(path, 1<<20, 0)
+		if err != nil {
+			t.Fatalf("NewFileSink: %v", err)
+		}
+		if err := s.Publish(context.Background(), testEvent(i)); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+		_ = s.Close()
+	}
+
+	if got := readLines(t, path); len(got) != 2 {
+		t.Fatalf("expected 2 lines across reopen, got %d", len(got))
+	}
+}
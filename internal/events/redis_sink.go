@@ -0,0 +1,44 @@
+package events
+
+import (
+	"context"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStreamSink appends events to a Redis stream with XADD. Each entry has
+// "version", "type" and "event" (the JSON-encoded event) fields. The stream is
+// trimmed to roughly maxLen entries; 0 disables trimming.
+type RedisStreamSink struct {
+	rdb    *redis.Client
+	stream string
+	maxLen int64
+}
+
+// NewRedisStreamSink reuses rdb; closing the sink does not close the client.
+func NewRedisStreamSink(rdb *redis.Client, stream string, maxLen int64) *RedisStreamSink {
+	return &RedisStreamSink{rdb: rdb, stream: stream, maxLen: maxLen}
+}
+
+func (s *RedisStreamSink) Publish(ctx context.Context, ev model.Event) error {
+	data, err := Encode(ev)
+	if err != nil {
+		return err
+	}
+
+	return s.rdb.XAdd(ctx, &redis.XAddArgs{
+		Stream: s.stream,
+		MaxLen: s.maxLen,
+		Approx: s.maxLen > 0,
+		Values: map[string]any{
+			"version": model.EventSchemaVersion,
+			"type":    string(ev.Type),
+			"event":   data,
+		},
+	}).Err()
+}
+
+func (s *RedisStreamSink) Close() error {
+	return nil
+}
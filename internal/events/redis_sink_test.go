@@ -0,0 +1,105 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRedisStreamSink_Publish(t *testing.T) {
+	t.Parallel()
+
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	s := NewRedisStreamSink(rdb, "message-events", 0)
+
+	ctx := context.Background()
+	if err := s.Publish(ctx, testEvent(42)); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	entries, err := rdb.XRange(ctx, "message-events", "-", "+").Result()
+	if err != nil {
+		t.Fatalf("XRange: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 stream entry, got %d", len(entries))
+	}
+
+	vals := entries[0].Values
+	if vals["type"] != "message.sent" || vals["version"] != "1" {
+		t.Fatalf("unexpected entry fields: %v", vals)
+	}
+
+	var ev model.Event
+	if err := json.Unmarshal([]byte(vals["event"].(string)), &ev); err != nil {
+		t.Fatalf("event field is not JSON: %v", err)
+	}
+	if ev.MessageID != 42 || ev.Version != model.EventSchemaVersion {
+		t.Fatalf("unexpected event: %+v", ev)
+	}
+}
+
+func TestRedisStreamSink_TrimsToMaxLen(t *testing.T) {
+	t.Parallel()
+
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	s := NewRedisStreamSink(rdb, "message-events", 3)
+
+	ctx := context.Background()
+	for i := int64(1); i <= 10; i++ {
+		if err := s.Publish(ctx, testEvent(i)); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+	}
+
+	n, err := rdb.XLen(ctx, "message-events").Result()
+	if err != nil {
+		t.Fatalf("XLen: %v", err)
+	}
+	if n > 3 {
+		t.Fatalf("expected stream trimmed to 3 entries, got %d", n)
+	}
+}
+
+type stubSink struct {
+	got    []model.Event
+	err    error
+	closed bool
+}
+
+func (s *stubSink) Publish(_ context.Context, ev model.Event) error {
+	s.got = append(s.got, ev)
+	return s.err
+}
+
+func (s *stubSink) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestMulti_FansOutAndJoinsErrors(t *testing.T) {
+	t.Parallel()
+
+	boom := errors.New("boom")
+	a, b := &stubSink{}, &stubSink{err: boom}
+	m := Multi(a, b)
+
+	err := m.Publish(context.Background(), testEvent(1))
+	if !errors.Is(err, boom) {
+		t.Fatalf("expected joined error, got %v", err)
+	}
+	if len(a.got) != 1 || len(b.got) != 1 {
+		t.Fatalf("expected both sinks to receive the event: a=%d b=%d", len(a.got), len(b.got))
+	}
+
+	if err := m.Close(); err != nil || !a.closed || !b.closed {
+		t.Fatalf("expected both sinks closed, err=%v", err)
+	}
+}
@@ -0,0 +1,51 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+)
+
+// Sink receives message lifecycle events. Implementations must be safe for
+// concurrent use.
+type Sink interface {
+	Publish(ctx context.Context, ev model.Event) error
+	Close() error
+}
+
+// Encode returns the versioned JSON form of ev shared by all sinks.
+func Encode(ev model.Event) ([]byte, error) {
+	if ev.Version == 0 {
+		ev.Version = model.EventSchemaVersion
+	}
+	return json.Marshal(ev)
+}
+
+type multiSink []Sink
+
+// Multi fans every event out to all sinks and joins their errors.
+func Multi(sinks ...Sink) Sink {
+	return multiSink(sinks)
+}
+
+func (m multiSink) Publish(ctx context.Context, ev model.Event) error {
+	var errs []error
+	for _, s := range m {
+		if err := s.Publish(ctx, ev); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m multiSink) Close() error {
+	var errs []error
+	for _, s := range m {
+		if err := s.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,62 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestNew_AddsContextAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, FormatJSON, slog.LevelInfo)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+
+	ctx := With(context.Background(), "request_id", "abc")
+	ctx = With(ctx, slog.Int64("tick", 7))
+	logger.With("component", "test").InfoContext(ctx, "hello")
+	logger.DebugContext(ctx, "hidden")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("expected one line at info level, got %q", buf.String())
+	}
+
+	var rec map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &rec); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if rec["request_id"] != "abc" || rec["tick"] != float64(7) || rec["component"] != "test" {
+		t.Fatalf("unexpected record: %v", rec)
+	}
+}
+
+func TestNew_Text(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, FormatText, slog.LevelDebug)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+
+	logger.DebugContext(With(context.Background(), "tick_id", "t1"), "tick")
+	if !strings.Contains(buf.String(), "tick_id=t1") {
+		t.Fatalf("expected tick_id in %q", buf.String())
+	}
+}
+
+func TestNew_UnknownFormat(t *testing.T) {
+	if _, err := New(&bytes.Buffer{}, "xml", slog.LevelInfo); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestNewID(t *testing.T) {
+	a, b := NewID(), NewID()
+	if len(a) != 16 || a == b {
+		t.Fatalf("expected distinct 16-char IDs, got %q and %q", a, b)
+	}
+}
@@ -0,0 +1,284 @@
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/breaker"
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+	"github.com/LeventeLantos/automatic-messaging/internal/repo"
+	"github.com/LeventeLantos/automatic-messaging/internal/service"
+)
+
+const namespace = "messaging"
+
+// Tick outcomes used as the outcome label of the tick duration histogram.
+const (
+	TickSkipped   = "skipped"
+	TickError     = "error"
+	TickEmpty     = "empty"
+	TickProcessed = "processed"
+)
+
+// Metrics owns a registry with the service's collectors. Every label takes
+// values from a closed set (tick outcomes, error classes, status classes,
+// route patterns) or from configuration (provider names), never from request
+// data, so cardinality stays bounded.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	tickDuration *prometheus.HistogramVec
+	tickMessages *prometheus.CounterVec
+
+	webhookDuration  *prometheus.HistogramVec
+	webhookResponses *prometheus.CounterVec
+	webhookErrors    *prometheus.CounterVec
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		tickDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "scheduler",
+			Name:      "tick_duration_seconds",
+			Help:      "Duration of scheduler ticks by outcome.",
+			Buckets:   []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+		}, []string{"outcome"}),
+		tickMessages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "scheduler",
+			Name:      "messages_total",
+			Help:      "Messages claimed, sent and failed by scheduler ticks.",
+		}, []string{"result"}),
+		webhookDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "webhook",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of provider send requests.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"provider"}),
+		webhookResponses: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "webhook",
+			Name:      "responses_total",
+			Help:      "Provider responses by status class.",
+		}, []string{"provider", "code"}),
+		webhookErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "webhook",
+			Name:      "errors_total",
+			Help:      "Failed provider sends by error class.",
+		}, []string{"provider", "class"}),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "API requests by method, route and status code.",
+		}, []string{"method", "route", "code"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "API request latency by method and route.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.tickDuration,
+		m.tickMessages,
+		m.webhookDuration,
+		m.webhookResponses,
+		m.webhookErrors,
+		m.httpRequests,
+		m.httpDuration,
+	)
+	return m
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveTick records one scheduler tick.
+func (m *Metrics) ObserveTick(outcome string, d time.Duration, claimed, sent, failed int) {
+	m.tickDuration.WithLabelValues(outcome).Observe(d.Seconds())
+	m.tickMessages.WithLabelValues("claimed").Add(float64(claimed))
+	m.tickMessages.WithLabelValues("sent").Add(float64(sent))
+	m.tickMessages.WithLabelValues("failed").Add(float64(failed))
+}
+
+// WebhookObserver returns a callback for client.WebhookClient.WithObserver
+// that records sends to provider.
+func (m *Metrics) WebhookObserver(provider string) func(res model.SendResult, d time.Duration, err error) {
+	return func(res model.SendResult, d time.Duration, err error) {
+		m.webhookDuration.WithLabelValues(provider).Observe(d.Seconds())
+		if res.StatusCode != 0 {
+			m.webhookResponses.WithLabelValues(provider, statusClass(res.StatusCode)).Inc()
+		}
+		if class := service.ClassifyError(res, err); class != "" {
+			m.webhookErrors.WithLabelValues(provider, class).Inc()
+		}
+	}
+}
+
+// ObserveHTTP records one API request. pattern is the ServeMux pattern that
+// matched, e.g. "GET /v1/messages/{id}", or "" when none did.
+func (m *Metrics) ObserveHTTP(method, pattern string, status int, d time.Duration) {
+	method = normalizeMethod(method)
+	route := routeLabel(pattern)
+	m.httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	m.httpDuration.WithLabelValues(method, route).Observe(d.Seconds())
+}
+
+// QueueStatsSource reports the messages waiting to be sent.
+type QueueStatsSource interface {
+	QueueStats(ctx context.Context) (repo.QueueStats, error)
+}
+
+// RegisterQueue exports queue depth and the age of the oldest pending message,
+// queried from src on every scrape.
+func (m *Metrics) RegisterQueue(src QueueStatsSource, timeout time.Duration) {
+	m.registry.MustRegister(&queueCollector{src: src, timeout: timeout, now: time.Now})
+}
+
+// RegisterBreaker exports the circuit breaker state.
+func (m *Metrics) RegisterBreaker(b *breaker.Breaker) {
+	m.registry.MustRegister(&breakerCollector{b: b})
+}
+
+var (
+	queueDepthDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "queue", "messages"),
+		"Messages waiting to be sent, by status.",
+		[]string{"status"}, nil,
+	)
+	queueOldestDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "queue", "oldest_pending_age_seconds"),
+		"Age of the oldest pending message; 0 when none is pending.",
+		nil, nil,
+	)
+)
+
+type queueCollector struct {
+	src     QueueStatsSource
+	timeout time.Duration
+	now     func() time.Time
+}
+
+func (c *queueCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- queueDepthDesc
+	ch <- queueOldestDesc
+}
+
+// Collect exports nothing when the query fails, so a broken database shows as
+// missing series rather than an empty queue.
+func (c *queueCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	st, err := c.src.QueueStats(ctx)
+	if err != nil {
+		slog.Warn("failed to collect queue metrics", "err", err)
+		return
+	}
+
+	for status, n := range map[model.Status]int64{
+		model.Pending:    st.Pending,
+		model.Processing: st.Processing,
+		model.Sending:    st.Sending,
+	} {
+		ch <- prometheus.MustNewConstMetric(queueDepthDesc, prometheus.GaugeValue, float64(n), string(status))
+	}
+
+	var age float64
+	if st.OldestPending != nil {
+		age = max(c.now().Sub(*st.OldestPending).Seconds(), 0)
+	}
+	ch <- prometheus.MustNewConstMetric(queueOldestDesc, prometheus.GaugeValue, age)
+}
+
+var (
+	breakerStateDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "breaker", "state"),
+		"Circuit breaker state; 1 for the current state, 0 otherwise.",
+		[]string{"state"}, nil,
+	)
+	breakerOpensDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "breaker", "opens_total"),
+		"Times the circuit breaker opened.",
+		nil, nil,
+	)
+	breakerRejectedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "breaker", "rejected_total"),
+		"Sends rejected while the circuit breaker was open.",
+		nil, nil,
+	)
+)
+
+type breakerCollector struct {
+	b *breaker.Breaker
+}
+
+func (c *breakerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- breakerStateDesc
+	ch <- breakerOpensDesc
+	ch <- breakerRejectedDesc
+}
+
+func (c *breakerCollector) Collect(ch chan<- prometheus.Metric) {
+	st := c.b.Status()
+	for _, s := range []breaker.State{breaker.Closed, breaker.Open, breaker.HalfOpen} {
+		var v float64
+		if st.State == s {
+			v = 1
+		}
+		ch <- prometheus.MustNewConstMetric(breakerStateDesc, prometheus.GaugeValue, v, string(s))
+	}
+	ch <- prometheus.MustNewConstMetric(breakerOpensDesc, prometheus.CounterValue, float64(st.Opens))
+	ch <- prometheus.MustNewConstMetric(breakerRejectedDesc, prometheus.CounterValue, float64(st.Rejected))
+}
+
+func statusClass(code int) string {
+	if code < 100 || code > 599 {
+		return "other"
+	}
+	return strconv.Itoa(code/100) + "xx"
+}
+
+func normalizeMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+		http.MethodPatch, http.MethodDelete, http.MethodOptions:
+		return method
+	default:
+		return "other"
+	}
+}
+
+// routeLabel strips the method from a ServeMux pattern. Unmatched requests
+// share one label so scanning for random paths cannot add series.
+func routeLabel(pattern string) string {
+	if pattern == "" {
+		return "unmatched"
+	}
+	if _, path, ok := strings.Cut(pattern, " "); ok {
+		return path
+	}
+	return pattern
+}
@@ -0,0 +1,99 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+	"github.com/LeventeLantos/automatic-messaging/internal/repo"
+)
+
+func TestObserveHTTP_BoundsLabels(t *testing.T) {
+	m := New()
+
+	m.ObserveHTTP(http.MethodGet, "GET /v1/messages/{id}", 200, time.Millisecond)
+	m.ObserveHTTP(http.MethodGet, "GET /v1/messages/{id}", 200, time.Millisecond)
+	m.ObserveHTTP("PROPFIND", "", 404, time.Millisecond)
+
+	if got := testutil.ToFloat64(m.httpRequests.WithLabelValues("GET", "/v1/messages/{id}", "200")); got != 2 {
+		t.Fatalf("expected 2 requests for the route pattern, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.httpRequests.WithLabelValues("other", "unmatched", "404")); got != 1 {
+		t.Fatalf("expected the unknown method and path to be folded, got %v", got)
+	}
+}
+
+func TestWebhookObserver(t *testing.T) {
+	m := New()
+	observe := m.WebhookObserver("primary")
+
+	observe(model.SendResult{StatusCode: 202, RemoteMessageID: "r"}, 10*time.Millisecond, nil)
+	observe(model.SendResult{StatusCode: 503}, 10*time.Millisecond, errors.New("unexpected status code: 503"))
+	observe(model.SendResult{}, time.Second, context.DeadlineExceeded)
+
+	cases := []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{"2xx responses", testutil.ToFloat64(m.webhookResponses.WithLabelValues("primary", "2xx")), 1},
+		{"5xx responses", testutil.ToFloat64(m.webhookResponses.WithLabelValues("primary", "5xx")), 1},
+		{"http_5xx errors", testutil.ToFloat64(m.webhookErrors.WithLabelValues("primary", "http_5xx")), 1},
+		{"timeout errors", testutil.ToFloat64(m.webhookErrors.WithLabelValues("primary", "timeout")), 1},
+	}
+	for _, tc := range cases {
+		if tc.got != tc.want {
+			t.Fatalf("%s: expected %v, got %v", tc.name, tc.want, tc.got)
+		}
+	}
+	if n := testutil.CollectAndCount(m.webhookResponses); n != 2 {
+		t.Fatalf("expected no response series for the timeout, got %d series", n)
+	}
+}
+
+type fakeQueue struct {
+	stats repo.QueueStats
+	err   error
+}
+
+func (f fakeQueue) QueueStats(ctx context.Context) (repo.QueueStats, error) {
+	return f.stats, f.err
+}
+
+func TestQueueCollector(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	oldest := now.Add(-90 * time.Second)
+	c := &queueCollector{
+		src:     fakeQueue{stats: repo.QueueStats{Pending: 7, Sending: 1, OldestPending: &oldest}},
+		timeout: time.Second,
+		now:     func() time.Time { return now },
+	}
+
+	want := `
+# HELP messaging_queue_messages Messages waiting to be sent, by status.
+# TYPE messaging_queue_messages gauge
+messaging_queue_messages{status="pending"} 7
+messaging_queue_messages{status="processing"} 0
+messaging_queue_messages{status="sending"} 1
+# HELP messaging_queue_oldest_pending_age_seconds Age of the oldest pending message; 0 when none is pending.
+# TYPE messaging_queue_oldest_pending_age_seconds gauge
+messaging_queue_oldest_pending_age_seconds 90
+`
+	if err := testutil.CollectAndCompare(c, strings.NewReader(want)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestQueueCollector_OmitsSeriesOnError(t *testing.T) {
+	c := &queueCollector{src: fakeQueue{err: errors.New("db down")}, timeout: time.Second, now: time.Now}
+
+	if n := testutil.CollectAndCount(c); n != 0 {
+		t.Fatalf("expected no series when the query fails, got %d", n)
+	}
+}
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// APIKey is a client credential. The key itself is only shown once, when it
+// is created; Prefix identifies it afterwards. Operator keys have no TenantID
+// and act on every tenant.
+type APIKey struct {
+	ID        int64      `json:"id"`
+	Name      string     `json:"name"`
+	TenantID  string     `json:"tenantId,omitempty"`
+	Prefix    string     `json:"prefix"`
+	Scopes    []string   `json:"scopes"`
+	CreatedAt time.Time  `json:"createdAt"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+}
@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// SendResult is what a SendClient observed from the provider. StatusCode and
+// Body are also set on failed sends when the provider did respond. Provider
+// names the provider that produced the result when sends are routed.
+type SendResult struct {
+	RemoteMessageID string
+	StatusCode      int
+	Body            string
+	Provider        string
+}
+
+// Attempt records a single SendClient.Send call for a message.
+type Attempt struct {
+	ID              int64
+	MessageID       int64
+	StartedAt       time.Time
+	FinishedAt      time.Time
+	HTTPStatus      *int
+	ErrorClass      *string
+	ResponseBody    *string
+	RemoteMessageID *string
+	Provider        *string
+	InstanceID      string
+}
@@ -0,0 +1,36 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type AuditAction string
+
+const (
+	AuditSchedulerStart     AuditAction = "scheduler.start"
+	AuditSchedulerStop      AuditAction = "scheduler.stop"
+	AuditMessagesRequeue    AuditAction = "messages.requeue"
+	AuditMessageCancel      AuditAction = "message.cancel"
+	AuditMessageUpdate      AuditAction = "message.update"
+	AuditRoutePut           AuditAction = "route.put"
+	AuditRouteDelete        AuditAction = "route.delete"
+	AuditSubscriptionCreate AuditAction = "subscription.create"
+	AuditSubscriptionDelete AuditAction = "subscription.delete"
+	AuditReconciliationRun  AuditAction = "reconciliation.run"
+	AuditTenantUpdate       AuditAction = "tenant.update"
+)
+
+// AuditEvent records who took an administrative action, on what, and with
+// which parameters. Actor is "apikey:<prefix>" for authenticated requests and
+// "anonymous" when authentication is disabled.
+type AuditEvent struct {
+	ID         int64           `json:"id"`
+	Actor      string          `json:"actor"`
+	TenantID   string          `json:"tenantId,omitempty"`
+	Action     AuditAction     `json:"action"`
+	Target     string          `json:"target,omitempty"`
+	Params     json.RawMessage `json:"params,omitempty"`
+	SourceIP   string          `json:"sourceIp,omitempty"`
+	OccurredAt time.Time       `json:"occurredAt"`
+}
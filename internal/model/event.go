@@ -0,0 +1,93 @@
+package model
+
+import "time"
+
+// EventSchemaVersion is bumped on any incompatible change to Event's JSON.
+const EventSchemaVersion = 1
+
+type EventType string
+
+const (
+	EventMessageSent        EventType = "message.sent"
+	EventMessageFailed      EventType = "message.failed"
+	EventMessageDelivered   EventType = "message.delivered"
+	EventMessageUndelivered EventType = "message.undelivered"
+)
+
+var EventTypes = []EventType{
+	EventMessageSent,
+	EventMessageFailed,
+	EventMessageDelivered,
+	EventMessageUndelivered,
+}
+
+func (t EventType) Valid() bool {
+	for _, known := range EventTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Event is a message lifecycle change as published to subscribers and event
+// sinks. Its JSON form is the public contract, so fields are tagged explicitly.
+// ID is only set for events that went through the outbox.
+type Event struct {
+	Version         int       `json:"version"`
+	ID              int64     `json:"id,omitempty"`
+	Type            EventType `json:"type"`
+	OccurredAt      time.Time `json:"occurredAt"`
+	MessageID       int64     `json:"messageId"`
+	TenantID        string    `json:"tenantId,omitempty"`
+	Status          Status    `json:"status"`
+	RemoteMessageID string    `json:"remoteMessageId,omitempty"`
+	Provider        string    `json:"provider,omitempty"`
+	Reason          string    `json:"reason,omitempty"`
+	ErrorCode       string    `json:"errorCode,omitempty"`
+}
+
+// Subscription receives the events of its tenant, or of every tenant when
+// TenantID is empty.
+type Subscription struct {
+	ID         int64
+	TenantID   string
+	URL        string
+	Secret     string
+	EventTypes []EventType
+	Active     bool
+	CreatedAt  time.Time
+}
+
+type DeliveryState string
+
+const (
+	DeliveryPending   DeliveryState = "pending"
+	DeliverySucceeded DeliveryState = "succeeded"
+	DeliveryFailed    DeliveryState = "failed"
+)
+
+// EventDelivery tracks one event being delivered to one subscription.
+type EventDelivery struct {
+	ID             int64
+	EventID        int64
+	SubscriptionID int64
+	EventType      EventType
+	State          DeliveryState
+	Attempts       int
+	NextAttemptAt  time.Time
+	LastStatus     *int
+	LastError      *string
+	DeliveredAt    *time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// DueDelivery is a claimed delivery together with what is needed to send it.
+type DueDelivery struct {
+	DeliveryID int64
+	Attempts   int
+	URL        string
+	Secret     string
+	Event      Event
+}
@@ -7,12 +7,20 @@ type Status string
 const (
 	Pending    Status = "pending"
 	Processing Status = "processing"
+	Sending    Status = "sending"
 	Sent       Status = "sent"
 	Failed     Status = "failed"
+	Cancelled  Status = "cancelled"
+
+	// Delivery receipt outcomes for messages that were sent.
+	Delivered   Status = "delivered"
+	Undelivered Status = "undelivered"
+	Unknown     Status = "unknown"
 )
 
 type Message struct {
 	ID             int64
+	TenantID       string
 	RecipientPhone string
 	Content        string
 	Status         Status
@@ -21,6 +29,22 @@ type Message struct {
 	LastError       *string
 	SentAt          *time.Time
 	RemoteMessageID *string
-	CreatedAt       time.Time
-	UpdatedAt       time.Time
+	Provider        *string
+	RequeueCount    int
+	RequeueNote     *string
+	RequeuedAt      *time.Time
+
+	DeliveryStatusAt  *time.Time
+	DeliveryErrorCode *string
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Receipt is a provider's delivery report for a sent message.
+type Receipt struct {
+	RemoteMessageID string
+	Status          Status
+	At              time.Time
+	ErrorCode       string
 }
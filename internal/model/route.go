@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// Route sends messages whose recipient starts with Prefix (E.164, e.g. "+36")
+// through Provider. The longest matching prefix wins; "+" matches every
+// number. SenderID, when set, overrides the provider's default sender.
+type Route struct {
+	Prefix    string
+	Provider  string
+	SenderID  *string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
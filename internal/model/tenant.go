@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// Tenant is a team sharing the deployment. Provider and SenderID, when set,
+// override the routing table for the tenant's messages.
+type Tenant struct {
+	ID        string
+	Provider  *string
+	SenderID  *string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
@@ -0,0 +1,266 @@
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+	"github.com/LeventeLantos/automatic-messaging/internal/redact"
+	"github.com/LeventeLantos/automatic-messaging/internal/repo"
+	"github.com/LeventeLantos/automatic-messaging/internal/service"
+)
+
+type Outcome string
+
+const (
+	MarkedSent     Outcome = "marked_sent"
+	ReceiptApplied Outcome = "receipt_applied"
+	Unchanged      Outcome = "unchanged"
+	NotFound       Outcome = "not_found"
+	Failed         Outcome = "error"
+)
+
+// Item is the result of asking the provider about one message.
+type Item struct {
+	MessageID      int64        `json:"messageId"`
+	PreviousStatus model.Status `json:"previousStatus"`
+	ProviderStatus model.Status `json:"providerStatus,omitempty"`
+	Provider       string       `json:"provider,omitempty"`
+	Outcome        Outcome      `json:"outcome"`
+	Error          string       `json:"error,omitempty"`
+}
+
+// Report summarizes one reconciliation run. Released lists the stuck messages
+// returned to pending; Items lists every provider lookup.
+type Report struct {
+	StartedAt       time.Time `json:"startedAt"`
+	FinishedAt      time.Time `json:"finishedAt"`
+	StatusLookups   bool      `json:"statusLookups"`
+	Checked         int       `json:"checked"`
+	MarkedSent      int       `json:"markedSent"`
+	ReceiptsApplied int       `json:"receiptsApplied"`
+	NotFound        int       `json:"notFound"`
+	Errors          int       `json:"errors"`
+	Released        []int64   `json:"released"`
+	Items           []Item    `json:"items"`
+}
+
+// Settings control which rows a run looks at. Rows in processing or sending
+// for StuckAfter are stuck; sent rows without a receipt after ReceiptAfter are
+// looked up at most once per RecheckAfter. BatchSize caps each lookup query.
+type Settings struct {
+	StuckAfter   time.Duration
+	ReceiptAfter time.Duration
+	RecheckAfter time.Duration
+	BatchSize    int
+}
+
+// Reconciler resolves messages whose outcome is unknown. With a status client
+// it first asks the provider about ambiguous sends and late receipts; in any
+// case it then returns stuck rows to pending, to be resent with the same
+// idempotency key.
+type Reconciler struct {
+	repo      repo.MessageRepository
+	status    service.StatusClient
+	settings  Settings
+	onChanged func(ctx context.Context, id int64)
+
+	runMu sync.Mutex
+	mu    sync.Mutex
+	last  *Report
+
+	now func() time.Time
+}
+
+func New(r repo.MessageRepository, s Settings) *Reconciler {
+	return &Reconciler{repo: r, settings: s, now: time.Now}
+}
+
+// WithStatusClient enables provider lookups.
+func (r *Reconciler) WithStatusClient(sc service.StatusClient) *Reconciler {
+	r.status = sc
+	return r
+}
+
+// WithChangeHook registers a hook called for every message a run changed,
+// e.g. to invalidate caches.
+func (r *Reconciler) WithChangeHook(onChanged func(ctx context.Context, id int64)) *Reconciler {
+	r.onChanged = onChanged
+	return r
+}
+
+// Run performs one reconciliation pass. Concurrent calls are serialized. The
+// report is kept for LastReport even when the run fails part way.
+func (r *Reconciler) Run(ctx context.Context) (Report, error) {
+	r.runMu.Lock()
+	defer r.runMu.Unlock()
+
+	now := r.now()
+	rep := Report{
+		StartedAt:     now.UTC(),
+		StatusLookups: r.status != nil,
+		Released:      []int64{},
+		Items:         []Item{},
+	}
+	err := r.run(ctx, now, &rep)
+	rep.FinishedAt = r.now().UTC()
+
+	r.mu.Lock()
+	r.last = &rep
+	r.mu.Unlock()
+	return rep, err
+}
+
+// LastReport returns the report of the most recent run.
+func (r *Reconciler) LastReport() (Report, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.last == nil {
+		return Report{}, false
+	}
+	return *r.last, true
+}
+
+func (r *Reconciler) run(ctx context.Context, now time.Time, rep *Report) error {
+	stuckBefore := now.Add(-r.settings.StuckAfter)
+
+	if r.status != nil {
+		msgs, err := r.repo.ListAmbiguous(ctx, stuckBefore, r.settings.BatchSize)
+		if err != nil {
+			return fmt.Errorf("list ambiguous: %w", err)
+		}
+		r.checkAll(ctx, msgs, rep)
+	}
+
+	released, err := r.repo.ReleaseStuck(ctx, stuckBefore)
+	if err != nil {
+		return fmt.Errorf("release stuck: %w", err)
+	}
+	if released != nil {
+		rep.Released = released
+	}
+	for _, id := range released {
+		r.changed(ctx, id)
+	}
+
+	if r.status != nil {
+		msgs, err := r.repo.ListAwaitingReceipt(ctx,
+			now.Add(-r.settings.ReceiptAfter),
+			now.Add(-r.settings.RecheckAfter),
+			r.settings.BatchSize,
+		)
+		if err != nil {
+			return fmt.Errorf("list awaiting receipt: %w", err)
+		}
+		r.checkAll(ctx, msgs, rep)
+	}
+	return nil
+}
+
+func (r *Reconciler) checkAll(ctx context.Context, msgs []model.Message, rep *Report) {
+	for _, m := range msgs {
+		if ctx.Err() != nil {
+			return
+		}
+
+		item := r.check(ctx, m)
+		if err := r.repo.MarkStatusChecked(ctx, m.ID); err != nil {
+			slog.WarnContext(ctx, "failed to record status check", "id", m.ID, "err", err)
+		}
+
+		rep.Checked++
+		switch item.Outcome {
+		case NotFound:
+			rep.NotFound++
+		case Failed:
+			rep.Errors++
+		case MarkedSent, ReceiptApplied:
+			// A receipt may be applied right after marking the message sent.
+			if m.Status == model.Sending || m.Status == model.Failed {
+				rep.MarkedSent++
+			}
+			if item.Outcome == ReceiptApplied {
+				rep.ReceiptsApplied++
+			}
+			r.changed(ctx, m.ID)
+		}
+		rep.Items = append(rep.Items, item)
+	}
+}
+
+func (r *Reconciler) check(ctx context.Context, m model.Message) Item {
+	item := Item{MessageID: m.ID, PreviousStatus: m.Status, Outcome: Unchanged}
+
+	q := service.StatusQuery{IdempotencyKey: service.MessageIdempotencyKey(m)}
+	if m.Provider != nil {
+		q.Provider = *m.Provider
+	}
+	if m.RemoteMessageID != nil {
+		q.RemoteMessageID = *m.RemoteMessageID
+	}
+
+	st, err := r.status.Status(ctx, q)
+	if errors.Is(err, service.ErrStatusNotFound) {
+		item.Outcome = NotFound
+		return item
+	}
+	if err != nil {
+		return failed(item, err)
+	}
+	item.ProviderStatus = st.Status
+	item.Provider = st.Provider
+
+	// The provider accepted a message we never recorded as sent.
+	if m.Status == model.Sending || m.Status == model.Failed {
+		if st.RemoteMessageID == "" {
+			return failed(item, errors.New("provider returned no remote message ID"))
+		}
+		err := r.repo.MarkReconciledSent(ctx, m.ID, st.RemoteMessageID, st.Provider)
+		if errors.Is(err, repo.ErrStatusChanged) {
+			// Requeued, claimed again or cancelled since it was listed.
+			slog.InfoContext(ctx, "message changed during reconciliation, leaving it", "id", m.ID)
+			return item
+		}
+		if err != nil {
+			return failed(item, err)
+		}
+		item.Outcome = MarkedSent
+		slog.InfoContext(ctx, "reconciled message as sent", "id", m.ID, "previous_status", m.Status, "provider", st.Provider)
+	}
+
+	if st.Status == model.Delivered || st.Status == model.Undelivered {
+		applied, err := r.repo.ApplyReceipt(ctx, m.ID, model.Receipt{
+			RemoteMessageID: st.RemoteMessageID,
+			Status:          st.Status,
+			At:              st.At,
+			ErrorCode:       st.ErrorCode,
+		})
+		if err != nil {
+			return failed(item, err)
+		}
+		if applied {
+			item.Outcome = ReceiptApplied
+			slog.InfoContext(ctx, "reconciled delivery status", "id", m.ID, "status", st.Status)
+		}
+	}
+	return item
+}
+
+func (r *Reconciler) changed(ctx context.Context, id int64) {
+	if r.onChanged != nil {
+		r.onChanged(ctx, id)
+	}
+}
+
+// failed records err on item. Status lookup errors quote the provider's
+// response body, so phone numbers are masked before the report is shown.
+func failed(item Item, err error) Item {
+	item.Outcome = Failed
+	item.Error = redact.Scrub(err.Error())
+	return item
+}
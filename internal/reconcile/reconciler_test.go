@@ -0,0 +1,222 @@
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+	"github.com/LeventeLantos/automatic-messaging/internal/repo"
+	"github.com/LeventeLantos/automatic-messaging/internal/service"
+)
+
+// fakeRepo implements the calls a run makes; anything else panics through
+// the nil embedded interface.
+type fakeRepo struct {
+	repo.MessageRepository
+
+	ambiguous []model.Message
+	awaiting  []model.Message
+	released  []int64
+
+	stuckBefore time.Time
+	markedSent  map[int64]string
+	changedIDs  map[int64]bool
+	receipts    map[int64]model.Status
+	checked     []int64
+}
+
+func (f *fakeRepo) ListAmbiguous(ctx context.Context, before time.Time, limit int) ([]model.Message, error) {
+	f.stuckBefore = before
+	return f.ambiguous, nil
+}
+
+func (f *fakeRepo) ListAwaitingReceipt(ctx context.Context, sentBefore, checkedBefore time.Time, limit int) ([]model.Message, error) {
+	return f.awaiting, nil
+}
+
+func (f *fakeRepo) ReleaseStuck(ctx context.Context, before time.Time) ([]int64, error) {
+	return f.released, nil
+}
+
+func (f *fakeRepo) MarkStatusChecked(ctx context.Context, id int64) error {
+	f.checked = append(f.checked, id)
+	return nil
+}
+
+func (f *fakeRepo) MarkReconciledSent(ctx context.Context, id int64, remoteMessageID, provider string) error {
+	if f.changedIDs[id] {
+		return repo.ErrStatusChanged
+	}
+	if f.markedSent == nil {
+		f.markedSent = map[int64]string{}
+	}
+	f.markedSent[id] = provider + "/" + remoteMessageID
+	return nil
+}
+
+func (f *fakeRepo) ApplyReceipt(ctx context.Context, id int64, rcpt model.Receipt) (bool, error) {
+	if f.receipts == nil {
+		f.receipts = map[int64]model.Status{}
+	}
+	f.receipts[id] = rcpt.Status
+	return true, nil
+}
+
+// fakeStatus answers by idempotency key for messages without a remote ID and
+// by remote ID otherwise.
+type fakeStatus struct {
+	byKey    map[string]service.ProviderStatus
+	byRemote map[string]service.ProviderStatus
+	queries  []service.StatusQuery
+}
+
+func (f *fakeStatus) Status(ctx context.Context, q service.StatusQuery) (service.ProviderStatus, error) {
+	f.queries = append(f.queries, q)
+	st, ok := f.byKey[q.IdempotencyKey]
+	if q.RemoteMessageID != "" {
+		st, ok = f.byRemote[q.RemoteMessageID]
+	}
+	if !ok {
+		return service.ProviderStatus{}, service.ErrStatusNotFound
+	}
+	return st, nil
+}
+
+func newTestReconciler(fr *fakeRepo) *Reconciler {
+	r := New(fr, Settings{StuckAfter: 5 * time.Minute, ReceiptAfter: time.Hour, RecheckAfter: time.Hour, BatchSize: 10})
+	r.now = func() time.Time { return time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC) }
+	return r
+}
+
+func TestRun_ResolvesAmbiguousAndLateReceipts(t *testing.T) {
+	remote := "r-3"
+	provider := "primary"
+	fr := &fakeRepo{
+		ambiguous: []model.Message{
+			{ID: 1, Status: model.Sending},
+			{ID: 2, Status: model.Failed, RequeueCount: 1},
+		},
+		awaiting: []model.Message{
+			{ID: 3, Status: model.Sent, RemoteMessageID: &remote, Provider: &provider},
+		},
+		released: []int64{2},
+	}
+	sc := &fakeStatus{
+		byKey: map[string]service.ProviderStatus{
+			"msg-1-0": {Provider: "backup", RemoteMessageID: "r-1", Status: model.Sent},
+		},
+		byRemote: map[string]service.ProviderStatus{
+			"r-3": {Provider: "primary", RemoteMessageID: "r-3", Status: model.Undelivered},
+		},
+	}
+
+	var changed []int64
+	r := newTestReconciler(fr).
+		WithStatusClient(sc).
+		WithChangeHook(func(ctx context.Context, id int64) { changed = append(changed, id) })
+
+	rep, err := r.Run(context.Background())
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	if rep.Checked != 3 || rep.MarkedSent != 1 || rep.ReceiptsApplied != 1 || rep.NotFound != 1 || rep.Errors != 0 {
+		t.Fatalf("unexpected report counts: %+v", rep)
+	}
+	if fr.markedSent[1] != "backup/r-1" || len(fr.markedSent) != 1 {
+		t.Fatalf("expected only message 1 marked sent via backup, got %v", fr.markedSent)
+	}
+	if fr.receipts[3] != model.Undelivered {
+		t.Fatalf("expected receipt applied to message 3, got %v", fr.receipts)
+	}
+	if len(fr.checked) != 3 {
+		t.Fatalf("expected every looked-up message to be marked checked, got %v", fr.checked)
+	}
+	if q := sc.queries[2]; q.Provider != "primary" || q.RemoteMessageID != "r-3" {
+		t.Fatalf("expected the stored provider and remote ID in the query, got %+v", q)
+	}
+	if sc.queries[1].IdempotencyKey != "msg-2-1" {
+		t.Fatalf("expected the idempotency key of the failed attempt group, got %+v", sc.queries[1])
+	}
+	if !fr.stuckBefore.Equal(time.Date(2026, 1, 1, 11, 55, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected stuck cutoff: %v", fr.stuckBefore)
+	}
+	if len(changed) != 3 {
+		t.Fatalf("expected change hook for 1, 2 (released) and 3, got %v", changed)
+	}
+
+	last, ok := r.LastReport()
+	if !ok || last.Checked != 3 || len(last.Released) != 1 {
+		t.Fatalf("expected last report to be kept, got %+v", last)
+	}
+}
+
+func TestRun_WithoutStatusClientOnlyReleasesStuck(t *testing.T) {
+	fr := &fakeRepo{
+		ambiguous: []model.Message{{ID: 1, Status: model.Sending}},
+		released:  []int64{1},
+	}
+	r := newTestReconciler(fr)
+
+	if _, ok := r.LastReport(); ok {
+		t.Fatalf("expected no report before the first run")
+	}
+
+	rep, err := r.Run(context.Background())
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if rep.StatusLookups || rep.Checked != 0 || len(rep.Released) != 1 || len(fr.checked) != 0 {
+		t.Fatalf("expected only a release, got %+v", rep)
+	}
+}
+
+func TestRun_ReportsLookupErrors(t *testing.T) {
+	fr := &fakeRepo{ambiguous: []model.Message{{ID: 1, Status: model.Sending}}}
+	r := newTestReconciler(fr).WithStatusClient(errStatus{})
+
+	rep, err := r.Run(context.Background())
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if rep.Errors != 1 || rep.Items[0].Outcome != Failed || rep.Items[0].Error == "" {
+		t.Fatalf("expected the lookup error in the report, got %+v", rep)
+	}
+	if len(fr.markedSent) != 0 {
+		t.Fatalf("expected no changes on error, got %v", fr.markedSent)
+	}
+}
+
+type errStatus struct{}
+
+func (errStatus) Status(ctx context.Context, q service.StatusQuery) (service.ProviderStatus, error) {
+	return service.ProviderStatus{}, errors.New("provider unavailable")
+}
+
+func TestRun_LeavesMessagesThatChangedSinceListed(t *testing.T) {
+	fr := &fakeRepo{
+		ambiguous:  []model.Message{{ID: 1, Status: model.Sending}},
+		changedIDs: map[int64]bool{1: true},
+	}
+	sc := &fakeStatus{byKey: map[string]service.ProviderStatus{
+		"msg-1-0": {Provider: "primary", RemoteMessageID: "r-1", Status: model.Delivered},
+	}}
+
+	var changed []int64
+	r := newTestReconciler(fr).
+		WithStatusClient(sc).
+		WithChangeHook(func(ctx context.Context, id int64) { changed = append(changed, id) })
+
+	rep, err := r.Run(context.Background())
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if rep.MarkedSent != 0 || rep.ReceiptsApplied != 0 || rep.Errors != 0 || rep.Items[0].Outcome != Unchanged {
+		t.Fatalf("expected the message left unchanged, got %+v", rep)
+	}
+	if len(fr.markedSent) != 0 || len(fr.receipts) != 0 || len(changed) != 0 {
+		t.Fatalf("expected no changes, got sent=%v receipts=%v changed=%v", fr.markedSent, fr.receipts, changed)
+	}
+}
@@ -0,0 +1,110 @@
+// Package redact masks personal data, recipient phone numbers and message
+// content, before it leaves the service in API responses, logs or stored
+// provider errors.
+package redact
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+)
+
+// ContentMode says what happens to message content in redacted output.
+type ContentMode string
+
+const (
+	ContentPlain ContentMode = "plain"
+	ContentHash  ContentMode = "hash"
+)
+
+// Policy is applied to messages shown to clients without full visibility.
+type Policy struct {
+	Phones  bool
+	Content ContentMode
+}
+
+// Message returns a copy of m with the policy applied.
+func (p Policy) Message(m model.Message) model.Message {
+	if p.Phones {
+		m.RecipientPhone = Phone(m.RecipientPhone)
+	}
+	if p.Content == ContentHash {
+		m.Content = Hash(m.Content)
+	}
+	return m
+}
+
+// Phone keeps the first three and the last three characters of p, e.g.
+// +36******111; shorter numbers are masked completely.
+func Phone(p string) string {
+	if len(p) <= 6 {
+		return strings.Repeat("*", len(p))
+	}
+	return p[:3] + strings.Repeat("*", len(p)-6) + p[len(p)-3:]
+}
+
+// Hash replaces s with its SHA-256, so equal contents can still be matched.
+func Hash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// minSecretLen keeps Scrub from replacing every occurrence of a very short
+// content, which would mangle the text without protecting anything.
+const minSecretLen = 4
+
+const redacted = "[REDACTED]"
+
+// phoneRE finds international numbers, "+" and digits that may be grouped by
+// spaces, dashes or parentheses, and national numbers with the area code in
+// parentheses, such as (555) 123-4567. Bare digit runs are left alone: in
+// provider errors they are far more often dates, amounts or IDs.
+var phoneRE = regexp.MustCompile(`\+\d[\d ()-]{5,}\d|\(\d{2,4}\)[ -]?\d{3}[ -]?\d{3,4}`)
+
+// minPhoneDigits is the fewest digits a match needs to be masked; E.164
+// numbers have at least eight.
+const minPhoneDigits = 8
+
+// Scrub masks everything that looks like a phone number in text and removes
+// the given secrets, such as the recipient and content of the message a
+// provider error is about, including their JSON- and Go-quoted forms as they
+// appear in echoed request bodies.
+func Scrub(text string, secrets ...string) string {
+	for _, s := range secrets {
+		if len(s) < minSecretLen {
+			continue
+		}
+		for _, form := range quotedForms(s) {
+			text = strings.ReplaceAll(text, form, redacted)
+		}
+	}
+	return phoneRE.ReplaceAllStringFunc(text, func(m string) string {
+		digits := 0
+		for _, c := range m {
+			if c >= '0' && c <= '9' {
+				digits++
+			}
+		}
+		if digits < minPhoneDigits {
+			return m
+		}
+		return Phone(m)
+	})
+}
+
+func quotedForms(s string) []string {
+	forms := []string{s}
+	if b, err := json.Marshal(s); err == nil {
+		forms = append(forms, strings.Trim(string(b), `"`))
+	}
+	q := strconv.Quote(s)
+	forms = append(forms, q[1:len(q)-1])
+	// Error messages quote provider bodies with %q, escaping them twice.
+	qq := strconv.Quote(forms[1])
+	return append(forms, qq[1:len(qq)-1])
+}
@@ -0,0 +1,92 @@
+package redact
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+)
+
+func TestPhone(t *testing.T) {
+	cases := map[string]string{
+		"+36301234111": "+36******111",
+		"06301234567":  "063*****567",
+		"1234567":      "123*567",
+		"123456":       "******",
+		"":             "",
+	}
+	for in, want := range cases {
+		if got := Phone(in); got != want {
+			t.Fatalf("Phone(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestPolicy_Message(t *testing.T) {
+	m := model.Message{ID: 1, RecipientPhone: "+36301234111", Content: "hello"}
+
+	got := Policy{Phones: true, Content: ContentHash}.Message(m)
+	if got.RecipientPhone != "+36******111" || !strings.HasPrefix(got.Content, "sha256:") || got.ID != 1 {
+		t.Fatalf("unexpected redacted message: %+v", got)
+	}
+	if got.Content != Hash("hello") {
+		t.Fatalf("expected a stable hash, got %q", got.Content)
+	}
+	if m.RecipientPhone != "+36301234111" {
+		t.Fatalf("expected the original to be untouched")
+	}
+
+	if got := (Policy{Content: ContentPlain}).Message(m); got != m {
+		t.Fatalf("expected no changes, got %+v", got)
+	}
+}
+
+func TestScrub(t *testing.T) {
+	phone, content := "+36301234111", `Your "code" is 4821`
+	body := fmt.Sprintf(`{"error":"rejected","to":%q,"content":%q}`, phone, content)
+	err := fmt.Errorf("unexpected status code: 400 body=%q", body)
+
+	got := Scrub(err.Error(), phone, content)
+	if strings.Contains(got, "30123") || strings.Contains(got, "4821") {
+		t.Fatalf("expected phone and content to be scrubbed, got %s", got)
+	}
+	if strings.Count(got, "[REDACTED]") != 2 || !strings.Contains(got, "rejected") {
+		t.Fatalf("unexpected scrubbed text: %s", got)
+	}
+
+	if got := Scrub("call +36 30 123 4567 now"); got != "call +36*********567 now" {
+		t.Fatalf("expected a spaced number to be masked, got %q", got)
+	}
+	if got := Scrub("status 500", "ok"); got != "status 500" {
+		t.Fatalf("expected short text and secrets to be left alone, got %q", got)
+	}
+}
+
+func TestScrub_PhoneShapes(t *testing.T) {
+	masked := map[string]string{
+		"to +36301234567":       "to +36******567",
+		"to +1 (555) 123-4567":  "to +1 ***********567",
+		"call (555) 123-4567":   "call (55********567",
+		"+44-20-7946-0958 busy": "+44**********958 busy",
+	}
+	for in, want := range masked {
+		if got := Scrub(in); got != want {
+			t.Fatalf("Scrub(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	for _, in := range []string{
+		"retry after 2026-10-18",
+		"retry after 2026-10-18 12:30:00",
+		"order 1234567890 failed",
+		"charged 1 234 567.89 HUF",
+		"balance -1234567",
+		"request id 550e8400-e29b-41d4-a716-446655440000",
+		"quota +1234567 messages",
+	} {
+		if got := Scrub(in); got != in {
+			t.Fatalf("expected %q to be left alone, got %q", in, got)
+		}
+	}
+}
@@ -0,0 +1,15 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+)
+
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, k model.APIKey, hash string) (*model.APIKey, error)
+	// APIKeyByHash returns ErrNotFound for unknown and revoked keys.
+	APIKeyByHash(ctx context.Context, hash string) (*model.APIKey, error)
+	ListAPIKeys(ctx context.Context) ([]model.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id int64) error
+}
@@ -0,0 +1,12 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+)
+
+type AttemptRepository interface {
+	RecordAttempt(ctx context.Context, a model.Attempt) error
+	ListAttempts(ctx context.Context, messageID int64) ([]model.Attempt, error)
+}
@@ -0,0 +1,25 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+)
+
+// AuditFilter selects audit events. Zero-valued fields do not filter; events
+// are returned newest first, starting below BeforeID when it is set.
+type AuditFilter struct {
+	Actor    string
+	Action   model.AuditAction
+	Target   string
+	From     *time.Time
+	To       *time.Time
+	BeforeID int64
+	Limit    int
+}
+
+type AuditRepository interface {
+	RecordAudit(ctx context.Context, e model.AuditEvent) error
+	ListAudit(ctx context.Context, f AuditFilter) ([]model.AuditEvent, error)
+}
@@ -2,13 +2,97 @@ package repo
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/LeventeLantos/automatic-messaging/internal/model"
 )
 
+var (
+	ErrNotFound      = errors.New("not found")
+	ErrNotPending    = errors.New("message is not pending")
+	ErrNotClaimed    = errors.New("message is not claimed")
+	ErrStatusChanged = errors.New("message is no longer sending or failed")
+)
+
+// MessageUpdate holds the editable fields of a pending message. Nil fields are
+// left unchanged.
+type MessageUpdate struct {
+	RecipientPhone *string
+	Content        *string
+}
+
+// RequeueFilter selects failed messages to move back to pending. Zero-valued
+// fields do not filter.
+type RequeueFilter struct {
+	FailedFrom    *time.Time
+	FailedTo      *time.Time
+	ErrorContains string
+	IDs           []int64
+}
+
+func (f RequeueFilter) IsEmpty() bool {
+	return f.FailedFrom == nil && f.FailedTo == nil && f.ErrorContains == "" && len(f.IDs) == 0
+}
+
+// QueueStats counts the messages not yet handed off to a provider.
+// OldestPending is the creation time of the oldest pending message, or nil
+// when none is pending.
+type QueueStats struct {
+	Pending       int64
+	Processing    int64
+	Sending       int64
+	OldestPending *time.Time
+}
+
+// MessageRepository methods only see the messages of the tenant in ctx, or of
+// every tenant when ctx is not scoped (see tenant.With).
 type MessageRepository interface {
+	// ClaimPending moves up to limit pending messages to processing, taking
+	// turns between tenants so none waits behind another's backlog.
 	ClaimPending(ctx context.Context, limit int) ([]model.Message, error)
-	MarkSent(ctx context.Context, id int64, remoteMessageID string) error
+	MarkSent(ctx context.Context, id int64, remoteMessageID, provider string) error
+	// MarkReconciledSent is MarkSent for a message the provider reports as
+	// accepted. It returns ErrStatusChanged if the message is no longer sending
+	// or failed, e.g. because it was requeued, claimed again or cancelled.
+	MarkReconciledSent(ctx context.Context, id int64, remoteMessageID, provider string) error
 	MarkFailed(ctx context.Context, id int64, errMsg string) error
+	// MarkSending moves a claimed message to sending before it is handed to
+	// the provider. It returns ErrNotClaimed if the message is not processing.
+	MarkSending(ctx context.Context, id int64) error
+	// ReleaseClaimed moves claimed (processing or sending) messages that were
+	// not attempted back to pending.
+	ReleaseClaimed(ctx context.Context, ids []int64) error
+	// ReleaseStuck moves messages left in processing or sending since before
+	// back to pending and returns their IDs.
+	ReleaseStuck(ctx context.Context, before time.Time) ([]int64, error)
+
+	// ListAmbiguous returns messages the provider may or may not have
+	// accepted: rows left in sending since before, and failed rows whose last
+	// attempt timed out and that were not checked since.
+	ListAmbiguous(ctx context.Context, before time.Time, limit int) ([]model.Message, error)
+	// ListAwaitingReceipt returns sent or unknown messages sent before
+	// sentBefore without a delivery status, skipping those checked after
+	// checkedBefore. Least recently checked come first.
+	ListAwaitingReceipt(ctx context.Context, sentBefore, checkedBefore time.Time, limit int) ([]model.Message, error)
+	// MarkStatusChecked records that the provider was asked about id.
+	MarkStatusChecked(ctx context.Context, id int64) error
 	ListSent(ctx context.Context, limit, offset int) ([]model.Message, error)
+	GetByID(ctx context.Context, id int64) (*model.Message, error)
+	Cancel(ctx context.Context, id int64) (*model.Message, error)
+	UpdatePending(ctx context.Context, id int64, upd MessageUpdate) (*model.Message, error)
+	// RequeueFailed returns the IDs of the failed messages matching f. Unless
+	// dryRun is set, those messages are moved back to pending with note.
+	RequeueFailed(ctx context.Context, f RequeueFilter, note string, dryRun bool) ([]int64, error)
+
+	GetByRemoteID(ctx context.Context, remoteMessageID string) (*model.Message, error)
+	// ApplyReceipt records a delivery receipt for a sent (or unknown) message.
+	// It reports false when the message already has a final delivery status,
+	// which makes repeated callbacks harmless.
+	ApplyReceipt(ctx context.Context, id int64, rcpt model.Receipt) (bool, error)
+	// MarkUnknownWithoutReceipt moves messages sent before sentBefore that
+	// never got a receipt to unknown and returns their IDs.
+	MarkUnknownWithoutReceipt(ctx context.Context, sentBefore time.Time) ([]int64, error)
+
+	QueueStats(ctx context.Context) (QueueStats, error)
 }
@@ -0,0 +1,120 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+)
+
+type PostgresAPIKeyRepo struct {
+	db *sql.DB
+}
+
+func NewPostgresAPIKeyRepo(db *sql.DB) *PostgresAPIKeyRepo {
+	return &PostgresAPIKeyRepo{db: db}
+}
+
+// CreateAPIKey creates k.TenantID first if it does not exist yet. An empty
+// TenantID creates an operator key.
+func (r *PostgresAPIKeyRepo) CreateAPIKey(ctx context.Context, k model.APIKey, hash string) (*model.APIKey, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if k.TenantID != "" {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO tenants (id) VALUES ($1)
+			ON CONFLICT (id) DO NOTHING
+		`, k.TenantID); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO api_keys (name, tenant_id, prefix, key_hash, scopes)
+		VALUES ($1, NULLIF($2, ''), $3, $4, string_to_array($5, ','))
+		RETURNING id, created_at
+	`, k.Name, k.TenantID, k.Prefix, hash, strings.Join(k.Scopes, ",")).Scan(&k.ID, &k.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+func (r *PostgresAPIKeyRepo) APIKeyByHash(ctx context.Context, hash string) (*model.APIKey, error) {
+	var (
+		k      model.APIKey
+		scopes string
+	)
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, name, COALESCE(tenant_id, ''), prefix, array_to_string(scopes, ','), created_at
+		FROM api_keys
+		WHERE key_hash = $1 AND revoked_at IS NULL
+	`, hash).Scan(&k.ID, &k.Name, &k.TenantID, &k.Prefix, &scopes, &k.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	k.Scopes = splitScopes(scopes)
+	return &k, nil
+}
+
+func (r *PostgresAPIKeyRepo) ListAPIKeys(ctx context.Context) ([]model.APIKey, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, name, COALESCE(tenant_id, ''), prefix, array_to_string(scopes, ','), created_at, revoked_at
+		FROM api_keys
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []model.APIKey
+	for rows.Next() {
+		var (
+			k      model.APIKey
+			scopes string
+		)
+		if err := rows.Scan(&k.ID, &k.Name, &k.TenantID, &k.Prefix, &scopes, &k.CreatedAt, &k.RevokedAt); err != nil {
+			return nil, err
+		}
+		k.Scopes = splitScopes(scopes)
+		out = append(out, k)
+	}
+	return out, rows.Err()
+}
+
+// RevokeAPIKey returns ErrNotFound if the key does not exist or is already
+// revoked.
+func (r *PostgresAPIKeyRepo) RevokeAPIKey(ctx context.Context, id int64) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE api_keys SET revoked_at = now() WHERE id = $1 AND revoked_at IS NULL
+	`, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func splitScopes(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, ",")
+}
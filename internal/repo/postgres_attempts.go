@@ -0,0 +1,96 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+)
+
+type PostgresAttemptRepo struct {
+	db *sql.DB
+}
+
+func NewPostgresAttemptRepo(db *sql.DB) *PostgresAttemptRepo {
+	return &PostgresAttemptRepo{db: db}
+}
+
+func (r *PostgresAttemptRepo) RecordAttempt(ctx context.Context, a model.Attempt) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO message_attempts (
+			message_id, started_at, finished_at, http_status,
+			error_class, response_body, remote_message_id, provider, instance_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`,
+		a.MessageID,
+		a.StartedAt,
+		a.FinishedAt,
+		a.HTTPStatus,
+		a.ErrorClass,
+		a.ResponseBody,
+		a.RemoteMessageID,
+		a.Provider,
+		a.InstanceID,
+	)
+	return err
+}
+
+func (r *PostgresAttemptRepo) ListAttempts(ctx context.Context, messageID int64) ([]model.Attempt, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, message_id, started_at, finished_at, http_status,
+		       error_class, response_body, remote_message_id, provider, instance_id
+		FROM message_attempts
+		WHERE message_id = $1
+		ORDER BY started_at ASC, id ASC
+	`, messageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []model.Attempt
+	for rows.Next() {
+		var a model.Attempt
+		var httpStatus sql.NullInt64
+		var errorClass, body, remoteID, provider sql.NullString
+
+		if err := rows.Scan(
+			&a.ID,
+			&a.MessageID,
+			&a.StartedAt,
+			&a.FinishedAt,
+			&httpStatus,
+			&errorClass,
+			&body,
+			&remoteID,
+			&provider,
+			&a.InstanceID,
+		); err != nil {
+			return nil, err
+		}
+
+		if httpStatus.Valid {
+			v := int(httpStatus.Int64)
+			a.HTTPStatus = &v
+		}
+		if errorClass.Valid {
+			s := errorClass.String
+			a.ErrorClass = &s
+		}
+		if body.Valid {
+			s := body.String
+			a.ResponseBody = &s
+		}
+		if remoteID.Valid {
+			s := remoteID.String
+			a.RemoteMessageID = &s
+		}
+		if provider.Valid {
+			s := provider.String
+			a.Provider = &s
+		}
+
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
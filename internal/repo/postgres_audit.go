@@ -0,0 +1,71 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+	"github.com/LeventeLantos/automatic-messaging/internal/tenant"
+)
+
+// PostgresAuditRepo lists only the events of the tenant in ctx; an unscoped
+// ctx sees every event.
+type PostgresAuditRepo struct {
+	db *sql.DB
+}
+
+func NewPostgresAuditRepo(db *sql.DB) *PostgresAuditRepo {
+	return &PostgresAuditRepo{db: db}
+}
+
+func (r *PostgresAuditRepo) RecordAudit(ctx context.Context, e model.AuditEvent) error {
+	var params any
+	if len(e.Params) > 0 {
+		params = string(e.Params)
+	}
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO audit_events (actor, tenant_id, action, target, params, source_ip)
+		VALUES ($1, NULLIF($2, ''), $3, $4, $5::jsonb, $6)
+	`, e.Actor, e.TenantID, string(e.Action), e.Target, params, e.SourceIP)
+	return err
+}
+
+func (r *PostgresAuditRepo) ListAudit(ctx context.Context, f AuditFilter) ([]model.AuditEvent, error) {
+	if f.Limit <= 0 {
+		f.Limit = 50
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, actor, COALESCE(tenant_id, ''), action, target, COALESCE(params::text, ''), source_ip, occurred_at
+		FROM audit_events
+		WHERE ($1 = '' OR actor = $1)
+		  AND ($2 = '' OR action = $2)
+		  AND ($3 = '' OR target = $3)
+		  AND ($4::timestamptz IS NULL OR occurred_at >= $4)
+		  AND ($5::timestamptz IS NULL OR occurred_at < $5)
+		  AND ($6::bigint = 0 OR id < $6)
+		  AND ($8 = '' OR tenant_id = $8)
+		ORDER BY id DESC
+		LIMIT $7
+	`, f.Actor, string(f.Action), f.Target, f.From, f.To, f.BeforeID, f.Limit, tenant.From(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []model.AuditEvent
+	for rows.Next() {
+		var (
+			e      model.AuditEvent
+			params string
+		)
+		if err := rows.Scan(&e.ID, &e.Actor, &e.TenantID, &e.Action, &e.Target, &params, &e.SourceIP, &e.OccurredAt); err != nil {
+			return nil, err
+		}
+		if params != "" {
+			e.Params = []byte(params)
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
@@ -6,9 +6,15 @@ import (
 	"errors"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+
 	"github.com/LeventeLantos/automatic-messaging/internal/model"
+	"github.com/LeventeLantos/automatic-messaging/internal/tenant"
 )
 
+// PostgresMessageRepo scopes every method to the tenant in ctx (see
+// tenant.With): rows of other tenants are neither returned nor changed. An
+// unscoped ctx covers them all.
 type PostgresMessageRepo struct {
 	db *sql.DB
 }
@@ -17,7 +23,19 @@ func NewPostgresMessageRepo(db *sql.DB) *PostgresMessageRepo {
 	return &PostgresMessageRepo{db: db}
 }
 
-func (r *PostgresMessageRepo) ClaimPending(ctx context.Context, limit int) ([]model.Message, error) {
+// ClaimPending claims the oldest pending messages round-robin across tenants:
+// each tenant's oldest message first, then each tenant's second, and so on,
+// so a tenant with a large backlog cannot starve the others. Rows are locked
+// while each tenant's candidates are picked, so concurrent instances skip
+// each other's rows instead of all picking the same oldest ones and claiming
+// nothing.
+func (r *PostgresMessageRepo) ClaimPending(ctx context.Context, limit int) (msgs []model.Message, err error) {
+	ctx, span := startSpan(ctx, "ClaimPending", attribute.Int("messages.limit", limit))
+	defer func() {
+		span.SetAttributes(attribute.Int("messages.claimed", len(msgs)))
+		endSpan(ctx, span, "ClaimPending", err)
+	}()
+
 	if limit <= 0 {
 		return nil, errors.New("limit must be > 0")
 	}
@@ -29,28 +47,44 @@ func (r *PostgresMessageRepo) ClaimPending(ctx context.Context, limit int) ([]mo
 	defer func() { _ = tx.Rollback() }()
 
 	rows, err := tx.QueryContext(ctx, `
-		SELECT id, recipient_phone, content, status, attempt_count, created_at, updated_at
-		FROM messages
-		WHERE status = 'pending'
-		ORDER BY created_at ASC
-		FOR UPDATE SKIP LOCKED
+		WITH candidates AS (
+			SELECT c.id, c.created_at,
+			       row_number() OVER (PARTITION BY t.id ORDER BY c.created_at, c.id) AS turn
+			FROM tenants t
+			CROSS JOIN LATERAL (
+				SELECT id, created_at
+				FROM messages
+				WHERE tenant_id = t.id AND status = 'pending'
+				ORDER BY created_at, id
+				LIMIT $1
+				FOR UPDATE SKIP LOCKED
+			) c
+			WHERE ($2 = '' OR t.id = $2)
+		)
+		SELECT m.id, m.tenant_id, m.recipient_phone, m.content, m.status,
+		       m.attempt_count, m.requeue_count, m.created_at, m.updated_at
+		FROM messages m
+		JOIN candidates c ON c.id = m.id
+		WHERE m.status = 'pending'
+		ORDER BY c.turn, c.created_at, c.id
 		LIMIT $1
-	`, limit)
+	`, limit, tenant.From(ctx))
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var msgs []model.Message
 	for rows.Next() {
 		var m model.Message
 		var status string
 		if err := rows.Scan(
 			&m.ID,
+			&m.TenantID,
 			&m.RecipientPhone,
 			&m.Content,
 			&status,
 			&m.AttemptCount,
+			&m.RequeueCount,
 			&m.CreatedAt,
 			&m.UpdatedAt,
 		); err != nil {
@@ -92,30 +126,248 @@ func (r *PostgresMessageRepo) ClaimPending(ctx context.Context, limit int) ([]mo
 	return msgs, nil
 }
 
-func (r *PostgresMessageRepo) MarkSent(ctx context.Context, id int64, remoteMessageID string) error {
+// MarkSent records the remote ID and, when non-empty, the provider that
+// accepted the message.
+func (r *PostgresMessageRepo) MarkSent(ctx context.Context, id int64, remoteMessageID, provider string) (err error) {
+	ctx, span := startSpan(ctx, "MarkSent", attribute.Int64("message.id", id))
+	defer func() { endSpan(ctx, span, "MarkSent", err) }()
+
+	return r.markSent(ctx, id, remoteMessageID, provider, false)
+}
+
+func (r *PostgresMessageRepo) MarkReconciledSent(ctx context.Context, id int64, remoteMessageID, provider string) (err error) {
+	ctx, span := startSpan(ctx, "MarkReconciledSent", attribute.Int64("message.id", id))
+	defer func() { endSpan(ctx, span, "MarkReconciledSent", err) }()
+
+	return r.markSent(ctx, id, remoteMessageID, provider, true)
+}
+
+// markSent only updates sending or failed messages when unresolvedOnly is set.
+func (r *PostgresMessageRepo) markSent(ctx context.Context, id int64, remoteMessageID, provider string, unresolvedOnly bool) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		var (
+			sentAt   time.Time
+			tenantID string
+		)
+		if err := tx.QueryRowContext(ctx, `
+			UPDATE messages
+			SET status = 'sent',
+			    sent_at = now(),
+			    remote_message_id = $2,
+			    provider = NULLIF($3, ''),
+			    updated_at = now()
+			WHERE id = $1 AND ($4 = '' OR tenant_id = $4)
+			  AND (NOT $5 OR status IN ('sending', 'failed'))
+			RETURNING sent_at, tenant_id
+		`, id, remoteMessageID, provider, tenant.From(ctx), unresolvedOnly).Scan(&sentAt, &tenantID); err != nil {
+			if unresolvedOnly && errors.Is(err, sql.ErrNoRows) {
+				return ErrStatusChanged
+			}
+			return err
+		}
+
+		return insertEvent(ctx, tx, model.Event{
+			Type:            model.EventMessageSent,
+			OccurredAt:      sentAt,
+			MessageID:       id,
+			TenantID:        tenantID,
+			Status:          model.Sent,
+			RemoteMessageID: remoteMessageID,
+			Provider:        provider,
+		})
+	})
+}
+
+func (r *PostgresMessageRepo) MarkFailed(ctx context.Context, id int64, reason string) (err error) {
+	ctx, span := startSpan(ctx, "MarkFailed", attribute.Int64("message.id", id))
+	defer func() { endSpan(ctx, span, "MarkFailed", err) }()
+
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		var (
+			failedAt time.Time
+			tenantID string
+		)
+		if err := tx.QueryRowContext(ctx, `
+			UPDATE messages
+			SET status = 'failed',
+			    attempt_count = attempt_count + 1,
+			    last_error = $2,
+			    updated_at = now()
+			WHERE id = $1 AND ($3 = '' OR tenant_id = $3)
+			RETURNING updated_at, tenant_id
+		`, id, reason, tenant.From(ctx)).Scan(&failedAt, &tenantID); err != nil {
+			return err
+		}
+
+		return insertEvent(ctx, tx, model.Event{
+			Type:       model.EventMessageFailed,
+			OccurredAt: failedAt,
+			MessageID:  id,
+			TenantID:   tenantID,
+			Status:     model.Failed,
+			Reason:     reason,
+		})
+	})
+}
+
+// MarkSending records that the message is about to be handed to the
+// provider. A row still in sending after a crash is picked up by ReleaseStuck.
+func (r *PostgresMessageRepo) MarkSending(ctx context.Context, id int64) (err error) {
+	ctx, span := startSpan(ctx, "MarkSending", attribute.Int64("message.id", id))
+	defer func() { endSpan(ctx, span, "MarkSending", err) }()
+
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE messages
+		SET status = 'sending',
+		    updated_at = now()
+		WHERE id = $1 AND status = 'processing' AND ($2 = '' OR tenant_id = $2)
+	`, id, tenant.From(ctx))
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotClaimed
+	}
+	return nil
+}
+
+// ReleaseClaimed returns claimed messages that were never attempted to
+// pending without counting an attempt.
+func (r *PostgresMessageRepo) ReleaseClaimed(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
 	_, err := r.db.ExecContext(ctx, `
 		UPDATE messages
-		SET status = 'sent',
-		    sent_at = now(),
-		    remote_message_id = $2,
+		SET status = 'pending',
 		    updated_at = now()
-		WHERE id = $1
-	`, id, remoteMessageID)
+		WHERE id = ANY($1) AND status IN ('processing', 'sending')
+		  AND ($2 = '' OR tenant_id = $2)
+	`, ids, tenant.From(ctx))
 	return err
 }
 
-func (r *PostgresMessageRepo) MarkFailed(ctx context.Context, id int64, reason string) error {
-	_, err := r.db.ExecContext(ctx, `
+// ReleaseStuck is the recovery path for a process that died between claiming
+// or sending and recording the outcome. The resend carries the same
+// idempotency key, so a provider that already accepted the message can drop
+// the duplicate.
+func (r *PostgresMessageRepo) ReleaseStuck(ctx context.Context, before time.Time) ([]int64, error) {
+	rows, err := r.db.QueryContext(ctx, `
 		UPDATE messages
-		SET status = 'failed',
-		    attempt_count = attempt_count + 1,
-		    last_error = $2,
+		SET status = 'pending',
 		    updated_at = now()
-		WHERE id = $1
-	`, id, reason)
+		WHERE status IN ('processing', 'sending') AND updated_at < $1
+		  AND ($2 = '' OR tenant_id = $2)
+		RETURNING id
+	`, before, tenant.From(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return scanIDs(rows)
+}
+
+func (r *PostgresMessageRepo) ListAmbiguous(ctx context.Context, before time.Time, limit int) ([]model.Message, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+messageColumns+`
+		FROM messages m
+		WHERE ((status = 'sending' AND updated_at < $1)
+		   OR (status = 'failed'
+		       AND (status_checked_at IS NULL OR status_checked_at < updated_at)
+		       AND (
+		           SELECT a.error_class
+		           FROM message_attempts a
+		           WHERE a.message_id = m.id
+		           ORDER BY a.started_at DESC
+		           LIMIT 1
+		       ) = 'timeout'))
+		  AND ($3 = '' OR tenant_id = $3)
+		ORDER BY updated_at ASC
+		LIMIT $2
+	`, before, limit, tenant.From(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return scanMessages(rows)
+}
+
+func (r *PostgresMessageRepo) ListAwaitingReceipt(ctx context.Context, sentBefore, checkedBefore time.Time, limit int) ([]model.Message, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+messageColumns+`
+		FROM messages
+		WHERE status IN ('sent', 'unknown')
+		  AND remote_message_id IS NOT NULL
+		  AND sent_at < $1
+		  AND (status_checked_at IS NULL OR status_checked_at < $2)
+		  AND ($4 = '' OR tenant_id = $4)
+		ORDER BY status_checked_at ASC NULLS FIRST, sent_at ASC
+		LIMIT $3
+	`, sentBefore, checkedBefore, limit, tenant.From(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return scanMessages(rows)
+}
+
+func (r *PostgresMessageRepo) MarkStatusChecked(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE messages SET status_checked_at = now()
+		WHERE id = $1 AND ($2 = '' OR tenant_id = $2)
+	`, id, tenant.From(ctx))
 	return err
 }
 
+func (r *PostgresMessageRepo) QueueStats(ctx context.Context) (QueueStats, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT status, count(*), min(created_at)
+		FROM messages
+		WHERE status IN ('pending', 'processing', 'sending')
+		  AND ($1 = '' OR tenant_id = $1)
+		GROUP BY status
+	`, tenant.From(ctx))
+	if err != nil {
+		return QueueStats{}, err
+	}
+	defer rows.Close()
+
+	var st QueueStats
+	for rows.Next() {
+		var (
+			status model.Status
+			n      int64
+			oldest time.Time
+		)
+		if err := rows.Scan(&status, &n, &oldest); err != nil {
+			return QueueStats{}, err
+		}
+		switch status {
+		case model.Pending:
+			st.Pending = n
+			st.OldestPending = &oldest
+		case model.Processing:
+			st.Processing = n
+		case model.Sending:
+			st.Sending = n
+		}
+	}
+	return st, rows.Err()
+}
+
+const messageColumns = `
+	id, tenant_id, recipient_phone, content, status, attempt_count,
+	last_error, sent_at, remote_message_id, provider,
+	requeue_count, requeue_note, requeued_at,
+	delivery_status_at, delivery_error_code,
+	created_at, updated_at
+`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
 func (r *PostgresMessageRepo) ListSent(ctx context.Context, limit, offset int) ([]model.Message, error) {
 	if limit <= 0 {
 		limit = 50
@@ -125,57 +377,304 @@ func (r *PostgresMessageRepo) ListSent(ctx context.Context, limit, offset int) (
 	}
 
 	rows, err := r.db.QueryContext(ctx, `
-		SELECT id, recipient_phone, content, status, attempt_count,
-		       last_error, sent_at, remote_message_id, created_at, updated_at
+		SELECT `+messageColumns+`
 		FROM messages
-		WHERE status = 'sent'
+		WHERE status = 'sent' AND ($3 = '' OR tenant_id = $3)
 		ORDER BY sent_at DESC
 		LIMIT $1 OFFSET $2
-	`, limit, offset)
+	`, limit, offset, tenant.From(ctx))
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	return scanMessages(rows)
+}
 
-	var out []model.Message
-	for rows.Next() {
-		var m model.Message
-		var status string
-		var lastErr sql.NullString
-		var sentAt sql.NullTime
-		var remoteID sql.NullString
+func (r *PostgresMessageRepo) GetByID(ctx context.Context, id int64) (*model.Message, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT `+messageColumns+`
+		FROM messages
+		WHERE id = $1 AND ($2 = '' OR tenant_id = $2)
+	`, id, tenant.From(ctx))
 
-		if err := rows.Scan(
-			&m.ID,
-			&m.RecipientPhone,
-			&m.Content,
-			&status,
-			&m.AttemptCount,
-			&lastErr,
-			&sentAt,
-			&remoteID,
-			&m.CreatedAt,
-			&m.UpdatedAt,
-		); err != nil {
-			return nil, err
-		}
+	m, err := scanMessage(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
 
-		m.Status = model.Status(status)
+func (r *PostgresMessageRepo) GetByRemoteID(ctx context.Context, remoteMessageID string) (*model.Message, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT `+messageColumns+`
+		FROM messages
+		WHERE remote_message_id = $1 AND ($2 = '' OR tenant_id = $2)
+	`, remoteMessageID, tenant.From(ctx))
+
+	m, err := scanMessage(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (r *PostgresMessageRepo) ApplyReceipt(ctx context.Context, id int64, rcpt model.Receipt) (bool, error) {
+	var code *string
+	if rcpt.ErrorCode != "" {
+		code = &rcpt.ErrorCode
+	}
 
-		if lastErr.Valid {
-			s := lastErr.String
-			m.LastError = &s
+	applied := false
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		var (
+			remoteID sql.NullString
+			tenantID string
+		)
+		err := tx.QueryRowContext(ctx, `
+			UPDATE messages
+			SET status = $2,
+			    delivery_status_at = $3,
+			    delivery_error_code = $4,
+			    updated_at = now()
+			WHERE id = $1 AND status IN ('sent', 'unknown')
+			  AND ($5 = '' OR tenant_id = $5)
+			RETURNING remote_message_id, tenant_id
+		`, id, string(rcpt.Status), rcpt.At, code, tenant.From(ctx)).Scan(&remoteID, &tenantID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
 		}
-		if sentAt.Valid {
-			t := sentAt.Time
-			m.SentAt = &t
+		if err != nil {
+			return err
 		}
-		if remoteID.Valid {
-			s := remoteID.String
-			m.RemoteMessageID = &s
+		applied = true
+
+		evType := model.EventMessageDelivered
+		if rcpt.Status == model.Undelivered {
+			evType = model.EventMessageUndelivered
 		}
+		return insertEvent(ctx, tx, model.Event{
+			Type:            evType,
+			OccurredAt:      rcpt.At,
+			MessageID:       id,
+			TenantID:        tenantID,
+			Status:          rcpt.Status,
+			RemoteMessageID: remoteID.String,
+			ErrorCode:       rcpt.ErrorCode,
+		})
+	})
+	return applied, err
+}
 
+func (r *PostgresMessageRepo) MarkUnknownWithoutReceipt(ctx context.Context, sentBefore time.Time) ([]int64, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		UPDATE messages
+		SET status = 'unknown',
+		    updated_at = now()
+		WHERE status = 'sent' AND sent_at < $1
+		  AND ($2 = '' OR tenant_id = $2)
+		RETURNING id
+	`, sentBefore, tenant.From(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return scanIDs(rows)
+}
+
+// Cancel and UpdatePending only touch rows that are still pending. A row locked
+// by ClaimPending makes the UPDATE wait for the claim to commit, after which the
+// status check fails and the caller gets ErrNotPending.
+func (r *PostgresMessageRepo) Cancel(ctx context.Context, id int64) (*model.Message, error) {
+	row := r.db.QueryRowContext(ctx, `
+		UPDATE messages
+		SET status = 'cancelled',
+		    updated_at = now()
+		WHERE id = $1 AND status = 'pending' AND ($2 = '' OR tenant_id = $2)
+		RETURNING `+messageColumns, id, tenant.From(ctx))
+
+	return r.scanPendingUpdate(ctx, id, row)
+}
+
+func (r *PostgresMessageRepo) UpdatePending(ctx context.Context, id int64, upd MessageUpdate) (*model.Message, error) {
+	row := r.db.QueryRowContext(ctx, `
+		UPDATE messages
+		SET recipient_phone = COALESCE($2, recipient_phone),
+		    content = COALESCE($3, content),
+		    updated_at = now()
+		WHERE id = $1 AND status = 'pending' AND ($4 = '' OR tenant_id = $4)
+		RETURNING `+messageColumns, id, upd.RecipientPhone, upd.Content, tenant.From(ctx))
+
+	return r.scanPendingUpdate(ctx, id, row)
+}
+
+func (r *PostgresMessageRepo) scanPendingUpdate(ctx context.Context, id int64, row *sql.Row) (*model.Message, error) {
+	m, err := scanMessage(row)
+	if err == nil {
+		return &m, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM messages WHERE id = $1 AND ($2 = '' OR tenant_id = $2))
+	`, id, tenant.From(ctx)).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return nil, ErrNotPending
+}
+
+// requeueWhere matches failed rows against a RequeueFilter passed as $1..$4
+// and the tenant scope passed as $5.
+// Failed rows are not touched again until requeued, so updated_at is the time
+// they failed.
+const requeueWhere = `
+	status = 'failed'
+	AND ($1::timestamptz IS NULL OR updated_at >= $1)
+	AND ($2::timestamptz IS NULL OR updated_at < $2)
+	AND ($3 = '' OR strpos(lower(last_error), lower($3)) > 0)
+	AND (cardinality($4::bigint[]) = 0 OR id = ANY($4))
+	AND ($5 = '' OR tenant_id = $5)
+`
+
+func (r *PostgresMessageRepo) RequeueFailed(ctx context.Context, f RequeueFilter, note string, dryRun bool) ([]int64, error) {
+	ids := f.IDs
+	if ids == nil {
+		ids = []int64{}
+	}
+	args := []any{f.FailedFrom, f.FailedTo, f.ErrorContains, ids, tenant.From(ctx)}
+
+	var rows *sql.Rows
+	var err error
+	if dryRun {
+		rows, err = r.db.QueryContext(ctx, `
+			SELECT id FROM messages
+			WHERE `+requeueWhere+`
+			ORDER BY id
+		`, args...)
+	} else {
+		rows, err = r.db.QueryContext(ctx, `
+			UPDATE messages
+			SET status = 'pending',
+			    last_error = NULL,
+			    requeue_count = requeue_count + 1,
+			    requeue_note = $6,
+			    requeued_at = now(),
+			    updated_at = now()
+			WHERE `+requeueWhere+`
+			RETURNING id
+		`, append(args, note)...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return scanIDs(rows)
+}
+
+func scanMessages(rows *sql.Rows) ([]model.Message, error) {
+	defer rows.Close()
+
+	var out []model.Message
+	for rows.Next() {
+		m, err := scanMessage(rows)
+		if err != nil {
+			return nil, err
+		}
 		out = append(out, m)
 	}
 	return out, rows.Err()
 }
+
+func scanIDs(rows *sql.Rows) ([]int64, error) {
+	defer rows.Close()
+
+	var out []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		out = append(out, id)
+	}
+	return out, rows.Err()
+}
+
+func scanMessage(row rowScanner) (model.Message, error) {
+	var m model.Message
+	var status string
+	var lastErr sql.NullString
+	var sentAt sql.NullTime
+	var remoteID sql.NullString
+	var provider sql.NullString
+	var requeueNote sql.NullString
+	var requeuedAt sql.NullTime
+	var deliveryAt sql.NullTime
+	var deliveryCode sql.NullString
+
+	if err := row.Scan(
+		&m.ID,
+		&m.TenantID,
+		&m.RecipientPhone,
+		&m.Content,
+		&status,
+		&m.AttemptCount,
+		&lastErr,
+		&sentAt,
+		&remoteID,
+		&provider,
+		&m.RequeueCount,
+		&requeueNote,
+		&requeuedAt,
+		&deliveryAt,
+		&deliveryCode,
+		&m.CreatedAt,
+		&m.UpdatedAt,
+	); err != nil {
+		return model.Message{}, err
+	}
+
+	m.Status = model.Status(status)
+
+	if lastErr.Valid {
+		s := lastErr.String
+		m.LastError = &s
+	}
+	if sentAt.Valid {
+		t := sentAt.Time
+		m.SentAt = &t
+	}
+	if remoteID.Valid {
+		s := remoteID.String
+		m.RemoteMessageID = &s
+	}
+	if provider.Valid {
+		s := provider.String
+		m.Provider = &s
+	}
+	if requeueNote.Valid {
+		s := requeueNote.String
+		m.RequeueNote = &s
+	}
+	if requeuedAt.Valid {
+		t := requeuedAt.Time
+		m.RequeuedAt = &t
+	}
+	if deliveryAt.Valid {
+		t := deliveryAt.Time
+		m.DeliveryStatusAt = &t
+	}
+	if deliveryCode.Valid {
+		s := deliveryCode.String
+		m.DeliveryErrorCode = &s
+	}
+
+	return m, nil
+}
@@ -0,0 +1,51 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+)
+
+// insertEvent writes ev to the outbox and fans it out to every active
+// subscription for its type of the event's tenant, and to the subscriptions
+// that have no tenant. It must run in the transaction that made the
+// status change, so an event exists if and only if the change committed.
+func insertEvent(ctx context.Context, tx *sql.Tx, ev model.Event) error {
+	ev.Version = model.EventSchemaVersion
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+
+	var eventID int64
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO outbox_events (event_type, message_id, payload, created_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`, string(ev.Type), ev.MessageID, string(payload), ev.OccurredAt).Scan(&eventID); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO event_deliveries (event_id, subscription_id)
+		SELECT $1, id FROM subscriptions
+		WHERE active AND $2 = ANY(event_types)
+		  AND (tenant_id IS NULL OR tenant_id = $3)
+	`, eventID, string(ev.Type), ev.TenantID)
+	return err
+}
+
+func (r *PostgresMessageRepo) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,85 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+)
+
+type PostgresRouteRepo struct {
+	db *sql.DB
+}
+
+func NewPostgresRouteRepo(db *sql.DB) *PostgresRouteRepo {
+	return &PostgresRouteRepo{db: db}
+}
+
+func (r *PostgresRouteRepo) ListRoutes(ctx context.Context) ([]model.Route, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT prefix, provider, sender_id, created_at, updated_at
+		FROM routes
+		ORDER BY prefix
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []model.Route
+	for rows.Next() {
+		rt, err := scanRoute(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, rt)
+	}
+	return out, rows.Err()
+}
+
+func (r *PostgresRouteRepo) PutRoute(ctx context.Context, rt model.Route) (*model.Route, error) {
+	row := r.db.QueryRowContext(ctx, `
+		INSERT INTO routes (prefix, provider, sender_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (prefix) DO UPDATE
+		SET provider = EXCLUDED.provider,
+		    sender_id = EXCLUDED.sender_id,
+		    updated_at = now()
+		RETURNING prefix, provider, sender_id, created_at, updated_at
+	`, rt.Prefix, rt.Provider, rt.SenderID)
+
+	out, err := scanRoute(row)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (r *PostgresRouteRepo) DeleteRoute(ctx context.Context, prefix string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM routes WHERE prefix = $1`, prefix)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func scanRoute(row rowScanner) (model.Route, error) {
+	var rt model.Route
+	var senderID sql.NullString
+
+	if err := row.Scan(&rt.Prefix, &rt.Provider, &senderID, &rt.CreatedAt, &rt.UpdatedAt); err != nil {
+		return model.Route{}, err
+	}
+	if senderID.Valid {
+		s := senderID.String
+		rt.SenderID = &s
+	}
+	return rt, nil
+}
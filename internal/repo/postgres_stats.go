@@ -0,0 +1,148 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+	"github.com/LeventeLantos/automatic-messaging/internal/tenant"
+)
+
+type PostgresStatsRepo struct {
+	db *sql.DB
+}
+
+func NewPostgresStatsRepo(db *sql.DB) *PostgresStatsRepo {
+	return &PostgresStatsRepo{db: db}
+}
+
+// MessageStats covers the tenant in ctx, or every tenant when ctx is not
+// scoped. It reads counts from message_tenant_status_counts plus the deltas
+// the triggers appended since the last compaction; every other figure is an
+// index range scan over the last hour.
+func (r *PostgresStatsRepo) MessageStats(ctx context.Context) (MessageStats, error) {
+	tenantID := tenant.From(ctx)
+	st := MessageStats{Counts: map[model.Status]int64{}}
+
+	var (
+		oldest   sql.NullTime
+		p50, p95 sql.NullFloat64
+	)
+	if err := r.db.QueryRowContext(ctx, `
+		SELECT now(),
+		       (SELECT min(created_at) FROM messages
+		        WHERE status = 'pending' AND ($1 = '' OR tenant_id = $1)),
+		       s.last_1m, s.last_5m, s.last_60m, s.p50, s.p95,
+		       a.total, a.failed
+		FROM (
+		    SELECT count(*) FILTER (WHERE sent_at >= now() - interval '1 minute') AS last_1m,
+		           count(*) FILTER (WHERE sent_at >= now() - interval '5 minutes') AS last_5m,
+		           count(*) AS last_60m,
+		           percentile_cont(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM sent_at - created_at)) AS p50,
+		           percentile_cont(0.95) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM sent_at - created_at)) AS p95
+		    FROM messages
+		    WHERE sent_at >= now() - interval '60 minutes'
+		      AND ($1 = '' OR tenant_id = $1)
+		) s, (
+		    SELECT count(*) AS total,
+		           count(*) FILTER (WHERE error_class IS NOT NULL) AS failed
+		    FROM message_attempts
+		    WHERE started_at >= now() - interval '60 minutes'
+		      AND ($1 = '' OR EXISTS (
+		          SELECT 1 FROM messages m WHERE m.id = message_id AND m.tenant_id = $1
+		      ))
+		) a
+	`, tenantID).Scan(
+		&st.At,
+		&oldest,
+		&st.SentLast1m,
+		&st.SentLast5m,
+		&st.SentLast60m,
+		&p50,
+		&p95,
+		&st.AttemptsLast60m,
+		&st.FailedAttemptsLast60m,
+	); err != nil {
+		return MessageStats{}, err
+	}
+	if oldest.Valid {
+		st.OldestPending = &oldest.Time
+	}
+	st.SendLatencyP50 = seconds(p50)
+	st.SendLatencyP95 = seconds(p95)
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT status, sum(n)
+		FROM (
+		    SELECT status, count AS n FROM message_tenant_status_counts
+		    WHERE $1 = '' OR tenant_id = $1
+		    UNION ALL
+		    SELECT status, delta FROM message_status_count_deltas
+		    WHERE $1 = '' OR tenant_id = $1
+		) c
+		GROUP BY status
+		HAVING sum(n) > 0
+	`, tenantID)
+	if err != nil {
+		return MessageStats{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			status model.Status
+			n      int64
+		)
+		if err := rows.Scan(&status, &n); err != nil {
+			return MessageStats{}, err
+		}
+		st.Counts[status] = n
+	}
+	return st, rows.Err()
+}
+
+// statsCompactionLock is the advisory lock key that keeps instances from
+// compacting at the same time and deadlocking on each other's delta rows.
+const statsCompactionLock = 0x6d736763 // "msgc"
+
+// CompactStatusCounts moves the queued deltas into
+// message_tenant_status_counts in one transaction, so readers see either the
+// deltas or their sum. It does nothing while another instance is compacting.
+func (r *PostgresStatsRepo) CompactStatusCounts(ctx context.Context) error {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var locked bool
+	if err := tx.QueryRowContext(ctx, `SELECT pg_try_advisory_xact_lock($1)`, statsCompactionLock).Scan(&locked); err != nil {
+		return err
+	}
+	if !locked {
+		return nil
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		WITH moved AS (
+		    DELETE FROM message_status_count_deltas
+		    RETURNING tenant_id, status, delta
+		)
+		INSERT INTO message_tenant_status_counts (tenant_id, status, count)
+		SELECT tenant_id, status, sum(delta) FROM moved GROUP BY tenant_id, status
+		ON CONFLICT (tenant_id, status) DO UPDATE
+		SET count = message_tenant_status_counts.count + EXCLUDED.count
+	`); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func seconds(v sql.NullFloat64) *time.Duration {
+	if !v.Valid {
+		return nil
+	}
+	d := time.Duration(v.Float64 * float64(time.Second))
+	return &d
+}
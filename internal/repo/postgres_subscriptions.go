@@ -0,0 +1,264 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+	"github.com/LeventeLantos/automatic-messaging/internal/tenant"
+)
+
+// PostgresSubscriptionRepo scopes every method to the tenant in ctx, like
+// PostgresMessageRepo. A subscription created without a tenant receives the
+// events of every tenant.
+type PostgresSubscriptionRepo struct {
+	db *sql.DB
+}
+
+func NewPostgresSubscriptionRepo(db *sql.DB) *PostgresSubscriptionRepo {
+	return &PostgresSubscriptionRepo{db: db}
+}
+
+func (r *PostgresSubscriptionRepo) CreateSubscription(ctx context.Context, s model.Subscription) (*model.Subscription, error) {
+	types := make([]string, len(s.EventTypes))
+	for i, t := range s.EventTypes {
+		types[i] = string(t)
+	}
+
+	s.TenantID = tenant.From(ctx)
+	if err := r.db.QueryRowContext(ctx, `
+		INSERT INTO subscriptions (url, secret, event_types, tenant_id)
+		VALUES ($1, $2, string_to_array($3, ','), NULLIF($4, ''))
+		RETURNING id, active, created_at
+	`, s.URL, s.Secret, strings.Join(types, ","), s.TenantID).Scan(&s.ID, &s.Active, &s.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *PostgresSubscriptionRepo) ListSubscriptions(ctx context.Context) ([]model.Subscription, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, COALESCE(tenant_id, ''), url, secret, array_to_string(event_types, ','), active, created_at
+		FROM subscriptions
+		WHERE active AND ($1 = '' OR tenant_id = $1)
+		ORDER BY id
+	`, tenant.From(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []model.Subscription
+	for rows.Next() {
+		var s model.Subscription
+		var types string
+		if err := rows.Scan(&s.ID, &s.TenantID, &s.URL, &s.Secret, &types, &s.Active, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		for _, t := range strings.Split(types, ",") {
+			s.EventTypes = append(s.EventTypes, model.EventType(t))
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+// DeactivateSubscription stops new deliveries and gives up on the pending
+// ones, but keeps the delivery history.
+func (r *PostgresSubscriptionRepo) DeactivateSubscription(ctx context.Context, id int64) error {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE subscriptions SET active = false
+		WHERE id = $1 AND active AND ($2 = '' OR tenant_id = $2)
+	`, id, tenant.From(ctx))
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE event_deliveries
+		SET state = 'failed',
+		    last_error = 'subscription deactivated',
+		    updated_at = now()
+		WHERE subscription_id = $1 AND state = 'pending'
+	`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *PostgresSubscriptionRepo) ListDeliveries(ctx context.Context, subscriptionID int64, limit int) ([]model.EventDelivery, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT d.id, d.event_id, d.subscription_id, e.event_type, d.state, d.attempts,
+		       d.next_attempt_at, d.last_status, d.last_error, d.delivered_at,
+		       d.created_at, d.updated_at
+		FROM event_deliveries d
+		JOIN outbox_events e ON e.id = d.event_id
+		JOIN subscriptions s ON s.id = d.subscription_id
+		WHERE d.subscription_id = $1 AND ($3 = '' OR s.tenant_id = $3)
+		ORDER BY d.created_at DESC
+		LIMIT $2
+	`, subscriptionID, limit, tenant.From(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []model.EventDelivery
+	for rows.Next() {
+		var d model.EventDelivery
+		var eventType, state string
+		var lastStatus sql.NullInt64
+		var lastErr sql.NullString
+		var deliveredAt sql.NullTime
+
+		if err := rows.Scan(
+			&d.ID,
+			&d.EventID,
+			&d.SubscriptionID,
+			&eventType,
+			&state,
+			&d.Attempts,
+			&d.NextAttemptAt,
+			&lastStatus,
+			&lastErr,
+			&deliveredAt,
+			&d.CreatedAt,
+			&d.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		d.EventType = model.EventType(eventType)
+		d.State = model.DeliveryState(state)
+		if lastStatus.Valid {
+			v := int(lastStatus.Int64)
+			d.LastStatus = &v
+		}
+		if lastErr.Valid {
+			s := lastErr.String
+			d.LastError = &s
+		}
+		if deliveredAt.Valid {
+			t := deliveredAt.Time
+			d.DeliveredAt = &t
+		}
+
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+func (r *PostgresSubscriptionRepo) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]model.DueDelivery, error) {
+	if limit <= 0 {
+		return nil, errors.New("limit must be > 0")
+	}
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT d.id, d.attempts, s.url, s.secret, e.id, e.payload
+		FROM event_deliveries d
+		JOIN outbox_events e ON e.id = d.event_id
+		JOIN subscriptions s ON s.id = d.subscription_id
+		WHERE d.state = 'pending' AND d.next_attempt_at <= now() AND s.active
+		ORDER BY d.next_attempt_at ASC
+		FOR UPDATE OF d SKIP LOCKED
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []model.DueDelivery
+	for rows.Next() {
+		var d model.DueDelivery
+		var eventID int64
+		var payload []byte
+		if err := rows.Scan(&d.DeliveryID, &d.Attempts, &d.URL, &d.Secret, &eventID, &payload); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(payload, &d.Event); err != nil {
+			return nil, err
+		}
+		d.Event.ID = eventID
+		out = append(out, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, d := range out {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE event_deliveries
+			SET next_attempt_at = now() + $2 * interval '1 second',
+			    updated_at = now()
+			WHERE id = $1
+		`, d.DeliveryID, lease.Seconds()); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *PostgresSubscriptionRepo) MarkDeliverySucceeded(ctx context.Context, id int64, status int) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE event_deliveries
+		SET state = 'succeeded',
+		    attempts = attempts + 1,
+		    last_status = $2,
+		    last_error = NULL,
+		    delivered_at = now(),
+		    updated_at = now()
+		WHERE id = $1
+	`, id, status)
+	return err
+}
+
+func (r *PostgresSubscriptionRepo) MarkDeliveryFailed(ctx context.Context, id int64, status int, errMsg string, retryAt *time.Time) error {
+	var lastStatus *int
+	if status != 0 {
+		lastStatus = &status
+	}
+
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE event_deliveries
+		SET state = CASE WHEN $4::timestamptz IS NULL THEN 'failed' ELSE 'pending' END,
+		    attempts = attempts + 1,
+		    last_status = $2,
+		    last_error = $3,
+		    next_attempt_at = COALESCE($4, next_attempt_at),
+		    updated_at = now()
+		WHERE id = $1
+	`, id, lastStatus, errMsg, retryAt)
+	return err
+}
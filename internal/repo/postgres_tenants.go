@@ -0,0 +1,90 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+)
+
+type PostgresTenantRepo struct {
+	db *sql.DB
+}
+
+func NewPostgresTenantRepo(db *sql.DB) *PostgresTenantRepo {
+	return &PostgresTenantRepo{db: db}
+}
+
+const tenantColumns = `id, provider, sender_id, created_at, updated_at`
+
+func (r *PostgresTenantRepo) ListTenants(ctx context.Context) ([]model.Tenant, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+tenantColumns+`
+		FROM tenants
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []model.Tenant
+	for rows.Next() {
+		t, err := scanTenant(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+func (r *PostgresTenantRepo) GetTenant(ctx context.Context, id string) (*model.Tenant, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT `+tenantColumns+`
+		FROM tenants
+		WHERE id = $1
+	`, id)
+	return scanTenantRow(row)
+}
+
+func (r *PostgresTenantRepo) UpdateTenant(ctx context.Context, t model.Tenant) (*model.Tenant, error) {
+	row := r.db.QueryRowContext(ctx, `
+		UPDATE tenants
+		SET provider = $2,
+		    sender_id = $3,
+		    updated_at = now()
+		WHERE id = $1
+		RETURNING `+tenantColumns, t.ID, t.Provider, t.SenderID)
+	return scanTenantRow(row)
+}
+
+func scanTenantRow(row *sql.Row) (*model.Tenant, error) {
+	t, err := scanTenant(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func scanTenant(row rowScanner) (model.Tenant, error) {
+	var (
+		t        model.Tenant
+		provider sql.NullString
+		senderID sql.NullString
+	)
+	if err := row.Scan(&t.ID, &provider, &senderID, &t.CreatedAt, &t.UpdatedAt); err != nil {
+		return model.Tenant{}, err
+	}
+	if provider.Valid {
+		t.Provider = &provider.String
+	}
+	if senderID.Valid {
+		t.SenderID = &senderID.String
+	}
+	return t, nil
+}
@@ -0,0 +1,14 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+)
+
+type RouteRepository interface {
+	ListRoutes(ctx context.Context) ([]model.Route, error)
+	// PutRoute creates the route for rt.Prefix or replaces it.
+	PutRoute(ctx context.Context, rt model.Route) (*model.Route, error)
+	DeleteRoute(ctx context.Context, prefix string) error
+}
@@ -0,0 +1,27 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+)
+
+// SchemaVersion is the highest migration this build expects to be applied.
+const SchemaVersion = 21
+
+// CurrentSchemaVersion reports the highest applied migration, or 0 when the
+// schema_migrations table does not exist yet.
+func CurrentSchemaVersion(ctx context.Context, db *sql.DB) (int, error) {
+	var exists bool
+	if err := db.QueryRowContext(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		return 0, err
+	}
+	if !exists {
+		return 0, nil
+	}
+
+	var version sql.NullInt64
+	if err := db.QueryRowContext(ctx, `SELECT max(version) FROM schema_migrations`).Scan(&version); err != nil {
+		return 0, err
+	}
+	return int(version.Int64), nil
+}
@@ -0,0 +1,38 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+)
+
+// MessageStats summarizes the queue and recent sending. Windowed figures
+// cover the hour before At, the database clock when the stats were read.
+// Latencies run from creation to send and are nil when nothing was sent in
+// the window.
+type MessageStats struct {
+	At            time.Time
+	Counts        map[model.Status]int64
+	OldestPending *time.Time
+
+	SentLast1m  int64
+	SentLast5m  int64
+	SentLast60m int64
+
+	AttemptsLast60m       int64
+	FailedAttemptsLast60m int64
+
+	SendLatencyP50 *time.Duration
+	SendLatencyP95 *time.Duration
+}
+
+type StatsRepository interface {
+	MessageStats(ctx context.Context) (MessageStats, error)
+}
+
+// StatusCountCompactor folds the per-status deltas written on every status
+// change into the counters MessageStats reads, so the deltas stay few.
+type StatusCountCompactor interface {
+	CompactStatusCounts(ctx context.Context) error
+}
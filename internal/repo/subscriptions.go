@@ -0,0 +1,27 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+)
+
+type SubscriptionRepository interface {
+	CreateSubscription(ctx context.Context, s model.Subscription) (*model.Subscription, error)
+	ListSubscriptions(ctx context.Context) ([]model.Subscription, error)
+	DeactivateSubscription(ctx context.Context, id int64) error
+	ListDeliveries(ctx context.Context, subscriptionID int64, limit int) ([]model.EventDelivery, error)
+}
+
+// DeliveryQueue is the dispatcher's view of pending event deliveries.
+type DeliveryQueue interface {
+	// ClaimDueDeliveries returns up to limit pending deliveries to active
+	// subscriptions whose next attempt is due and pushes their next attempt
+	// out by lease, so other instances skip them while they are in flight.
+	ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]model.DueDelivery, error)
+	MarkDeliverySucceeded(ctx context.Context, id int64, status int) error
+	// MarkDeliveryFailed schedules another attempt at retryAt, or gives up on
+	// the delivery when retryAt is nil.
+	MarkDeliveryFailed(ctx context.Context, id int64, status int, errMsg string, retryAt *time.Time) error
+}
@@ -0,0 +1,16 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+)
+
+type TenantRepository interface {
+	ListTenants(ctx context.Context) ([]model.Tenant, error)
+	// GetTenant returns ErrNotFound for unknown tenants.
+	GetTenant(ctx context.Context, id string) (*model.Tenant, error)
+	// UpdateTenant replaces the provider and sender ID of tenant t.ID. It
+	// returns ErrNotFound for unknown tenants.
+	UpdateTenant(ctx context.Context, t model.Tenant) (*model.Tenant, error)
+}
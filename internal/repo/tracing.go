@@ -0,0 +1,29 @@
+package repo
+
+import (
+	"context"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/tracing"
+)
+
+// startSpan starts a client span named after the repository method.
+func startSpan(ctx context.Context, op string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append([]attribute.KeyValue{attribute.String("db.system.name", "postgresql")}, attrs...)
+	return tracing.Start(ctx, op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+// endSpan ends a span from startSpan and logs a failed call at debug level,
+// so the error shows up next to the request or tick ID that caused it.
+func endSpan(ctx context.Context, span trace.Span, op string, err error) {
+	tracing.End(span, err)
+	if err != nil {
+		slog.DebugContext(ctx, "repository call failed", "op", op, "err", err)
+	}
+}
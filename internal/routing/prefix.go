@@ -0,0 +1,168 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+	"github.com/LeventeLantos/automatic-messaging/internal/redact"
+	"github.com/LeventeLantos/automatic-messaging/internal/service"
+	"github.com/LeventeLantos/automatic-messaging/internal/tenant"
+)
+
+// RouteSource loads the routing table.
+type RouteSource interface {
+	ListRoutes(ctx context.Context) ([]model.Route, error)
+}
+
+// TenantSource loads the per-tenant provider and sender ID overrides.
+type TenantSource interface {
+	ListTenants(ctx context.Context) ([]model.Tenant, error)
+}
+
+// PrefixRouter picks a provider by the longest route prefix matching the
+// recipient and sends through next.SendVia. While the table is empty every
+// message goes through next.Send unchanged; once routes exist a recipient
+// without a match fails with service.ErrNoRoute. The table is reloaded after
+// refresh, or on the next send after Invalidate.
+type PrefixRouter struct {
+	source  RouteSource
+	tenants TenantSource
+	next    *Router
+	refresh time.Duration
+
+	mu        sync.Mutex
+	table     []model.Route
+	overrides map[string]model.Tenant
+	loadedAt  time.Time
+	stale     bool
+
+	now func() time.Time
+}
+
+func NewPrefixRouter(source RouteSource, next *Router, refresh time.Duration) *PrefixRouter {
+	return &PrefixRouter{
+		source:  source,
+		next:    next,
+		refresh: refresh,
+		stale:   true,
+		now:     time.Now,
+	}
+}
+
+// WithTenants applies per-tenant overrides, looked up by the tenant in the
+// send context: a tenant's provider takes its messages regardless of the
+// routing table, and its sender ID replaces the route's. They are reloaded
+// together with the routing table.
+func (p *PrefixRouter) WithTenants(source TenantSource) *PrefixRouter {
+	p.tenants = source
+	return p
+}
+
+func (p *PrefixRouter) Send(ctx context.Context, phoneNumber, message string) (model.SendResult, error) {
+	table, overrides, err := p.routes(ctx)
+	if err != nil {
+		return model.SendResult{}, err
+	}
+
+	t := overrides[tenant.From(ctx)]
+	if t.Provider != nil {
+		return p.next.SendVia(withSenderID(ctx, t.SenderID), *t.Provider, phoneNumber, message)
+	}
+	if len(table) == 0 {
+		return p.next.Send(withSenderID(ctx, t.SenderID), phoneNumber, message)
+	}
+
+	rt, ok := match(table, phoneNumber)
+	if !ok {
+		// The error ends up in last_error and the logs, so the number is masked.
+		return model.SendResult{}, fmt.Errorf("%w for destination %s", service.ErrNoRoute, redact.Phone(phoneNumber))
+	}
+	senderID := rt.SenderID
+	if t.SenderID != nil {
+		senderID = t.SenderID
+	}
+	return p.next.SendVia(withSenderID(ctx, senderID), rt.Provider, phoneNumber, message)
+}
+
+func withSenderID(ctx context.Context, senderID *string) context.Context {
+	if senderID == nil {
+		return ctx
+	}
+	return service.WithSenderID(ctx, *senderID)
+}
+
+// Invalidate forces a reload before the next send.
+func (p *PrefixRouter) Invalidate() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stale = true
+}
+
+// routes returns the cached table and tenant overrides, reloading them when
+// due. A failed reload keeps serving the previous ones if there are any.
+func (p *PrefixRouter) routes(ctx context.Context) ([]model.Route, map[string]model.Tenant, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	if !p.stale && now.Sub(p.loadedAt) < p.refresh {
+		return p.table, p.overrides, nil
+	}
+
+	table, overrides, err := p.load(ctx)
+	if err != nil {
+		if p.loadedAt.IsZero() {
+			return nil, nil, err
+		}
+		slog.WarnContext(ctx, "failed to reload routes, using previous table", "err", err)
+		return p.table, p.overrides, nil
+	}
+
+	p.table = table
+	p.overrides = overrides
+	p.loadedAt = now
+	p.stale = false
+	return table, overrides, nil
+}
+
+func (p *PrefixRouter) load(ctx context.Context) ([]model.Route, map[string]model.Tenant, error) {
+	table, err := p.source.ListRoutes(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("load routes: %w", err)
+	}
+	// Longest prefix first, so the first match wins.
+	slices.SortFunc(table, func(a, b model.Route) int {
+		return len(b.Prefix) - len(a.Prefix)
+	})
+
+	if p.tenants == nil {
+		return table, nil, nil
+	}
+	tenants, err := p.tenants.ListTenants(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("load tenants: %w", err)
+	}
+	overrides := make(map[string]model.Tenant, len(tenants))
+	for _, t := range tenants {
+		if t.Provider != nil || t.SenderID != nil {
+			overrides[t.ID] = t
+		}
+	}
+	return table, overrides, nil
+}
+
+func match(table []model.Route, phoneNumber string) (model.Route, bool) {
+	phoneNumber = strings.TrimSpace(phoneNumber)
+	for _, rt := range table {
+		if strings.HasPrefix(phoneNumber, rt.Prefix) {
+			return rt, true
+		}
+	}
+	return model.Route{}, false
+}
@@ -0,0 +1,208 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+	"github.com/LeventeLantos/automatic-messaging/internal/service"
+	"github.com/LeventeLantos/automatic-messaging/internal/tenant"
+)
+
+type fakeRoutes struct {
+	routes []model.Route
+	err    error
+	calls  int
+}
+
+func (f *fakeRoutes) ListRoutes(ctx context.Context) ([]model.Route, error) {
+	f.calls++
+	return append([]model.Route(nil), f.routes...), f.err
+}
+
+// senderIDClient records the sender ID it was called with.
+type senderIDClient struct {
+	fakeClient
+	senderID string
+}
+
+func (c *senderIDClient) Send(ctx context.Context, phoneNumber, message string) (model.SendResult, error) {
+	c.senderID = service.SenderID(ctx)
+	return c.fakeClient.Send(ctx, phoneNumber, message)
+}
+
+type fakeTenants []model.Tenant
+
+func (f fakeTenants) ListTenants(ctx context.Context) ([]model.Tenant, error) {
+	return f, nil
+}
+
+func strPtr(s string) *string { return &s }
+
+func newPrefixFixture(t *testing.T, routes []model.Route) (*PrefixRouter, *fakeRoutes, *senderIDClient, *senderIDClient) {
+	t.Helper()
+
+	a := &senderIDClient{fakeClient: *ok("a-1")}
+	b := &senderIDClient{fakeClient: *ok("b-1")}
+	r, err := NewRouter([]Provider{
+		{Name: "vendor-a", Client: a, Priority: 0, Weight: 1},
+		{Name: "vendor-b", Client: b, Priority: 1, Weight: 1},
+	})
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+
+	src := &fakeRoutes{routes: routes}
+	return NewPrefixRouter(src, r, time.Minute), src, a, b
+}
+
+func TestPrefixRouter_LongestMatchWins(t *testing.T) {
+	t.Parallel()
+
+	p, _, a, b := newPrefixFixture(t, []model.Route{
+		{Prefix: "+3", Provider: "vendor-a"},
+		{Prefix: "+36", Provider: "vendor-b", SenderID: strPtr("ACME")},
+		{Prefix: "+1", Provider: "vendor-a"},
+	})
+
+	res, err := p.Send(context.Background(), "+36201234567", "hi")
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if res.Provider != "vendor-b" || b.calls != 1 || b.senderID != "ACME" {
+		t.Fatalf("expected +36 via vendor-b as ACME, got %+v sender=%q", res, b.senderID)
+	}
+
+	res, err = p.Send(context.Background(), "+33123456", "hi")
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if res.Provider != "vendor-a" || a.senderID != "" {
+		t.Fatalf("expected +33 via vendor-a without sender ID, got %+v sender=%q", res, a.senderID)
+	}
+}
+
+func TestPrefixRouter_NoMatchFails(t *testing.T) {
+	t.Parallel()
+
+	p, _, a, b := newPrefixFixture(t, []model.Route{{Prefix: "+36", Provider: "vendor-a"}})
+
+	_, err := p.Send(context.Background(), "+447700900000", "hi")
+	if !errors.Is(err, service.ErrNoRoute) || !strings.Contains(err.Error(), "+44*******000") {
+		t.Fatalf("expected no-route error naming the masked destination, got %v", err)
+	}
+	if strings.Contains(err.Error(), "+447700900000") {
+		t.Fatalf("expected the destination masked, got %v", err)
+	}
+	if got := service.ClassifyError(model.SendResult{}, err); got != service.ErrClassNoRoute {
+		t.Fatalf("expected class %q, got %q", service.ErrClassNoRoute, got)
+	}
+	if a.calls+b.calls != 0 {
+		t.Fatalf("expected no provider calls")
+	}
+}
+
+func TestPrefixRouter_UnknownProviderFails(t *testing.T) {
+	t.Parallel()
+
+	p, _, _, _ := newPrefixFixture(t, []model.Route{{Prefix: "+", Provider: "gone"}})
+
+	_, err := p.Send(context.Background(), "+361", "hi")
+	if !errors.Is(err, service.ErrNoRoute) || !strings.Contains(err.Error(), `"gone"`) {
+		t.Fatalf("expected unknown provider error, got %v", err)
+	}
+}
+
+func TestPrefixRouter_EmptyTableUsesDefaultRouting(t *testing.T) {
+	t.Parallel()
+
+	p, _, a, _ := newPrefixFixture(t, nil)
+
+	res, err := p.Send(context.Background(), "+447700900000", "hi")
+	if err != nil || res.Provider != "vendor-a" || a.calls != 1 {
+		t.Fatalf("expected priority routing, got %+v err=%v", res, err)
+	}
+}
+
+func TestPrefixRouter_CachesAndReloads(t *testing.T) {
+	t.Parallel()
+
+	p, src, _, _ := newPrefixFixture(t, []model.Route{{Prefix: "+36", Provider: "vendor-a"}})
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	p.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		_, _ = p.Send(context.Background(), "+361", "hi")
+	}
+	if src.calls != 1 {
+		t.Fatalf("expected table loaded once, got %d loads", src.calls)
+	}
+
+	src.routes = append(src.routes, model.Route{Prefix: "+44", Provider: "vendor-b"})
+	p.Invalidate()
+	res, err := p.Send(context.Background(), "+447700900000", "hi")
+	if err != nil || res.Provider != "vendor-b" {
+		t.Fatalf("expected new route after Invalidate, got %+v err=%v", res, err)
+	}
+
+	// A failed reload keeps the previous table.
+	src.err = errors.New("db down")
+	now = now.Add(2 * time.Minute)
+	if _, err := p.Send(context.Background(), "+447700900000", "hi"); err != nil {
+		t.Fatalf("expected previous table on reload failure, got %v", err)
+	}
+	if src.calls != 3 {
+		t.Fatalf("expected a reload attempt after refresh, got %d loads", src.calls)
+	}
+}
+
+func TestPrefixRouter_InitialLoadFailure(t *testing.T) {
+	t.Parallel()
+
+	p, src, _, _ := newPrefixFixture(t, nil)
+	src.err = errors.New("db down")
+
+	if _, err := p.Send(context.Background(), "+361", "hi"); err == nil || !strings.Contains(err.Error(), "load routes") {
+		t.Fatalf("expected load error, got %v", err)
+	}
+}
+
+func TestPrefixRouter_TenantOverrides(t *testing.T) {
+	t.Parallel()
+
+	p, _, a, b := newPrefixFixture(t, []model.Route{{Prefix: "+36", Provider: "vendor-a", SenderID: strPtr("ACME")}})
+	p.WithTenants(fakeTenants{
+		{ID: "payments", Provider: strPtr("vendor-b"), SenderID: strPtr("PAYCO")},
+		{ID: "marketing", SenderID: strPtr("PROMO")},
+	})
+
+	cases := []struct {
+		tenant       string
+		wantProvider string
+		client       *senderIDClient
+		wantSender   string
+	}{
+		{"", "vendor-a", a, "ACME"},
+		{tenant.Default, "vendor-a", a, "ACME"},
+		{"payments", "vendor-b", b, "PAYCO"},
+		{"marketing", "vendor-a", a, "PROMO"},
+	}
+	for _, tc := range cases {
+		res, err := p.Send(tenant.With(context.Background(), tc.tenant), "+36201234567", "hi")
+		if err != nil {
+			t.Fatalf("%q: Send: %v", tc.tenant, err)
+		}
+		if res.Provider != tc.wantProvider || tc.client.senderID != tc.wantSender {
+			t.Fatalf("%q: expected %s as %s, got %+v sender=%q", tc.tenant, tc.wantProvider, tc.wantSender, res, tc.client.senderID)
+		}
+	}
+
+	// A tenant provider applies even to numbers without a route.
+	res, err := p.Send(tenant.With(context.Background(), "payments"), "+447700900000", "hi")
+	if err != nil || res.Provider != "vendor-b" {
+		t.Fatalf("expected the tenant provider without a route, got %+v err=%v", res, err)
+	}
+}
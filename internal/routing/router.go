@@ -0,0 +1,308 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+	"github.com/LeventeLantos/automatic-messaging/internal/redact"
+	"github.com/LeventeLantos/automatic-messaging/internal/service"
+)
+
+// Provider is a named SendClient. Lower Priority values are tried first;
+// providers sharing a priority split traffic by Weight.
+type Provider struct {
+	Name     string
+	Client   service.SendClient
+	Priority int
+	Weight   int
+}
+
+// ProviderHealth is a provider's routing state as reported by Health.
+type ProviderHealth struct {
+	Name                string     `json:"name"`
+	Priority            int        `json:"priority"`
+	Weight              int        `json:"weight"`
+	Healthy             bool       `json:"healthy"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	Sent                uint64     `json:"sent"`
+	Failed              uint64     `json:"failed"`
+	LastError           string     `json:"lastError,omitempty"`
+	LastFailureAt       *time.Time `json:"lastFailureAt,omitempty"`
+	LastSuccessAt       *time.Time `json:"lastSuccessAt,omitempty"`
+	UnhealthyUntil      *time.Time `json:"unhealthyUntil,omitempty"`
+}
+
+type provider struct {
+	Provider
+
+	consecutiveFailures int
+	sent, failed        uint64
+	lastError           string
+	lastFailureAt       time.Time
+	lastSuccessAt       time.Time
+	unhealthyUntil      time.Time
+}
+
+// Router is a SendClient that spreads messages over several providers. Each
+// send tries providers in priority order, picking within a priority by
+// weight, and fails over to the next candidate only when the failed provider
+// certainly did not accept the message (see service.SafeToResend). A provider
+// that fails failAfter times in a row is marked unhealthy for cooldown and
+// tried only after healthy ones.
+type Router struct {
+	mu        sync.Mutex
+	providers []*provider
+	failAfter int
+	cooldown  time.Duration
+
+	now  func() time.Time
+	rand func(n int) int
+}
+
+func NewRouter(providers []Provider) (*Router, error) {
+	if len(providers) == 0 {
+		return nil, errors.New("at least one provider is required")
+	}
+
+	r := &Router{
+		failAfter: 3,
+		cooldown:  30 * time.Second,
+		now:       time.Now,
+		rand:      rand.IntN,
+	}
+
+	seen := map[string]bool{}
+	for _, p := range providers {
+		switch {
+		case p.Name == "":
+			return nil, errors.New("provider name is required")
+		case seen[p.Name]:
+			return nil, fmt.Errorf("duplicate provider %q", p.Name)
+		case p.Client == nil:
+			return nil, fmt.Errorf("provider %q has no client", p.Name)
+		case p.Weight <= 0:
+			return nil, fmt.Errorf("provider %q: weight must be > 0", p.Name)
+		}
+		seen[p.Name] = true
+		r.providers = append(r.providers, &provider{Provider: p})
+	}
+
+	slices.SortStableFunc(r.providers, func(a, b *provider) int {
+		return a.Priority - b.Priority
+	})
+	return r, nil
+}
+
+func (r *Router) WithHealthPolicy(failAfter int, cooldown time.Duration) *Router {
+	r.failAfter = failAfter
+	r.cooldown = cooldown
+	return r
+}
+
+func (r *Router) Send(ctx context.Context, phoneNumber, message string) (model.SendResult, error) {
+	var (
+		res  model.SendResult
+		errs []error
+	)
+
+	for _, p := range r.candidates() {
+		if ctx.Err() != nil {
+			break
+		}
+
+		var err error
+		res, err = p.Client.Send(ctx, phoneNumber, message)
+		res.Provider = p.Name
+		r.record(p, err, phoneNumber, message)
+
+		if err == nil {
+			return res, nil
+		}
+		errs = append(errs, fmt.Errorf("provider %s: %w", p.Name, err))
+		if !service.SafeToResend(res, err) {
+			break
+		}
+	}
+
+	if len(errs) == 0 {
+		return res, ctx.Err()
+	}
+	return res, errors.Join(errs...)
+}
+
+// SendVia sends through the named provider only, without failover. Unknown
+// names wrap service.ErrNoRoute.
+func (r *Router) SendVia(ctx context.Context, name, phoneNumber, message string) (model.SendResult, error) {
+	i := slices.IndexFunc(r.providers, func(p *provider) bool { return p.Name == name })
+	if i < 0 {
+		return model.SendResult{}, fmt.Errorf("%w: unknown provider %q", service.ErrNoRoute, name)
+	}
+	p := r.providers[i]
+
+	res, err := p.Client.Send(ctx, phoneNumber, message)
+	res.Provider = p.Name
+	r.record(p, err, phoneNumber, message)
+	if err != nil {
+		return res, fmt.Errorf("provider %s: %w", p.Name, err)
+	}
+	return res, nil
+}
+
+// Status implements service.StatusClient. It asks q.Provider when set, and
+// otherwise every provider in priority order until one knows the message.
+// Providers whose client cannot look messages up are skipped.
+func (r *Router) Status(ctx context.Context, q service.StatusQuery) (service.ProviderStatus, error) {
+	var (
+		errs     []error
+		notFound bool
+	)
+
+	for _, p := range r.providers {
+		if q.Provider != "" && p.Name != q.Provider {
+			continue
+		}
+		sc, ok := p.Client.(service.StatusClient)
+		if !ok {
+			continue
+		}
+
+		st, err := sc.Status(ctx, q)
+		switch {
+		case err == nil:
+			st.Provider = p.Name
+			return st, nil
+		case errors.Is(err, service.ErrStatusUnsupported):
+		case errors.Is(err, service.ErrStatusNotFound):
+			notFound = true
+		default:
+			errs = append(errs, fmt.Errorf("provider %s: %w", p.Name, err))
+		}
+	}
+
+	switch {
+	case len(errs) > 0:
+		return service.ProviderStatus{}, errors.Join(errs...)
+	case notFound:
+		return service.ProviderStatus{}, service.ErrStatusNotFound
+	default:
+		return service.ProviderStatus{}, service.ErrStatusUnsupported
+	}
+}
+
+// Health returns a snapshot of every provider in priority order.
+func (r *Router) Health() []ProviderHealth {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	out := make([]ProviderHealth, 0, len(r.providers))
+	for _, p := range r.providers {
+		h := ProviderHealth{
+			Name:                p.Name,
+			Priority:            p.Priority,
+			Weight:              p.Weight,
+			Healthy:             p.healthy(now),
+			ConsecutiveFailures: p.consecutiveFailures,
+			Sent:                p.sent,
+			Failed:              p.failed,
+			LastError:           p.lastError,
+			LastFailureAt:       timePtr(p.lastFailureAt),
+			LastSuccessAt:       timePtr(p.lastSuccessAt),
+		}
+		if !h.Healthy {
+			h.UnhealthyUntil = timePtr(p.unhealthyUntil)
+		}
+		out = append(out, h)
+	}
+	return out
+}
+
+// candidates orders providers for one send: healthy before unhealthy, then
+// by priority, and within a priority a weighted random permutation.
+func (r *Router) candidates() []*provider {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	var healthy, unhealthy []*provider
+
+	for i := 0; i < len(r.providers); {
+		j := i
+		for j < len(r.providers) && r.providers[j].Priority == r.providers[i].Priority {
+			j++
+		}
+		for _, p := range r.weightedOrder(r.providers[i:j]) {
+			if p.healthy(now) {
+				healthy = append(healthy, p)
+			} else {
+				unhealthy = append(unhealthy, p)
+			}
+		}
+		i = j
+	}
+	return append(healthy, unhealthy...)
+}
+
+func (r *Router) weightedOrder(tier []*provider) []*provider {
+	rest := slices.Clone(tier)
+	out := make([]*provider, 0, len(tier))
+
+	for len(rest) > 0 {
+		total := 0
+		for _, p := range rest {
+			total += p.Weight
+		}
+		pick := r.rand(total)
+		for i, p := range rest {
+			if pick < p.Weight {
+				out = append(out, p)
+				rest = slices.Delete(rest, i, i+1)
+				break
+			}
+			pick -= p.Weight
+		}
+	}
+	return out
+}
+
+// record updates p's health after a send of message to phoneNumber. The last
+// error is served by GET /v1/providers, so provider bodies echoing the
+// recipient or content are scrubbed first.
+func (r *Router) record(p *provider, err error, phoneNumber, message string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	if err == nil {
+		p.sent++
+		p.consecutiveFailures = 0
+		p.unhealthyUntil = time.Time{}
+		p.lastSuccessAt = now
+		return
+	}
+
+	p.failed++
+	p.consecutiveFailures++
+	p.lastError = redact.Scrub(err.Error(), phoneNumber, message)
+	p.lastFailureAt = now
+	if r.failAfter > 0 && p.consecutiveFailures >= r.failAfter {
+		p.unhealthyUntil = now.Add(r.cooldown)
+	}
+}
+
+func (p *provider) healthy(now time.Time) bool {
+	return !now.Before(p.unhealthyUntil)
+}
+
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,307 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+	"github.com/LeventeLantos/automatic-messaging/internal/service"
+)
+
+type fakeClient struct {
+	calls int
+	res   model.SendResult
+	err   error
+}
+
+func (f *fakeClient) Send(ctx context.Context, phoneNumber, message string) (model.SendResult, error) {
+	f.calls++
+	return f.res, f.err
+}
+
+func ok(remoteID string) *fakeClient {
+	return &fakeClient{res: model.SendResult{RemoteMessageID: remoteID, StatusCode: 202}}
+}
+
+func failing(status int) *fakeClient {
+	if status == 0 {
+		return &fakeClient{err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}}
+	}
+	return &fakeClient{
+		res: model.SendResult{StatusCode: status},
+		err: fmt.Errorf("unexpected status code: %d", status),
+	}
+}
+
+func TestRouter_FailsOverOnRetryableError(t *testing.T) {
+	t.Parallel()
+
+	primary, backup := failing(503), ok("b-1")
+	r, err := NewRouter([]Provider{
+		{Name: "backup", Client: backup, Priority: 1, Weight: 1},
+		{Name: "primary", Client: primary, Priority: 0, Weight: 1},
+	})
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+
+	res, err := r.Send(context.Background(), "+361", "hi")
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if res.Provider != "backup" || res.RemoteMessageID != "b-1" {
+		t.Fatalf("expected backup result, got %+v", res)
+	}
+	if primary.calls != 1 || backup.calls != 1 {
+		t.Fatalf("expected one call each, got primary=%d backup=%d", primary.calls, backup.calls)
+	}
+}
+
+func TestRouter_DoesNotFailOverOnRejection(t *testing.T) {
+	t.Parallel()
+
+	primary, backup := failing(400), ok("b-1")
+	r, _ := NewRouter([]Provider{
+		{Name: "primary", Client: primary, Priority: 0, Weight: 1},
+		{Name: "backup", Client: backup, Priority: 1, Weight: 1},
+	})
+
+	res, err := r.Send(context.Background(), "+361", "hi")
+	if err == nil || !strings.Contains(err.Error(), "provider primary") {
+		t.Fatalf("expected primary error, got %v", err)
+	}
+	if res.Provider != "primary" || res.StatusCode != 400 {
+		t.Fatalf("expected primary result, got %+v", res)
+	}
+	if backup.calls != 0 {
+		t.Fatalf("expected no failover on 4xx, backup called %d times", backup.calls)
+	}
+}
+
+func TestRouter_DoesNotFailOverOnAmbiguousError(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]*fakeClient{
+		"deadline":        {err: context.DeadlineExceeded},
+		"network timeout": {err: &net.OpError{Op: "read", Err: timeoutErr{}}},
+		"connection lost": {err: &net.OpError{Op: "read", Err: errors.New("connection reset by peer")}},
+		"bad gateway":     failing(502),
+		"gateway timeout": failing(504),
+	}
+	for name, primary := range cases {
+		t.Run(name, func(t *testing.T) {
+			backup := ok("b-1")
+			r, _ := NewRouter([]Provider{
+				{Name: "primary", Client: primary, Priority: 0, Weight: 1},
+				{Name: "backup", Client: backup, Priority: 1, Weight: 1},
+			})
+
+			if _, err := r.Send(context.Background(), "+361", "hi"); err == nil {
+				t.Fatalf("expected the primary error")
+			}
+			if backup.calls != 0 {
+				t.Fatalf("expected no failover, backup called %d times", backup.calls)
+			}
+		})
+	}
+}
+
+type timeoutErr struct{}
+
+func (timeoutErr) Error() string   { return "i/o timeout" }
+func (timeoutErr) Timeout() bool   { return true }
+func (timeoutErr) Temporary() bool { return true }
+
+func TestRouter_AllProvidersFail(t *testing.T) {
+	t.Parallel()
+
+	r, _ := NewRouter([]Provider{
+		{Name: "a", Client: failing(0), Priority: 0, Weight: 1},
+		{Name: "b", Client: failing(503), Priority: 1, Weight: 1},
+	})
+
+	res, err := r.Send(context.Background(), "+361", "hi")
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	if !strings.Contains(err.Error(), "provider a") || !strings.Contains(err.Error(), "provider b") {
+		t.Fatalf("expected both provider errors, got %v", err)
+	}
+	if res.Provider != "b" || res.StatusCode != 503 {
+		t.Fatalf("expected last provider's result, got %+v", res)
+	}
+}
+
+func TestRouter_HealthScrubsLastError(t *testing.T) {
+	t.Parallel()
+
+	c := &fakeClient{
+		res: model.SendResult{StatusCode: 503},
+		err: fmt.Errorf("unexpected status code: 503 body=%q", `{"to":"+36301234567","text":"your code is 4711"}`),
+	}
+	r, _ := NewRouter([]Provider{{Name: "a", Client: c, Weight: 1}})
+
+	_, _ = r.Send(context.Background(), "+36301234567", "your code is 4711")
+
+	lastErr := r.Health()[0].LastError
+	if strings.Contains(lastErr, "+36301234567") || strings.Contains(lastErr, "4711") || !strings.Contains(lastErr, "503") {
+		t.Fatalf("expected recipient and content scrubbed from %q", lastErr)
+	}
+}
+
+func TestRouter_WeightedSplit(t *testing.T) {
+	t.Parallel()
+
+	main, canary := ok("m"), ok("c")
+	r, _ := NewRouter([]Provider{
+		{Name: "main", Client: main, Weight: 9},
+		{Name: "canary", Client: canary, Weight: 1},
+	})
+
+	// Deterministic first picks cycling through the weight range 0..9.
+	next := 0
+	r.rand = func(n int) int {
+		if n < 10 {
+			return 0
+		}
+		v := next % n
+		next++
+		return v
+	}
+
+	for i := 0; i < 100; i++ {
+		if _, err := r.Send(context.Background(), "+361", "hi"); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	}
+	if main.calls != 90 || canary.calls != 10 {
+		t.Fatalf("unexpected split: main=%d canary=%d", main.calls, canary.calls)
+	}
+}
+
+func TestRouter_UnhealthyProviderIsTriedLast(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	primary, backup := failing(503), ok("b")
+
+	r, _ := NewRouter([]Provider{
+		{Name: "primary", Client: primary, Priority: 0, Weight: 1},
+		{Name: "backup", Client: backup, Priority: 1, Weight: 1},
+	})
+	r.WithHealthPolicy(2, time.Minute)
+	r.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if _, err := r.Send(context.Background(), "+361", "hi"); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	}
+	if primary.calls != 2 {
+		t.Fatalf("expected primary tried while healthy, got %d calls", primary.calls)
+	}
+
+	h := r.Health()
+	if h[0].Name != "primary" || h[0].Healthy || h[0].ConsecutiveFailures != 2 || h[0].UnhealthyUntil == nil {
+		t.Fatalf("expected primary unhealthy, got %+v", h[0])
+	}
+	if !h[1].Healthy || h[1].Sent != 2 {
+		t.Fatalf("expected backup healthy with 2 sends, got %+v", h[1])
+	}
+
+	// While unhealthy the backup goes first and primary is skipped.
+	if _, err := r.Send(context.Background(), "+361", "hi"); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if primary.calls != 2 {
+		t.Fatalf("expected unhealthy primary to be skipped, got %d calls", primary.calls)
+	}
+
+	// After the cooldown primary is tried first again and recovers.
+	now = now.Add(2 * time.Minute)
+	primary.err, primary.res = nil, model.SendResult{RemoteMessageID: "p", StatusCode: 202}
+
+	res, err := r.Send(context.Background(), "+361", "hi")
+	if err != nil || res.Provider != "primary" {
+		t.Fatalf("expected primary after cooldown, got %+v err=%v", res, err)
+	}
+	if h := r.Health(); !h[0].Healthy || h[0].ConsecutiveFailures != 0 {
+		t.Fatalf("expected primary healthy again, got %+v", h[0])
+	}
+}
+
+func TestRouter_StopsWhenContextDone(t *testing.T) {
+	t.Parallel()
+
+	a, b := failing(0), ok("b")
+	a.err = context.Canceled
+	r, _ := NewRouter([]Provider{
+		{Name: "a", Client: a, Priority: 0, Weight: 1},
+		{Name: "b", Client: b, Priority: 1, Weight: 1},
+	})
+
+	if _, err := r.Send(context.Background(), "+361", "hi"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected canceled error, got %v", err)
+	}
+	if b.calls != 0 {
+		t.Fatalf("expected no failover after cancellation")
+	}
+}
+
+// statusClient is a SendClient that also answers status lookups.
+type statusClient struct {
+	*fakeClient
+	st  service.ProviderStatus
+	err error
+}
+
+func (s statusClient) Status(ctx context.Context, q service.StatusQuery) (service.ProviderStatus, error) {
+	return s.st, s.err
+}
+
+func TestRouter_Status(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter([]Provider{
+		{Name: "plain", Client: ok("x"), Priority: 0, Weight: 1},
+		{Name: "primary", Client: statusClient{fakeClient: ok("x"), err: service.ErrStatusNotFound}, Priority: 1, Weight: 1},
+		{Name: "backup", Client: statusClient{fakeClient: ok("x"), st: service.ProviderStatus{RemoteMessageID: "b-1", Status: model.Sent}}, Priority: 2, Weight: 1},
+	})
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+
+	st, err := r.Status(context.Background(), service.StatusQuery{IdempotencyKey: "msg-1-0"})
+	if err != nil || st.Provider != "backup" || st.RemoteMessageID != "b-1" {
+		t.Fatalf("expected backup to know the message, got %+v err=%v", st, err)
+	}
+
+	if _, err := r.Status(context.Background(), service.StatusQuery{Provider: "primary"}); !errors.Is(err, service.ErrStatusNotFound) {
+		t.Fatalf("expected only primary to be asked, got %v", err)
+	}
+	if _, err := r.Status(context.Background(), service.StatusQuery{Provider: "plain"}); !errors.Is(err, service.ErrStatusUnsupported) {
+		t.Fatalf("expected ErrStatusUnsupported for a client without lookups, got %v", err)
+	}
+}
+
+func TestNewRouter_Invalid(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string][]Provider{
+		"empty":       nil,
+		"no name":     {{Client: ok("x"), Weight: 1}},
+		"duplicate":   {{Name: "a", Client: ok("x"), Weight: 1}, {Name: "a", Client: ok("y"), Weight: 1}},
+		"no client":   {{Name: "a", Weight: 1}},
+		"zero weight": {{Name: "a", Client: ok("x")}},
+	}
+	for name, providers := range cases {
+		if _, err := NewRouter(providers); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
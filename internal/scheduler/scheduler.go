@@ -7,6 +7,8 @@ import (
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/logging"
 )
 
 type Scheduler struct {
@@ -14,6 +16,9 @@ type Scheduler struct {
 	tickFn   func(context.Context)
 
 	running atomic.Bool
+	// heartbeat is when the last tick finished, or when the scheduler
+	// started if no tick has finished since, in Unix nanoseconds.
+	heartbeat atomic.Int64
 
 	mu     sync.Mutex
 	cancel context.CancelFunc
@@ -46,6 +51,7 @@ func (s *Scheduler) Start() bool {
 	s.cancel = cancel
 	s.done = make(chan struct{})
 	s.running.Store(true)
+	s.heartbeat.Store(time.Now().UnixNano())
 
 	go func() {
 		defer close(s.done)
@@ -91,14 +97,29 @@ func (s *Scheduler) IsRunning() bool {
 	return s.running.Load()
 }
 
+// Heartbeat reports when the last tick finished, or when the scheduler was
+// started if none has finished since. A running scheduler whose heartbeat is
+// older than a few intervals is stuck in a tick.
+func (s *Scheduler) Heartbeat() time.Time {
+	ns := s.heartbeat.Load()
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ns)
+}
+
+// safeTick runs one tick with a fresh tick ID in ctx, so every line logged
+// for the tick carries it.
 func (s *Scheduler) safeTick(ctx context.Context) {
+	ctx = logging.With(ctx, "tick_id", logging.NewID())
 	defer func() {
 		if r := recover(); r != nil {
-			slog.Error("scheduler tick panic recovered", "panic", r)
+			slog.ErrorContext(ctx, "scheduler tick panic recovered", "panic", r)
 		}
+		s.heartbeat.Store(time.Now().UnixNano())
 	}()
 
 	start := time.Now()
 	s.tickFn(ctx)
-	slog.Info("scheduler tick completed", "duration_ms", time.Since(start).Milliseconds())
+	slog.InfoContext(ctx, "scheduler tick completed", "duration_ms", time.Since(start).Milliseconds())
 }
@@ -251,3 +251,42 @@ func waitForAtLeast(t *testing.T, calls *atomic.Int64, n int64, timeout time.Dur
 		time.Sleep(5 * time.Millisecond)
 	}
 }
+
+func TestScheduler_Heartbeat(t *testing.T) {
+	release := make(chan struct{})
+	var ticks atomic.Int64
+
+	s, err := New(time.Hour, func(context.Context) {
+		if ticks.Add(1) == 1 {
+			<-release
+		}
+	})
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	if !s.Heartbeat().IsZero() {
+		t.Fatalf("expected no heartbeat before Start()")
+	}
+
+	s.Start()
+	started := s.Heartbeat()
+	if started.IsZero() {
+		t.Fatalf("expected Start() to set the heartbeat")
+	}
+
+	// The first tick is blocked, so the heartbeat must not move.
+	time.Sleep(20 * time.Millisecond)
+	if !s.Heartbeat().Equal(started) {
+		t.Fatalf("expected heartbeat to stay put during a tick")
+	}
+
+	close(release)
+	deadline := time.Now().Add(time.Second)
+	for !s.Heartbeat().After(started) {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected heartbeat to advance after the tick finished")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	s.Stop()
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+)
+
+// Error classes recorded for failed send attempts. The set is closed so it can
+// be used as a label without unbounded cardinality.
+const (
+	ErrClassTimeout         = "timeout"
+	ErrClassCanceled        = "canceled"
+	ErrClassNetwork         = "network"
+	ErrClassHTTP4xx         = "http_4xx"
+	ErrClassHTTP5xx         = "http_5xx"
+	ErrClassInvalidResponse = "invalid_response"
+	ErrClassNoRoute         = "no_route"
+)
+
+// ErrNoRoute is wrapped by SendClients that could not pick a provider for a
+// recipient. Such sends never reached a provider.
+var ErrNoRoute = errors.New("no route")
+
+// ErrCircuitOpen is returned by a SendClient that refused to contact the
+// provider. The message was not attempted and should stay pending.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// ClassifyError maps the outcome of a Send call to one of the ErrClass
+// constants. It returns "" when err is nil.
+func ClassifyError(res model.SendResult, err error) string {
+	if err == nil {
+		return ""
+	}
+
+	switch {
+	case errors.Is(err, ErrNoRoute):
+		return ErrClassNoRoute
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrClassTimeout
+	case errors.Is(err, context.Canceled):
+		return ErrClassCanceled
+	}
+
+	if res.StatusCode == 0 {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return ErrClassTimeout
+		}
+		return ErrClassNetwork
+	}
+
+	switch {
+	case res.StatusCode >= 500:
+		return ErrClassHTTP5xx
+	case res.StatusCode >= 400:
+		return ErrClassHTTP4xx
+	default:
+		return ErrClassInvalidResponse
+	}
+}
+
+// Retryable reports whether a send that failed with class points at a
+// provider problem that a later attempt may not hit. Rejections (4xx) and
+// ambiguous responses are not retried, since the provider may already have
+// accepted the message. Whether another provider may be tried right away is
+// decided by SafeToResend.
+func Retryable(class string) bool {
+	switch class {
+	case ErrClassTimeout, ErrClassNetwork, ErrClassHTTP5xx:
+		return true
+	default:
+		return false
+	}
+}
+
+// SafeToResend reports whether a failed send certainly did not leave the
+// message with the provider, so it may be handed to another one. Timeouts,
+// connections lost after they were made and gateway errors are ambiguous:
+// the provider may have accepted the message, and resending it elsewhere
+// could deliver it twice. Those are left to the reconciler instead.
+func SafeToResend(res model.SendResult, err error) bool {
+	switch ClassifyError(res, err) {
+	case ErrClassHTTP5xx:
+		return res.StatusCode != http.StatusBadGateway && res.StatusCode != http.StatusGatewayTimeout
+	case ErrClassNetwork:
+		var opErr *net.OpError
+		return errors.As(err, &opErr) && opErr.Op == "dial"
+	default:
+		return false
+	}
+}
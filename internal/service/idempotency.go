@@ -0,0 +1,28 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+)
+
+type idempotencyKey struct{}
+
+// MessageIdempotencyKey derives the key sent to the provider for m. It is
+// stable across resends of the same message and only changes when the
+// message is requeued, which starts a new attempt group.
+func MessageIdempotencyKey(m model.Message) string {
+	return fmt.Sprintf("msg-%d-%d", m.ID, m.RequeueCount)
+}
+
+// WithIdempotencyKey attaches the idempotency key for the message being sent.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKey{}, key)
+}
+
+// IdempotencyKey returns the key set by WithIdempotencyKey, or "".
+func IdempotencyKey(ctx context.Context) string {
+	key, _ := ctx.Value(idempotencyKey{}).(string)
+	return key
+}
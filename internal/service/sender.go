@@ -2,22 +2,38 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"time"
 	"unicode/utf8"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
 	"github.com/LeventeLantos/automatic-messaging/internal/model"
+	"github.com/LeventeLantos/automatic-messaging/internal/redact"
+	"github.com/LeventeLantos/automatic-messaging/internal/tenant"
+	"github.com/LeventeLantos/automatic-messaging/internal/tracing"
 )
 
 type SendClient interface {
-	Send(ctx context.Context, phoneNumber, message string) (remoteMessageID string, err error)
+	Send(ctx context.Context, phoneNumber, message string) (model.SendResult, error)
 }
 
+// maxAttemptBody caps the provider response body kept on an attempt record.
+const maxAttemptBody = 1024
+
 type Sender struct {
 	client     SendClient
 	contentMax int
 
-	onSent   func(ctx context.Context, internalID int64, remoteMessageID string) error
+	onSent   func(ctx context.Context, internalID int64, res model.SendResult) error
 	onFailed func(ctx context.Context, internalID int64, reason string) error
+
+	onSending  func(ctx context.Context, internalID int64) error
+	onAttempt  func(ctx context.Context, attempt model.Attempt) error
+	onDeferred func(ctx context.Context, ids []int64) error
 }
 
 func NewSender(client SendClient, contentMax int) *Sender {
@@ -27,8 +43,10 @@ func NewSender(client SendClient, contentMax int) *Sender {
 	}
 }
 
+// WithHooks registers the outcome hooks. onSent receives the provider's
+// result, including which provider handled the message when routed.
 func (s *Sender) WithHooks(
-	onSent func(ctx context.Context, internalID int64, remoteMessageID string) error,
+	onSent func(ctx context.Context, internalID int64, res model.SendResult) error,
 	onFailed func(ctx context.Context, internalID int64, reason string) error,
 ) *Sender {
 	s.onSent = onSent
@@ -36,29 +54,155 @@ func (s *Sender) WithHooks(
 	return s
 }
 
+// WithSendingHook registers a hook called right before a message is handed to
+// the client. If it fails the message is skipped and left for reconciliation.
+func (s *Sender) WithSendingHook(onSending func(ctx context.Context, internalID int64) error) *Sender {
+	s.onSending = onSending
+	return s
+}
+
+// WithAttemptHook registers a hook called after every SendClient.Send call,
+// successful or not.
+func (s *Sender) WithAttemptHook(onAttempt func(ctx context.Context, attempt model.Attempt) error) *Sender {
+	s.onAttempt = onAttempt
+	return s
+}
+
+// WithDeferHook registers a hook for messages the client refused to attempt
+// (ErrCircuitOpen). They are neither sent nor failed and should be returned
+// to pending.
+func (s *Sender) WithDeferHook(onDeferred func(ctx context.Context, ids []int64) error) *Sender {
+	s.onDeferred = onDeferred
+	return s
+}
+
+// ProcessBatch sends msgs in order. If the client reports ErrCircuitOpen the
+// rest of the batch is handed to the defer hook untouched. Each message is
+// sent, and its hooks run, in the message's tenant scope, so the client can
+// apply the tenant's provider and sender ID and events carry the tenant.
 func (s *Sender) ProcessBatch(ctx context.Context, msgs []model.Message) (sent int, failed int) {
-	for _, m := range msgs {
+	for i, m := range msgs {
+		mctx := ctx
+		if m.TenantID != "" {
+			mctx = tenant.With(ctx, m.TenantID)
+		}
+
 		if utf8.RuneCountInString(m.Content) > s.contentMax {
 			failed++
-			s.fail(ctx, m.ID, fmt.Sprintf("content exceeds %d chars", s.contentMax))
+			s.fail(mctx, m.ID, fmt.Sprintf("content exceeds %d chars", s.contentMax))
 			continue
 		}
 
-		remoteID, err := s.client.Send(ctx, m.RecipientPhone, m.Content)
+		if s.onSending != nil {
+			if err := s.onSending(mctx, m.ID); err != nil {
+				continue
+			}
+		}
+
+		res, err := s.send(mctx, m)
+		if errors.Is(err, ErrCircuitOpen) {
+			s.deferRest(ctx, msgs[i:])
+			break
+		}
 		if err != nil {
 			failed++
-			s.fail(ctx, m.ID, err.Error())
+			// Provider errors quote the response body, which often echoes
+			// the recipient and content back.
+			s.fail(mctx, m.ID, redact.Scrub(err.Error(), m.RecipientPhone, m.Content))
 			continue
 		}
 
 		sent++
 		if s.onSent != nil {
-			_ = s.onSent(ctx, m.ID, remoteID)
+			_ = s.onSent(mctx, m.ID, res)
 		}
 	}
 	return sent, failed
 }
 
+func (s *Sender) send(ctx context.Context, m model.Message) (model.SendResult, error) {
+	ctx, span := tracing.Start(ctx, "Send",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.Int64("message.id", m.ID), attribute.String("tenant.id", m.TenantID)),
+	)
+	start := time.Now().UTC()
+	ctx = WithIdempotencyKey(ctx, MessageIdempotencyKey(m))
+	res, err := s.client.Send(ctx, m.RecipientPhone, m.Content)
+
+	if res.Provider != "" {
+		span.SetAttributes(attribute.String("message.provider", res.Provider))
+	}
+	if res.StatusCode != 0 {
+		span.SetAttributes(attribute.Int("http.response.status_code", res.StatusCode))
+	}
+	if class := ClassifyError(res, err); class != "" {
+		span.SetAttributes(attribute.String("error.type", class))
+	}
+	tracing.End(span, err)
+
+	attrs := []any{"id", m.ID, "tenant", m.TenantID, "provider", res.Provider, "status_code", res.StatusCode, "duration_ms", time.Since(start).Milliseconds()}
+	if err != nil {
+		attrs = append(attrs, "err", redact.Scrub(err.Error(), m.RecipientPhone, m.Content))
+	}
+	slog.DebugContext(ctx, "provider call finished", attrs...)
+
+	if s.onAttempt != nil && !errors.Is(err, ErrCircuitOpen) {
+		_ = s.onAttempt(ctx, newAttempt(m, start, time.Now().UTC(), res, err))
+	}
+
+	return res, err
+}
+
+func newAttempt(m model.Message, start, end time.Time, res model.SendResult, err error) model.Attempt {
+	a := model.Attempt{
+		MessageID:  m.ID,
+		StartedAt:  start,
+		FinishedAt: end,
+	}
+	if res.StatusCode != 0 {
+		status := res.StatusCode
+		a.HTTPStatus = &status
+	}
+	if class := ClassifyError(res, err); class != "" {
+		a.ErrorClass = &class
+	}
+	if res.Body != "" {
+		body := truncate(redact.Scrub(res.Body, m.RecipientPhone, m.Content), maxAttemptBody)
+		a.ResponseBody = &body
+	}
+	if err == nil && res.RemoteMessageID != "" {
+		remoteID := res.RemoteMessageID
+		a.RemoteMessageID = &remoteID
+	}
+	if res.Provider != "" {
+		provider := res.Provider
+		a.Provider = &provider
+	}
+	return a
+}
+
+// truncate cuts s to at most n bytes without splitting a UTF-8 sequence.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+func (s *Sender) deferRest(ctx context.Context, msgs []model.Message) {
+	if s.onDeferred == nil {
+		return
+	}
+	ids := make([]int64, len(msgs))
+	for i, m := range msgs {
+		ids[i] = m.ID
+	}
+	_ = s.onDeferred(ctx, ids)
+}
+
 func (s *Sender) fail(ctx context.Context, id int64, reason string) {
 	if s.onFailed != nil {
 		_ = s.onFailed(ctx, id, reason)
@@ -0,0 +1,17 @@
+package service
+
+import "context"
+
+type senderIDKey struct{}
+
+// WithSenderID attaches the sender ID chosen for a message, so the SendClient
+// that finally talks to the provider can include it in the request.
+func WithSenderID(ctx context.Context, senderID string) context.Context {
+	return context.WithValue(ctx, senderIDKey{}, senderID)
+}
+
+// SenderID returns the sender ID set by WithSenderID, or "".
+func SenderID(ctx context.Context) string {
+	id, _ := ctx.Value(senderIDKey{}).(string)
+	return id
+}
@@ -3,14 +3,24 @@ package service_test
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"sync"
 	"testing"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
 	"github.com/LeventeLantos/automatic-messaging/internal/client"
 	"github.com/LeventeLantos/automatic-messaging/internal/model"
 	"github.com/LeventeLantos/automatic-messaging/internal/service"
+	"github.com/LeventeLantos/automatic-messaging/internal/tenant"
+	"github.com/LeventeLantos/automatic-messaging/internal/tracing"
+	"github.com/LeventeLantos/automatic-messaging/internal/tracing/tracingtest"
 )
 
 func TestSender_MarksSentOn202(t *testing.T) {
@@ -39,11 +49,11 @@ func TestSender_MarksSentOn202(t *testing.T) {
 	)
 
 	sender.WithHooks(
-		func(ctx context.Context, internalID int64, remoteMessageID string) error {
+		func(ctx context.Context, internalID int64, res model.SendResult) error {
 			mu.Lock()
 			defer mu.Unlock()
 			sentIDs = append(sentIDs, internalID)
-			remoteIDs = append(remoteIDs, remoteMessageID)
+			remoteIDs = append(remoteIDs, res.RemoteMessageID)
 			return nil
 		},
 		func(ctx context.Context, internalID int64, reason string) error {
@@ -87,7 +97,7 @@ func TestSender_FailsWhenContentTooLong(t *testing.T) {
 	)
 
 	sender.WithHooks(
-		func(ctx context.Context, internalID int64, remoteMessageID string) error {
+		func(ctx context.Context, internalID int64, res model.SendResult) error {
 			t.Fatalf("did not expect sent hook")
 			return nil
 		},
@@ -122,8 +132,311 @@ func TestSender_FailsWhenContentTooLong(t *testing.T) {
 	}
 }
 
+func TestSender_RecordsAttempts(t *testing.T) {
+	t.Parallel()
+
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = w.Write([]byte(strings.Repeat("x", 2000)))
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"message":"Accepted","messageId":"remote-2"}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	var attempts []model.Attempt
+	sender := service.NewSender(client.NewWebhookClient(srv.URL), 5).
+		WithAttemptHook(func(ctx context.Context, a model.Attempt) error {
+			attempts = append(attempts, a)
+			return nil
+		})
+
+	sent, failed := sender.ProcessBatch(context.Background(), []model.Message{
+		{ID: 1, RecipientPhone: "+361", Content: "a"},
+		{ID: 2, RecipientPhone: "+362", Content: "b"},
+		{ID: 3, RecipientPhone: "+363", Content: "too long"},
+	})
+	if sent != 1 || failed != 2 {
+		t.Fatalf("expected sent=1 failed=2, got sent=%d failed=%d", sent, failed)
+	}
+
+	// The over-long message never reaches the client, so it has no attempt.
+	if len(attempts) != 2 {
+		t.Fatalf("expected 2 attempts, got %d", len(attempts))
+	}
+
+	first := attempts[0]
+	if first.MessageID != 1 || first.HTTPStatus == nil || *first.HTTPStatus != http.StatusBadGateway {
+		t.Fatalf("unexpected failed attempt: %+v", first)
+	}
+	if first.ErrorClass == nil || *first.ErrorClass != service.ErrClassHTTP5xx {
+		t.Fatalf("expected error class %q, got %v", service.ErrClassHTTP5xx, first.ErrorClass)
+	}
+	if first.ResponseBody == nil || len(*first.ResponseBody) != 1024 {
+		t.Fatalf("expected response body truncated to 1024 bytes, got %v", first.ResponseBody)
+	}
+	if first.RemoteMessageID != nil {
+		t.Fatalf("expected no remote id on failed attempt, got %q", *first.RemoteMessageID)
+	}
+	if first.FinishedAt.Before(first.StartedAt) {
+		t.Fatalf("expected finishedAt >= startedAt, got %+v", first)
+	}
+
+	second := attempts[1]
+	if second.MessageID != 2 || second.ErrorClass != nil {
+		t.Fatalf("unexpected successful attempt: %+v", second)
+	}
+	if second.RemoteMessageID == nil || *second.RemoteMessageID != "remote-2" {
+		t.Fatalf("expected remote id on successful attempt, got %v", second.RemoteMessageID)
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		res  model.SendResult
+		err  error
+		want string
+	}{
+		{"success", model.SendResult{StatusCode: 202}, nil, ""},
+		{"deadline", model.SendResult{}, fmt.Errorf("post: %w", context.DeadlineExceeded), service.ErrClassTimeout},
+		{"canceled", model.SendResult{}, context.Canceled, service.ErrClassCanceled},
+		{"connection refused", model.SendResult{}, errors.New("connection refused"), service.ErrClassNetwork},
+		{"bad request", model.SendResult{StatusCode: 400}, errors.New("status"), service.ErrClassHTTP4xx},
+		{"server error", model.SendResult{StatusCode: 503}, errors.New("status"), service.ErrClassHTTP5xx},
+		{"bad body", model.SendResult{StatusCode: 202}, errors.New("decode"), service.ErrClassInvalidResponse},
+		{"no route", model.SendResult{}, fmt.Errorf("%w for +44", service.ErrNoRoute), service.ErrClassNoRoute},
+	}
+
+	for _, tc := range cases {
+		if got := service.ClassifyError(tc.res, tc.err); got != tc.want {
+			t.Fatalf("%s: expected %q, got %q", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestSender_DefersRestOfBatchWhenCircuitOpens(t *testing.T) {
+	t.Parallel()
+
+	c := &scriptedClient{errs: []error{nil, service.ErrCircuitOpen}}
+	sender := service.NewSender(c, 160)
+
+	var sentIDs, failedIDs, deferred []int64
+	var attempts int
+	sender.
+		WithHooks(
+			func(ctx context.Context, internalID int64, res model.SendResult) error {
+				sentIDs = append(sentIDs, internalID)
+				return nil
+			},
+			func(ctx context.Context, internalID int64, reason string) error {
+				failedIDs = append(failedIDs, internalID)
+				return nil
+			},
+		).
+		WithAttemptHook(func(ctx context.Context, a model.Attempt) error {
+			attempts++
+			return nil
+		}).
+		WithDeferHook(func(ctx context.Context, ids []int64) error {
+			deferred = append(deferred, ids...)
+			return nil
+		})
+
+	sent, failed := sender.ProcessBatch(context.Background(), []model.Message{
+		{ID: 1, Content: "a"}, {ID: 2, Content: "b"}, {ID: 3, Content: "c"},
+	})
+
+	if sent != 1 || failed != 0 {
+		t.Fatalf("expected sent=1 failed=0, got sent=%d failed=%d", sent, failed)
+	}
+	if len(sentIDs) != 1 || len(failedIDs) != 0 {
+		t.Fatalf("unexpected hooks: sent=%v failed=%v", sentIDs, failedIDs)
+	}
+	if fmt.Sprint(deferred) != "[2 3]" {
+		t.Fatalf("expected messages 2 and 3 deferred, got %v", deferred)
+	}
+	if attempts != 1 || c.calls != 2 {
+		t.Fatalf("expected one recorded attempt and no call after the breaker opened, got attempts=%d calls=%d", attempts, c.calls)
+	}
+}
+
+func TestSender_MarksSendingAndPassesIdempotencyKey(t *testing.T) {
+	t.Parallel()
+
+	var log []string
+	c := keyRecorder(func(key string) { log = append(log, "send "+key) })
+	sender := service.NewSender(c, 160).
+		WithSendingHook(func(ctx context.Context, internalID int64) error {
+			log = append(log, fmt.Sprintf("sending %d", internalID))
+			if internalID == 2 {
+				return errors.New("message is not claimed")
+			}
+			return nil
+		})
+
+	sent, failed := sender.ProcessBatch(context.Background(), []model.Message{
+		{ID: 1, Content: "a"},
+		{ID: 2, Content: "b"},
+		{ID: 3, Content: "c", RequeueCount: 2},
+	})
+
+	if sent != 2 || failed != 0 {
+		t.Fatalf("expected sent=2 failed=0, got sent=%d failed=%d", sent, failed)
+	}
+	want := "[sending 1 send msg-1-0 sending 2 sending 3 send msg-3-2]"
+	if got := fmt.Sprint(log); got != want {
+		t.Fatalf("expected %s, got %s", want, got)
+	}
+}
+
+func TestSender_SendsInMessageTenantScope(t *testing.T) {
+	t.Parallel()
+
+	var got, hooks []string
+	sender := service.NewSender(tenantRecorder(func(id string) { got = append(got, id) }), 160).
+		WithHooks(
+			func(ctx context.Context, internalID int64, res model.SendResult) error {
+				hooks = append(hooks, tenant.From(ctx))
+				return nil
+			},
+			func(ctx context.Context, internalID int64, reason string) error {
+				hooks = append(hooks, tenant.From(ctx))
+				return nil
+			},
+		)
+	sender.ProcessBatch(context.Background(), []model.Message{
+		{ID: 1, TenantID: "payments", Content: "a"},
+		{ID: 2, TenantID: tenant.Default, Content: "b"},
+		{ID: 3, TenantID: "payments", Content: strings.Repeat("x", 161)},
+	})
+
+	if fmt.Sprint(got) != "[payments default]" {
+		t.Fatalf("expected each send scoped to its message's tenant, got %v", got)
+	}
+	if fmt.Sprint(hooks) != "[payments default payments]" {
+		t.Fatalf("expected each outcome hook scoped to its message's tenant, got %v", hooks)
+	}
+}
+
+func TestSender_RecordsSendSpans(t *testing.T) {
+	spans := tracingtest.Record(t)
+
+	sender := service.NewSender(&scriptedClient{errs: []error{errors.New("dial tcp: connection refused")}}, 160)
+
+	ctx, tick := tracing.Start(context.Background(), "scheduler.tick")
+	sender.ProcessBatch(ctx, []model.Message{
+		{ID: 1, RecipientPhone: "+361", Content: "a"},
+		{ID: 2, RecipientPhone: "+362", Content: "b"},
+	})
+	tick.End()
+
+	var sends []tracetest.SpanStub
+	for _, s := range spans.GetSpans() {
+		if s.Name == "Send" {
+			sends = append(sends, s)
+		}
+	}
+	if len(sends) != 2 {
+		t.Fatalf("expected one Send span per message, got %d", len(sends))
+	}
+	for _, s := range sends {
+		if s.Parent.SpanID() != tick.SpanContext().SpanID() {
+			t.Fatalf("expected Send spans to be children of the tick span")
+		}
+	}
+	if sends[0].Status.Code != codes.Error || !hasAttr(sends[0].Attributes, attribute.String("error.type", service.ErrClassNetwork)) {
+		t.Fatalf("expected the failed send to carry its error class, got %+v", sends[0])
+	}
+	if sends[1].Status.Code == codes.Error {
+		t.Fatalf("expected the second send to succeed, got %+v", sends[1].Status)
+	}
+}
+
+func hasAttr(attrs []attribute.KeyValue, want attribute.KeyValue) bool {
+	for _, a := range attrs {
+		if a == want {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSender_ScrubsProviderErrors(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]any{"error": "rejected", "echo": req})
+	}))
+	t.Cleanup(srv.Close)
+
+	var (
+		reason  string
+		attempt model.Attempt
+	)
+	sender := service.NewSender(client.NewWebhookClient(srv.URL), 160).
+		WithHooks(nil, func(ctx context.Context, id int64, r string) error {
+			reason = r
+			return nil
+		}).
+		WithAttemptHook(func(ctx context.Context, a model.Attempt) error {
+			attempt = a
+			return nil
+		})
+
+	sender.ProcessBatch(context.Background(), []model.Message{
+		{ID: 1, RecipientPhone: "+36301234111", Content: `PIN "4821" expires soon`},
+	})
+
+	for name, text := range map[string]string{"reason": reason, "response body": *attempt.ResponseBody} {
+		if strings.Contains(text, "30123") || strings.Contains(text, "4821") {
+			t.Fatalf("expected %s to be scrubbed, got %s", name, text)
+		}
+		if !strings.Contains(text, "rejected") {
+			t.Fatalf("expected %s to keep the provider error, got %s", name, text)
+		}
+	}
+}
+
+type tenantRecorder func(tenantID string)
+
+func (f tenantRecorder) Send(ctx context.Context, phoneNumber, message string) (model.SendResult, error) {
+	f(tenant.From(ctx))
+	return model.SendResult{RemoteMessageID: "r"}, nil
+}
+
+type keyRecorder func(key string)
+
+func (f keyRecorder) Send(ctx context.Context, phoneNumber, message string) (model.SendResult, error) {
+	f(service.IdempotencyKey(ctx))
+	return model.SendResult{RemoteMessageID: "r"}, nil
+}
+
+// scriptedClient returns errs[i] on the i-th call and succeeds afterwards.
+type scriptedClient struct {
+	errs  []error
+	calls int
+}
+
+func (c *scriptedClient) Send(ctx context.Context, phoneNumber, message string) (model.SendResult, error) {
+	c.calls++
+	if c.calls <= len(c.errs) && c.errs[c.calls-1] != nil {
+		return model.SendResult{}, c.errs[c.calls-1]
+	}
+	return model.SendResult{RemoteMessageID: fmt.Sprintf("r-%d", c.calls)}, nil
+}
+
 type fakeClient struct{}
 
-func (f *fakeClient) Send(ctx context.Context, phoneNumber, message string) (string, error) {
-	return "ignored", nil
+func (f *fakeClient) Send(ctx context.Context, phoneNumber, message string) (model.SendResult, error) {
+	return model.SendResult{RemoteMessageID: "ignored"}, nil
 }
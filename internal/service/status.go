@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/LeventeLantos/automatic-messaging/internal/model"
+)
+
+var (
+	// ErrStatusNotFound means the provider has no record of the message.
+	ErrStatusNotFound = errors.New("message not found at provider")
+	// ErrStatusUnsupported means the client cannot look messages up.
+	ErrStatusUnsupported = errors.New("provider status lookup not supported")
+)
+
+// StatusQuery identifies a message at the provider. RemoteMessageID is empty
+// for messages whose send never returned; the provider must then find it by
+// IdempotencyKey. Provider is empty when it is not known which provider was
+// tried.
+type StatusQuery struct {
+	Provider        string
+	RemoteMessageID string
+	IdempotencyKey  string
+}
+
+// ProviderStatus is the provider's view of a message. Status is model.Sent
+// for an accepted message without a final delivery outcome, or
+// model.Delivered / model.Undelivered.
+type ProviderStatus struct {
+	Provider        string
+	RemoteMessageID string
+	Status          model.Status
+	At              time.Time
+	ErrorCode       string
+}
+
+// StatusClient is implemented by SendClients that can ask the provider what
+// happened to a message. It is optional: callers type-assert for it.
+type StatusClient interface {
+	Status(ctx context.Context, q StatusQuery) (ProviderStatus, error)
+}
@@ -0,0 +1,67 @@
+package signature
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	HeaderSignature = "X-Signature"
+	HeaderTimestamp = "X-Signature-Timestamp"
+
+	prefix = "sha256="
+)
+
+var (
+	ErrMissing   = errors.New("missing signature or timestamp")
+	ErrMalformed = errors.New("malformed signature or timestamp")
+	ErrMismatch  = errors.New("signature mismatch")
+	ErrExpired   = errors.New("signature timestamp outside tolerance")
+)
+
+// Sign returns the "sha256=<hex>" HMAC-SHA256 of "<timestamp>.<body>".
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return prefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Timestamp formats t the way Sign and Verify expect it: Unix seconds.
+func Timestamp(t time.Time) string {
+	return strconv.FormatInt(t.Unix(), 10)
+}
+
+// Verify checks sig against body and rejects timestamps further than tolerance
+// from now in either direction. A zero tolerance disables the clock check.
+func Verify(secret []byte, timestamp, sig string, body []byte, tolerance time.Duration, now time.Time) error {
+	if timestamp == "" || sig == "" {
+		return ErrMissing
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || !strings.HasPrefix(sig, prefix) {
+		return ErrMalformed
+	}
+
+	if tolerance > 0 {
+		skew := now.Sub(time.Unix(unix, 0))
+		if skew < 0 {
+			skew = -skew
+		}
+		if skew > tolerance {
+			return ErrExpired
+		}
+	}
+
+	if !hmac.Equal([]byte(sig), []byte(Sign(secret, timestamp, body))) {
+		return ErrMismatch
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package signature
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSignVerify_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	secret := []byte("s3cret")
+	body := []byte(`{"remoteMessageId":"abc"}`)
+	now := time.Date(2026, 2, 2, 18, 0, 0, 0, time.UTC)
+	ts := Timestamp(now)
+
+	sig := Sign(secret, ts, body)
+	if err := Verify(secret, ts, sig, body, time.Minute, now); err != nil {
+		t.Fatalf("Verify() error: %v", err)
+	}
+}
+
+func TestVerify_Failures(t *testing.T) {
+	t.Parallel()
+
+	secret := []byte("s3cret")
+	body := []byte("payload")
+	now := time.Date(2026, 2, 2, 18, 0, 0, 0, time.UTC)
+	ts := Timestamp(now)
+	sig := Sign(secret, ts, body)
+
+	cases := []struct {
+		name string
+		ts   string
+		sig  string
+		body []byte
+		now  time.Time
+		want error
+	}{
+		{"missing signature", ts, "", body, now, ErrMissing},
+		{"missing timestamp", "", sig, body, now, ErrMissing},
+		{"non-numeric timestamp", "yesterday", sig, body, now, ErrMalformed},
+		{"no prefix", ts, sig[len(prefix):], body, now, ErrMalformed},
+		{"tampered body", ts, sig, []byte("payload!"), now, ErrMismatch},
+		{"wrong secret", ts, Sign([]byte("other"), ts, body), body, now, ErrMismatch},
+		{"too old", ts, sig, body, now.Add(2 * time.Minute), ErrExpired},
+		{"from the future", ts, sig, body, now.Add(-2 * time.Minute), ErrExpired},
+	}
+
+	for _, tc := range cases {
+		err := Verify(secret, tc.ts, tc.sig, tc.body, time.Minute, tc.now)
+		if !errors.Is(err, tc.want) {
+			t.Fatalf("%s: expected %v, got %v", tc.name, tc.want, err)
+		}
+	}
+}
+
+func TestVerify_ZeroToleranceSkipsClockCheck(t *testing.T) {
+	t.Parallel()
+
+	secret := []byte("s3cret")
+	body := []byte("payload")
+	ts := Timestamp(time.Unix(0, 0))
+
+	if err := Verify(secret, ts, Sign(secret, ts, body), body, 0, time.Now()); err != nil {
+		t.Fatalf("Verify() error: %v", err)
+	}
+}
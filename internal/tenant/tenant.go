@@ -0,0 +1,33 @@
+// Package tenant carries the tenant a request or message belongs to in the
+// context. Repositories scope their queries to it; a context without a
+// tenant, such as the scheduler's, sees every tenant.
+package tenant
+
+import (
+	"context"
+	"regexp"
+)
+
+// Default owns messages and API keys created without an explicit tenant.
+const Default = "default"
+
+var idRE = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// Valid reports whether id is a usable tenant ID: lowercase letters, digits
+// and dashes, starting with a letter or digit, at most 63 characters.
+func Valid(id string) bool {
+	return idRE.MatchString(id)
+}
+
+type ctxKey struct{}
+
+// With returns a context scoped to tenant id.
+func With(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// From returns the tenant set by With, or "" when ctx is not scoped.
+func From(ctx context.Context) string {
+	id, _ := ctx.Value(ctxKey{}).(string)
+	return id
+}
@@ -0,0 +1,30 @@
+package tenant
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestValid(t *testing.T) {
+	for _, id := range []string{Default, "payments", "team-7", "0", strings.Repeat("a", 63)} {
+		if !Valid(id) {
+			t.Errorf("expected %q to be valid", id)
+		}
+	}
+	for _, id := range []string{"", "-x", "Payments", "a_b", "a b", strings.Repeat("a", 64)} {
+		if Valid(id) {
+			t.Errorf("expected %q to be invalid", id)
+		}
+	}
+}
+
+func TestContext(t *testing.T) {
+	ctx := context.Background()
+	if From(ctx) != "" {
+		t.Fatalf("expected an unscoped context")
+	}
+	if got := From(With(ctx, "payments")); got != "payments" {
+		t.Fatalf("expected payments, got %q", got)
+	}
+}
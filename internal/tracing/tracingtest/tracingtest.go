@@ -0,0 +1,44 @@
+// Package tracingtest records spans in memory for tests.
+package tracingtest
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// Record installs a global tracer provider backed by an in-memory exporter
+// and the W3C trace context propagator, restoring the previous ones when the
+// test ends. Tests using it must not run in parallel.
+func Record(t testing.TB) *tracetest.InMemoryExporter {
+	t.Helper()
+
+	prevProvider := otel.GetTracerProvider()
+	prevPropagator := otel.GetTextMapPropagator()
+
+	exp := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp))
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	t.Cleanup(func() {
+		_ = tp.Shutdown(context.Background())
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+	return exp
+}
+
+// Find returns the first recorded span called name.
+func Find(exp *tracetest.InMemoryExporter, name string) (tracetest.SpanStub, bool) {
+	for _, s := range exp.GetSpans() {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return tracetest.SpanStub{}, false
+}
@@ -0,0 +1 @@
+ALTER TYPE message_status ADD VALUE IF NOT EXISTS 'cancelled';
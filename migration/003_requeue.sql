@@ -0,0 +1,4 @@
+ALTER TABLE messages
+    ADD COLUMN IF NOT EXISTS requeue_count INT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS requeue_note  TEXT,
+    ADD COLUMN IF NOT EXISTS requeued_at   TIMESTAMPTZ;
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS message_attempts (
+    id                BIGSERIAL PRIMARY KEY,
+    message_id        BIGINT NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    started_at        TIMESTAMPTZ NOT NULL,
+    finished_at       TIMESTAMPTZ NOT NULL,
+    http_status       INT,
+    error_class       TEXT,
+    response_body     TEXT,
+    remote_message_id TEXT,
+    instance_id       TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_attempts_message
+    ON message_attempts(message_id, started_at);
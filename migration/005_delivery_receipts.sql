@@ -0,0 +1,10 @@
+ALTER TYPE message_status ADD VALUE IF NOT EXISTS 'delivered';
+ALTER TYPE message_status ADD VALUE IF NOT EXISTS 'undelivered';
+ALTER TYPE message_status ADD VALUE IF NOT EXISTS 'unknown';
+
+ALTER TABLE messages
+    ADD COLUMN IF NOT EXISTS delivery_status_at  TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS delivery_error_code TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_messages_remote_message_id
+    ON messages(remote_message_id);
@@ -0,0 +1,41 @@
+CREATE TABLE IF NOT EXISTS subscriptions (
+    id          BIGSERIAL PRIMARY KEY,
+    url         TEXT NOT NULL,
+    secret      TEXT NOT NULL,
+    event_types TEXT[] NOT NULL,
+    active      BOOLEAN NOT NULL DEFAULT true,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Written in the same transaction as the message status change.
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id          BIGSERIAL PRIMARY KEY,
+    event_type  TEXT NOT NULL,
+    message_id  BIGINT NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    payload     JSONB NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS event_deliveries (
+    id              BIGSERIAL PRIMARY KEY,
+    event_id        BIGINT NOT NULL REFERENCES outbox_events(id) ON DELETE CASCADE,
+    subscription_id BIGINT NOT NULL REFERENCES subscriptions(id) ON DELETE CASCADE,
+    state           TEXT NOT NULL DEFAULT 'pending',
+    attempts        INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_status     INT,
+    last_error      TEXT,
+    delivered_at    TIMESTAMPTZ,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    CONSTRAINT event_deliveries_state_chk CHECK (state IN ('pending', 'succeeded', 'failed')),
+    CONSTRAINT event_deliveries_unique UNIQUE (event_id, subscription_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_deliveries_due
+    ON event_deliveries(next_attempt_at)
+    WHERE state = 'pending';
+
+CREATE INDEX IF NOT EXISTS idx_event_deliveries_subscription
+    ON event_deliveries(subscription_id, created_at DESC);
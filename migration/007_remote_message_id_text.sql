@@ -0,0 +1,4 @@
+-- Providers other than the original one return non-UUID message IDs
+-- (e.g. Twilio "SM..." SIDs), so store them as opaque text.
+ALTER TABLE messages
+    ALTER COLUMN remote_message_id TYPE TEXT USING remote_message_id::text;
@@ -0,0 +1,5 @@
+ALTER TABLE messages
+    ADD COLUMN IF NOT EXISTS provider TEXT;
+
+ALTER TABLE message_attempts
+    ADD COLUMN IF NOT EXISTS provider TEXT;
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS routes (
+    prefix     TEXT PRIMARY KEY CHECK (prefix ~ '^\+[0-9]{0,14}$'),
+    provider   TEXT NOT NULL,
+    sender_id  TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
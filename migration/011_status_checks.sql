@@ -0,0 +1,6 @@
+ALTER TABLE messages
+    ADD COLUMN IF NOT EXISTS status_checked_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_messages_awaiting_receipt
+    ON messages(sent_at)
+    WHERE status IN ('sent', 'unknown');
@@ -0,0 +1,43 @@
+-- Per-status message counts kept up to date by triggers, so GET /v1/stats
+-- does not have to count the whole messages table.
+CREATE TABLE IF NOT EXISTS message_status_counts (
+    status message_status PRIMARY KEY,
+    count  BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE OR REPLACE FUNCTION track_message_status_counts() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE message_status_counts SET count = count - 1 WHERE status = OLD.status;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        INSERT INTO message_status_counts (status, count) VALUES (NEW.status, 1)
+        ON CONFLICT (status) DO UPDATE SET count = message_status_counts.count + 1;
+    END IF;
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+-- Recount under a lock so rerunning the migration also repairs any drift.
+BEGIN;
+LOCK TABLE messages IN SHARE ROW EXCLUSIVE MODE;
+
+CREATE OR REPLACE TRIGGER messages_status_counts_insert_delete
+    AFTER INSERT OR DELETE ON messages
+    FOR EACH ROW EXECUTE FUNCTION track_message_status_counts();
+
+CREATE OR REPLACE TRIGGER messages_status_counts_update
+    AFTER UPDATE OF status ON messages
+    FOR EACH ROW
+    WHEN (OLD.status IS DISTINCT FROM NEW.status)
+    EXECUTE FUNCTION track_message_status_counts();
+
+UPDATE message_status_counts SET count = 0;
+INSERT INTO message_status_counts (status, count)
+SELECT status, count(*) FROM messages GROUP BY status
+ON CONFLICT (status) DO UPDATE SET count = EXCLUDED.count;
+COMMIT;
+
+-- Failure ratio over recent attempts.
+CREATE INDEX IF NOT EXISTS idx_message_attempts_started
+    ON message_attempts(started_at);
@@ -0,0 +1,11 @@
+-- Applied migration versions. Readiness checks compare the highest one with
+-- the version the binary expects, so every later migration must record its
+-- own number here.
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version    INT PRIMARY KEY,
+    applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+INSERT INTO schema_migrations (version)
+SELECT generate_series(1, 13)
+ON CONFLICT (version) DO NOTHING;
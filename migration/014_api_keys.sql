@@ -0,0 +1,14 @@
+-- API keys are stored as SHA-256 hashes; prefix is the first characters of
+-- the key, kept so operators can tell keys apart.
+CREATE TABLE IF NOT EXISTS api_keys (
+    id         BIGSERIAL PRIMARY KEY,
+    name       TEXT NOT NULL,
+    prefix     TEXT NOT NULL,
+    key_hash   TEXT NOT NULL UNIQUE,
+    scopes     TEXT[] NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    revoked_at TIMESTAMPTZ
+);
+
+INSERT INTO schema_migrations (version) VALUES (14)
+ON CONFLICT (version) DO NOTHING;
@@ -0,0 +1,25 @@
+-- Teams sharing the deployment. provider and sender_id, when set, override
+-- the routing table for the tenant's messages.
+CREATE TABLE IF NOT EXISTS tenants (
+    id         TEXT PRIMARY KEY CHECK (id ~ '^[a-z0-9][a-z0-9-]{0,62}$'),
+    provider   TEXT,
+    sender_id  TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+INSERT INTO tenants (id) VALUES ('default')
+ON CONFLICT (id) DO NOTHING;
+
+-- Existing messages and keys belong to the default tenant.
+ALTER TABLE messages
+    ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default' REFERENCES tenants (id);
+ALTER TABLE api_keys
+    ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default' REFERENCES tenants (id);
+
+-- ClaimPending reads the oldest pending messages of each tenant.
+CREATE INDEX IF NOT EXISTS idx_messages_tenant_pending
+    ON messages(tenant_id, created_at, id) WHERE status = 'pending';
+
+INSERT INTO schema_migrations (version) VALUES (16)
+ON CONFLICT (version) DO NOTHING;
@@ -0,0 +1,13 @@
+-- Subscriptions only receive events of their tenant. Existing subscriptions
+-- belong to the default tenant; a NULL tenant_id (created without a tenant,
+-- i.e. with auth disabled) receives the events of every tenant.
+ALTER TABLE subscriptions
+    ADD COLUMN IF NOT EXISTS tenant_id TEXT DEFAULT 'default' REFERENCES tenants (id);
+ALTER TABLE subscriptions
+    ALTER COLUMN tenant_id DROP DEFAULT;
+
+CREATE INDEX IF NOT EXISTS idx_subscriptions_tenant
+    ON subscriptions(tenant_id) WHERE active;
+
+INSERT INTO schema_migrations (version) VALUES (17)
+ON CONFLICT (version) DO NOTHING;
@@ -0,0 +1,14 @@
+-- Audit events record the tenant of the key that acted, so tenants only see
+-- their own. Events recorded without auth have no tenant; earlier events with
+-- a key were all made by default tenant keys.
+ALTER TABLE audit_events
+    ADD COLUMN IF NOT EXISTS tenant_id TEXT DEFAULT 'default';
+ALTER TABLE audit_events
+    ALTER COLUMN tenant_id DROP DEFAULT;
+UPDATE audit_events SET tenant_id = NULL
+WHERE actor = 'anonymous' AND tenant_id IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_tenant ON audit_events (tenant_id, id);
+
+INSERT INTO schema_migrations (version) VALUES (18)
+ON CONFLICT (version) DO NOTHING;
@@ -0,0 +1,7 @@
+-- Operator keys belong to no tenant: they see every tenant and are the only
+-- keys that may change state shared by all tenants, such as routes.
+ALTER TABLE api_keys
+    ALTER COLUMN tenant_id DROP NOT NULL;
+
+INSERT INTO schema_migrations (version) VALUES (19)
+ON CONFLICT (version) DO NOTHING;
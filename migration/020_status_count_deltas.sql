@@ -0,0 +1,38 @@
+-- message_status_counts had one row per status that every status change
+-- updated, so concurrent senders queued on the same rows and transactions
+-- changing several messages could deadlock on them. The triggers now only
+-- append deltas, which never conflict; CompactStatusCounts periodically folds
+-- them into message_status_counts, and readers add up both.
+CREATE TABLE IF NOT EXISTS message_status_count_deltas (
+    id     BIGSERIAL PRIMARY KEY,
+    status message_status NOT NULL,
+    delta  BIGINT NOT NULL
+);
+
+CREATE OR REPLACE FUNCTION track_message_status_counts() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        INSERT INTO message_status_count_deltas (status, delta) VALUES (OLD.status, -1);
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        INSERT INTO message_status_count_deltas (status, delta) VALUES (NEW.status, 1);
+    END IF;
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+-- Rerunning 012 recounts message_status_counts without knowing about the
+-- deltas, so recount here too, under the same lock, and drop the deltas the
+-- recount already includes.
+BEGIN;
+LOCK TABLE messages IN SHARE ROW EXCLUSIVE MODE;
+
+DELETE FROM message_status_count_deltas;
+UPDATE message_status_counts SET count = 0;
+INSERT INTO message_status_counts (status, count)
+SELECT status, count(*) FROM messages GROUP BY status
+ON CONFLICT (status) DO UPDATE SET count = EXCLUDED.count;
+
+INSERT INTO schema_migrations (version) VALUES (20)
+ON CONFLICT (version) DO NOTHING;
+COMMIT;
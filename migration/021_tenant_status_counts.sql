@@ -0,0 +1,49 @@
+-- Status counts per tenant, so GET /v1/stats only reports the caller's
+-- messages. They replace message_status_counts; deltas now carry the tenant.
+CREATE TABLE IF NOT EXISTS message_tenant_status_counts (
+    tenant_id TEXT NOT NULL,
+    status    message_status NOT NULL,
+    count     BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (tenant_id, status)
+);
+
+-- The default only covers the moment between rerunning 020 and this file,
+-- when 020's trigger function does not set the tenant; the recount below
+-- repairs those rows.
+ALTER TABLE message_status_count_deltas
+    ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default';
+
+CREATE OR REPLACE FUNCTION track_message_status_counts() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        INSERT INTO message_status_count_deltas (tenant_id, status, delta)
+        VALUES (OLD.tenant_id, OLD.status, -1);
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        INSERT INTO message_status_count_deltas (tenant_id, status, delta)
+        VALUES (NEW.tenant_id, NEW.status, 1);
+    END IF;
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+BEGIN;
+LOCK TABLE messages IN SHARE ROW EXCLUSIVE MODE;
+
+-- Moving a message to another tenant moves its count too.
+CREATE OR REPLACE TRIGGER messages_status_counts_update
+    AFTER UPDATE OF status, tenant_id ON messages
+    FOR EACH ROW
+    WHEN (OLD.status IS DISTINCT FROM NEW.status OR OLD.tenant_id IS DISTINCT FROM NEW.tenant_id)
+    EXECUTE FUNCTION track_message_status_counts();
+
+DELETE FROM message_status_count_deltas;
+DELETE FROM message_tenant_status_counts;
+INSERT INTO message_tenant_status_counts (tenant_id, status, count)
+SELECT tenant_id, status, count(*) FROM messages GROUP BY tenant_id, status;
+
+DROP TABLE IF EXISTS message_status_counts;
+
+INSERT INTO schema_migrations (version) VALUES (21)
+ON CONFLICT (version) DO NOTHING;
+COMMIT;